@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/abdul-hamid-achik/chessdrill/internal/chess"
+	"github.com/abdul-hamid-achik/chessdrill/internal/config"
+	"github.com/abdul-hamid-achik/chessdrill/internal/model"
+	"github.com/abdul-hamid-achik/chessdrill/internal/mongo"
+	"github.com/abdul-hamid-achik/chessdrill/internal/repository"
+)
+
+// Column order of the Lichess puzzle database CSV
+const (
+	colPuzzleID = iota
+	colFEN
+	colMoves
+	colRating
+	colRatingDeviation
+	colPopularity
+	colNbPlays
+	colThemes
+	colGameURL
+	colOpeningTags
+)
+
+func main() {
+	file := flag.String("file", "", "Path to a Lichess puzzle CSV, or - for stdin")
+	batchSize := flag.Int("batch", 1000, "Number of puzzles written per batch")
+	limit := flag.Int("limit", 0, "Stop after importing this many puzzles (0 = no limit)")
+	minRating := flag.Int("min-rating", 0, "Skip puzzles rated below this")
+	maxRating := flag.Int("max-rating", 0, "Skip puzzles rated above this (0 = no limit)")
+	flag.Parse()
+
+	if *file == "" {
+		fmt.Println("ChessDrill Puzzle Importer")
+		fmt.Println("")
+		fmt.Println("Usage:")
+		fmt.Println("  import-puzzles -file lichess_db_puzzle.csv [-batch 1000] [-limit N] [-min-rating R] [-max-rating R]")
+		fmt.Println("")
+		fmt.Println("The Lichess export is zstd-compressed; decompress it first, or stream it:")
+		fmt.Println("  zstdcat lichess_db_puzzle.csv.zst | import-puzzles -file -")
+		fmt.Println("")
+		fmt.Println("Environment variables:")
+		fmt.Println("  MONGODB_URI       MongoDB connection URI (default: mongodb://localhost:27017)")
+		fmt.Println("  MONGODB_DATABASE  Database name (default: chessdrill)")
+		os.Exit(0)
+	}
+
+	var in io.Reader = os.Stdin
+	if *file != "-" {
+		f, err := os.Open(*file)
+		if err != nil {
+			log.Fatalf("Failed to open %s: %v", *file, err)
+		}
+		defer f.Close()
+		in = f
+	}
+
+	cfg := config.Load()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	mongoClient, err := mongo.NewClient(ctx, cfg.MongoDBURI, cfg.MongoDBDatabase)
+	if err != nil {
+		log.Fatalf("Failed to connect to MongoDB: %v", err)
+	}
+	defer func() {
+		if err := mongoClient.Close(context.Background()); err != nil {
+			log.Printf("Error closing MongoDB connection: %v", err)
+		}
+	}()
+
+	if err := mongoClient.CreateIndexes(ctx); err != nil {
+		log.Printf("Warning: Failed to create indexes: %v", err)
+	}
+
+	puzzleRepo := repository.NewPuzzleRepository(mongoClient.Database())
+
+	reader := csv.NewReader(in)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+
+	var batch []*model.Puzzle
+	var read, skipped, inserted, updated int64
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+		defer cancel()
+		ins, upd, err := puzzleRepo.UpsertMany(ctx, batch)
+		if err != nil {
+			log.Fatalf("Failed to write puzzles: %v", err)
+		}
+		inserted += ins
+		updated += upd
+		batch = batch[:0]
+		log.Printf("Imported %d puzzles (%d new, %d updated, %d skipped)", inserted+updated, inserted, updated, skipped)
+	}
+
+	for *limit == 0 || inserted+updated+int64(len(batch)) < int64(*limit) {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			log.Fatalf("Failed to read CSV: %v", err)
+		}
+		read++
+		if read == 1 && record[0] == "PuzzleId" {
+			continue
+		}
+
+		puzzle, err := parsePuzzle(record)
+		if err != nil {
+			skipped++
+			log.Printf("Skipping line %d: %v", read, err)
+			continue
+		}
+		if puzzle.Rating < *minRating || (*maxRating > 0 && puzzle.Rating > *maxRating) {
+			skipped++
+			continue
+		}
+
+		batch = append(batch, puzzle)
+		if len(batch) >= *batchSize {
+			flush()
+		}
+	}
+	flush()
+
+	log.Printf("Import completed: %d new, %d updated, %d skipped", inserted, updated, skipped)
+}
+
+// parsePuzzle converts a CSV record into a puzzle, checking that every move
+// of the solution is legal
+func parsePuzzle(record []string) (*model.Puzzle, error) {
+	if len(record) < colThemes+1 {
+		return nil, fmt.Errorf("expected at least %d columns, got %d", colThemes+1, len(record))
+	}
+
+	puzzle := &model.Puzzle{
+		PuzzleID:   record[colPuzzleID],
+		FEN:        record[colFEN],
+		Moves:      strings.Fields(record[colMoves]),
+		Themes:     strings.Fields(record[colThemes]),
+		ImportedAt: time.Now(),
+	}
+	if len(record) > colGameURL {
+		puzzle.GameURL = record[colGameURL]
+	}
+	if len(record) > colOpeningTags {
+		puzzle.OpeningTags = strings.Fields(record[colOpeningTags])
+	}
+
+	var err error
+	if puzzle.Rating, err = strconv.Atoi(record[colRating]); err != nil {
+		return nil, fmt.Errorf("invalid rating %q", record[colRating])
+	}
+	puzzle.RatingDeviation, _ = strconv.Atoi(record[colRatingDeviation])
+	puzzle.Popularity, _ = strconv.Atoi(record[colPopularity])
+	puzzle.NbPlays, _ = strconv.Atoi(record[colNbPlays])
+
+	if puzzle.PuzzleID == "" {
+		return nil, errors.New("missing puzzle ID")
+	}
+	if len(puzzle.Moves) < 2 {
+		return nil, fmt.Errorf("puzzle %s has no solution", puzzle.PuzzleID)
+	}
+
+	pos, err := chess.ParseFEN(puzzle.FEN)
+	if err != nil {
+		return nil, fmt.Errorf("puzzle %s: %w", puzzle.PuzzleID, err)
+	}
+	for _, uci := range puzzle.Moves {
+		m, err := chess.ParseUCI(uci)
+		if err != nil || !pos.IsLegal(m) {
+			return nil, fmt.Errorf("puzzle %s: illegal move %s", puzzle.PuzzleID, uci)
+		}
+		pos = pos.Play(m)
+	}
+	return puzzle, nil
+}
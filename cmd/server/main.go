@@ -43,21 +43,44 @@ func main() {
 	sessionRepo := repository.NewSessionRepository(db)
 	drillSessionRepo := repository.NewDrillSessionRepository(db)
 	attemptRepo := repository.NewAttemptRepository(db)
+	questionRepo := repository.NewQuestionRepository(db)
+	puzzleRepo := repository.NewPuzzleRepository(db)
+	reviewRepo := repository.NewReviewRepository(db)
+	challengeRepo := repository.NewChallengeRepository(db)
+	leaderboardRepo := repository.NewLeaderboardRepository(db)
+	raceRepo := repository.NewRaceRepository(db)
+	groupRepo := repository.NewGroupRepository(db)
+	assignmentRepo := repository.NewAssignmentRepository(db)
+	repertoireRepo := repository.NewRepertoireRepository(db)
 
 	authService := service.NewAuthService(userRepo, sessionRepo, cfg.SessionMaxAge)
-	drillService := service.NewDrillService(drillSessionRepo, attemptRepo)
-	statsService := service.NewStatsService(attemptRepo, drillSessionRepo)
+	reviewService := service.NewReviewService(reviewRepo)
+	drillService := service.NewDrillService(drillSessionRepo, attemptRepo, questionRepo, puzzleRepo, repertoireRepo, challengeRepo, reviewService)
+	challengeService := service.NewChallengeService(challengeRepo, userRepo, drillService, cfg.SessionSecret)
+	leaderboardService := service.NewLeaderboardService(leaderboardRepo, attemptRepo, drillSessionRepo, userRepo)
+	raceService := service.NewRaceService(raceRepo, drillService)
+	statsService := service.NewStatsService(attemptRepo, drillSessionRepo, drillService)
 	userService := service.NewUserService(userRepo)
+	groupService := service.NewGroupService(groupRepo, assignmentRepo, statsService)
+	assignmentService := service.NewAssignmentService(assignmentRepo, groupRepo, attemptRepo)
+	repertoireService := service.NewRepertoireService(repertoireRepo, attemptRepo)
 
 	authMiddleware := middleware.NewAuthMiddleware(authService)
 
-	pageHandler := handler.NewPageHandler(statsService, drillService)
+	pageHandler := handler.NewPageHandler(statsService, drillService, reviewService, challengeService, leaderboardService, raceService, groupService, assignmentService, repertoireService)
 	authHandler := handler.NewAuthHandler(authService, cfg.SessionMaxAge)
 	drillHandler := handler.NewDrillHandler(drillService)
 	statsHandler := handler.NewStatsHandler(statsService)
 	settingsHandler := handler.NewSettingsHandler(userService)
+	challengeHandler := handler.NewChallengeHandler(challengeService)
+	leaderboardHandler := handler.NewLeaderboardHandler(leaderboardService)
+	raceHandler := handler.NewRaceHandler(raceService)
+	groupHandler := handler.NewGroupHandler(groupService)
+	assignmentHandler := handler.NewAssignmentHandler(assignmentService)
+	presetHandler := handler.NewPresetHandler(userService)
+	repertoireHandler := handler.NewRepertoireHandler(repertoireService)
 
-	srv := server.New(pageHandler, authHandler, drillHandler, statsHandler, settingsHandler, authMiddleware)
+	srv := server.New(pageHandler, authHandler, drillHandler, statsHandler, settingsHandler, challengeHandler, leaderboardHandler, raceHandler, groupHandler, assignmentHandler, presetHandler, repertoireHandler, authMiddleware)
 
 	httpServer := &http.Server{
 		Addr:         ":" + cfg.Port,
@@ -67,6 +90,14 @@ func main() {
 		IdleTimeout:  60 * time.Second,
 	}
 
+	// Leaderboards are precomputed in the background rather than per
+	// request, and timed sessions left running are closed once they run out
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	refreshInterval := time.Duration(max(cfg.LeaderboardRefreshMinutes, 1)) * time.Minute
+	go leaderboardService.Run(jobsCtx, refreshInterval)
+	go drillService.RunExpiry(jobsCtx, time.Minute)
+
 	go func() {
 		log.Printf("Server starting on http://localhost:%s", cfg.Port)
 		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -79,6 +110,7 @@ func main() {
 	<-quit
 
 	log.Println("Shutting down server...")
+	stopJobs()
 
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer shutdownCancel()
@@ -0,0 +1,253 @@
+package chess
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrInvalidSquare = errors.New("invalid square")
+
+// Color represents the side a piece belongs to
+type Color int8
+
+const (
+	White Color = iota
+	Black
+)
+
+func (c Color) Other() Color {
+	if c == White {
+		return Black
+	}
+	return White
+}
+
+func (c Color) String() string {
+	if c == White {
+		return "white"
+	}
+	return "black"
+}
+
+// PieceType represents the kind of piece, independent of color
+type PieceType int8
+
+const (
+	NoPieceType PieceType = iota
+	Pawn
+	Knight
+	Bishop
+	Rook
+	Queen
+	King
+)
+
+var pieceTypeNames = map[PieceType]string{
+	Pawn:   "pawn",
+	Knight: "knight",
+	Bishop: "bishop",
+	Rook:   "rook",
+	Queen:  "queen",
+	King:   "king",
+}
+
+func (t PieceType) String() string {
+	return pieceTypeNames[t]
+}
+
+// ParsePieceType converts a piece name such as "knight" into a PieceType
+func ParsePieceType(name string) (PieceType, bool) {
+	for t, n := range pieceTypeNames {
+		if n == name {
+			return t, true
+		}
+	}
+	return NoPieceType, false
+}
+
+// Piece is a colored piece. The zero value is an empty square.
+type Piece struct {
+	Type  PieceType
+	Color Color
+}
+
+var NoPiece = Piece{}
+
+func (p Piece) IsEmpty() bool {
+	return p.Type == NoPieceType
+}
+
+const pieceLetters = " pnbrqk"
+
+// Letter returns the FEN letter for the piece, uppercase for white
+func (p Piece) Letter() byte {
+	if p.IsEmpty() {
+		return 0
+	}
+	l := pieceLetters[p.Type]
+	if p.Color == White {
+		l -= 'a' - 'A'
+	}
+	return l
+}
+
+func pieceFromLetter(l byte) (Piece, bool) {
+	color := White
+	if l >= 'a' && l <= 'z' {
+		color = Black
+		l -= 'a' - 'A'
+	}
+	for t := Pawn; t <= King; t++ {
+		if pieceLetters[t]-('a'-'A') == l {
+			return Piece{Type: t, Color: color}, true
+		}
+	}
+	return NoPiece, false
+}
+
+// Square is a board index from a1 (0) to h8 (63)
+type Square int8
+
+const NoSquare Square = -1
+
+func NewSquare(file, rank int) Square {
+	return Square(rank*8 + file)
+}
+
+// ParseSquare converts algebraic notation such as "e4" into a Square
+func ParseSquare(s string) (Square, error) {
+	if len(s) != 2 || s[0] < 'a' || s[0] > 'h' || s[1] < '1' || s[1] > '8' {
+		return NoSquare, fmt.Errorf("%w: %q", ErrInvalidSquare, s)
+	}
+	return NewSquare(int(s[0]-'a'), int(s[1]-'1')), nil
+}
+
+func (s Square) File() int {
+	return int(s) % 8
+}
+
+func (s Square) Rank() int {
+	return int(s) / 8
+}
+
+func (s Square) String() string {
+	if s < 0 || s > 63 {
+		return "-"
+	}
+	return string([]byte{byte('a' + s.File()), byte('1' + s.Rank())})
+}
+
+// IsLight reports whether the square is a light square (h1 is light)
+func (s Square) IsLight() bool {
+	return (s.File()+s.Rank())%2 == 1
+}
+
+// Offset returns the square df files and dr ranks away, if it is on the board
+func (s Square) Offset(df, dr int) (Square, bool) {
+	f, r := s.File()+df, s.Rank()+dr
+	if f < 0 || f > 7 || r < 0 || r > 7 {
+		return NoSquare, false
+	}
+	return NewSquare(f, r), true
+}
+
+// CastlingRights is a bitmask of the castling moves still available
+type CastlingRights uint8
+
+const (
+	WhiteKingside CastlingRights = 1 << iota
+	WhiteQueenside
+	BlackKingside
+	BlackQueenside
+
+	NoCastling  CastlingRights = 0
+	AllCastling                = WhiteKingside | WhiteQueenside | BlackKingside | BlackQueenside
+)
+
+// Position is a full game state: piece placement, side to move, castling
+// rights, en passant target and move counters
+type Position struct {
+	board          [64]Piece
+	Turn           Color
+	Castling       CastlingRights
+	EnPassant      Square
+	HalfmoveClock  int
+	FullmoveNumber int
+}
+
+// NewPosition returns an empty board with white to move
+func NewPosition() *Position {
+	return &Position{
+		Turn:           White,
+		EnPassant:      NoSquare,
+		FullmoveNumber: 1,
+	}
+}
+
+// StartingPosition returns the standard initial position
+func StartingPosition() *Position {
+	pos, _ := ParseFEN(StartFEN)
+	return pos
+}
+
+func (p *Position) Piece(sq Square) Piece {
+	return p.board[sq]
+}
+
+func (p *Position) SetPiece(sq Square, piece Piece) {
+	p.board[sq] = piece
+}
+
+func (p *Position) RemovePiece(sq Square) {
+	p.board[sq] = NoPiece
+}
+
+// Clone returns an independent copy of the position
+func (p *Position) Clone() *Position {
+	c := *p
+	return &c
+}
+
+// KingSquare returns the square of the given side's king, or NoSquare
+func (p *Position) KingSquare(c Color) Square {
+	for sq := Square(0); sq < 64; sq++ {
+		if p.board[sq] == (Piece{Type: King, Color: c}) {
+			return sq
+		}
+	}
+	return NoSquare
+}
+
+// PieceSquares returns every square holding the given piece
+func (p *Position) PieceSquares(piece Piece) []Square {
+	var squares []Square
+	for sq := Square(0); sq < 64; sq++ {
+		if p.board[sq] == piece {
+			squares = append(squares, sq)
+		}
+	}
+	return squares
+}
+
+var ErrInvalidPosition = errors.New("invalid position")
+
+// Validate checks that the position could occur in a game: one king per
+// side, no pawns on the back ranks, and the side not to move not in check
+func (p *Position) Validate() error {
+	for _, c := range []Color{White, Black} {
+		if n := len(p.PieceSquares(Piece{Type: King, Color: c})); n != 1 {
+			return fmt.Errorf("%w: %s has %d kings", ErrInvalidPosition, c, n)
+		}
+	}
+	for file := 0; file < 8; file++ {
+		for _, rank := range []int{0, 7} {
+			if p.board[NewSquare(file, rank)].Type == Pawn {
+				return fmt.Errorf("%w: pawn on %s", ErrInvalidPosition, NewSquare(file, rank))
+			}
+		}
+	}
+	if p.kingAttacked(p.Turn.Other()) {
+		return fmt.Errorf("%w: side not to move is in check", ErrInvalidPosition)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package chess
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrNoCastlingRights     = errors.New("castling rights have been lost")
+	ErrCastlingRookMissing  = errors.New("rook is not on its starting square")
+	ErrCastlingBlocked      = errors.New("pieces stand between king and rook")
+	ErrCastlingInCheck      = errors.New("king is in check")
+	ErrCastlingThroughCheck = errors.New("king passes through an attacked square")
+	ErrCastlingIntoCheck    = errors.New("king would land on an attacked square")
+)
+
+// castlingPath describes the squares involved in one castling move
+type castlingPath struct {
+	color    Color
+	rank     int
+	rookFile int
+	between  []int // files that must be empty
+	crossing int   // file the king passes over
+	landing  int   // file the king lands on
+}
+
+func pathFor(right CastlingRights) castlingPath {
+	switch right {
+	case WhiteKingside:
+		return castlingPath{White, 0, 7, []int{5, 6}, 5, 6}
+	case WhiteQueenside:
+		return castlingPath{White, 0, 0, []int{1, 2, 3}, 3, 2}
+	case BlackKingside:
+		return castlingPath{Black, 7, 7, []int{5, 6}, 5, 6}
+	default:
+		return castlingPath{Black, 7, 0, []int{1, 2, 3}, 3, 2}
+	}
+}
+
+// CastlingRight returns the single castling right for a color and side
+func CastlingRight(c Color, kingside bool) CastlingRights {
+	switch {
+	case c == White && kingside:
+		return WhiteKingside
+	case c == White:
+		return WhiteQueenside
+	case kingside:
+		return BlackKingside
+	default:
+		return BlackQueenside
+	}
+}
+
+// castlingObstacle returns the first rule that prevents castling with the
+// given single right, and the square it applies to. It does not allocate so
+// move generation can use it.
+func (p *Position) castlingObstacle(right CastlingRights) (Square, error) {
+	path := pathFor(right)
+	king := NewSquare(4, path.rank)
+	if p.Castling&right == 0 || p.board[king] != (Piece{Type: King, Color: path.color}) {
+		return NoSquare, ErrNoCastlingRights
+	}
+	rook := NewSquare(path.rookFile, path.rank)
+	if p.board[rook] != (Piece{Type: Rook, Color: path.color}) {
+		return rook, ErrCastlingRookMissing
+	}
+	for _, f := range path.between {
+		if sq := NewSquare(f, path.rank); !p.board[sq].IsEmpty() {
+			return sq, ErrCastlingBlocked
+		}
+	}
+	if p.IsAttacked(king, path.color.Other()) {
+		return king, ErrCastlingInCheck
+	}
+	if sq := NewSquare(path.crossing, path.rank); p.IsAttacked(sq, path.color.Other()) {
+		return sq, ErrCastlingThroughCheck
+	}
+	if sq := NewSquare(path.landing, path.rank); p.IsAttacked(sq, path.color.Other()) {
+		return sq, ErrCastlingIntoCheck
+	}
+	return NoSquare, nil
+}
+
+// CanCastle reports whether castling with the given single right is legal,
+// regardless of whose turn it is. The returned error wraps one of the
+// ErrCastling* reasons (or ErrNoCastlingRights) and names the square and
+// piece responsible.
+func (p *Position) CanCastle(right CastlingRights) error {
+	sq, err := p.castlingObstacle(right)
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, ErrCastlingRookMissing):
+		return fmt.Errorf("%w: no rook on %s", err, sq)
+	case errors.Is(err, ErrCastlingBlocked):
+		return fmt.Errorf("%w: %s is occupied", err, sq)
+	case errors.Is(err, ErrNoCastlingRights):
+		return err
+	}
+
+	by := pathFor(right).color.Other()
+	attacker := p.Attackers(sq, by)[0]
+	piece := p.board[attacker]
+	return fmt.Errorf("%w: %s is attacked by the %s %s on %s", err, sq, piece.Color, piece.Type, attacker)
+}
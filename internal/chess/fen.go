@@ -0,0 +1,162 @@
+package chess
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	StartFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+	EmptyFEN = "8/8/8/8/8/8/8/8 w - - 0 1"
+)
+
+var ErrInvalidFEN = errors.New("invalid FEN")
+
+// ParseFEN parses a FEN string. Only the piece placement field is required;
+// missing fields default to white to move, no castling, no en passant.
+func ParseFEN(fen string) (*Position, error) {
+	fields := strings.Fields(fen)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("%w: empty string", ErrInvalidFEN)
+	}
+
+	pos := NewPosition()
+
+	rows := strings.Split(fields[0], "/")
+	if len(rows) != 8 {
+		return nil, fmt.Errorf("%w: expected 8 ranks, got %d", ErrInvalidFEN, len(rows))
+	}
+	for i, row := range rows {
+		rank := 7 - i
+		file := 0
+		for j := 0; j < len(row); j++ {
+			c := row[j]
+			if c >= '1' && c <= '8' {
+				file += int(c - '0')
+				continue
+			}
+			piece, ok := pieceFromLetter(c)
+			if !ok {
+				return nil, fmt.Errorf("%w: unknown piece %q", ErrInvalidFEN, c)
+			}
+			if file > 7 {
+				return nil, fmt.Errorf("%w: rank %d is too long", ErrInvalidFEN, rank+1)
+			}
+			pos.board[NewSquare(file, rank)] = piece
+			file++
+		}
+		if file != 8 {
+			return nil, fmt.Errorf("%w: rank %d has %d files", ErrInvalidFEN, rank+1, file)
+		}
+	}
+
+	if len(fields) > 1 {
+		switch fields[1] {
+		case "w":
+			pos.Turn = White
+		case "b":
+			pos.Turn = Black
+		default:
+			return nil, fmt.Errorf("%w: bad side to move %q", ErrInvalidFEN, fields[1])
+		}
+	}
+
+	if len(fields) > 2 && fields[2] != "-" {
+		for _, c := range fields[2] {
+			switch c {
+			case 'K':
+				pos.Castling |= WhiteKingside
+			case 'Q':
+				pos.Castling |= WhiteQueenside
+			case 'k':
+				pos.Castling |= BlackKingside
+			case 'q':
+				pos.Castling |= BlackQueenside
+			default:
+				return nil, fmt.Errorf("%w: bad castling field %q", ErrInvalidFEN, fields[2])
+			}
+		}
+	}
+
+	if len(fields) > 3 && fields[3] != "-" {
+		sq, err := ParseSquare(fields[3])
+		if err != nil {
+			return nil, fmt.Errorf("%w: bad en passant square %q", ErrInvalidFEN, fields[3])
+		}
+		pos.EnPassant = sq
+	}
+
+	if len(fields) > 4 {
+		n, err := strconv.Atoi(fields[4])
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("%w: bad halfmove clock %q", ErrInvalidFEN, fields[4])
+		}
+		pos.HalfmoveClock = n
+	}
+
+	if len(fields) > 5 {
+		n, err := strconv.Atoi(fields[5])
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("%w: bad fullmove number %q", ErrInvalidFEN, fields[5])
+		}
+		pos.FullmoveNumber = n
+	}
+
+	return pos, nil
+}
+
+// BoardFEN returns only the piece placement field
+func (p *Position) BoardFEN() string {
+	var sb strings.Builder
+	for rank := 7; rank >= 0; rank-- {
+		empty := 0
+		for file := 0; file < 8; file++ {
+			piece := p.board[NewSquare(file, rank)]
+			if piece.IsEmpty() {
+				empty++
+				continue
+			}
+			if empty > 0 {
+				sb.WriteByte(byte('0' + empty))
+				empty = 0
+			}
+			sb.WriteByte(piece.Letter())
+		}
+		if empty > 0 {
+			sb.WriteByte(byte('0' + empty))
+		}
+		if rank > 0 {
+			sb.WriteByte('/')
+		}
+	}
+	return sb.String()
+}
+
+// FEN serializes the position
+func (p *Position) FEN() string {
+	turn := "w"
+	if p.Turn == Black {
+		turn = "b"
+	}
+
+	castling := ""
+	if p.Castling&WhiteKingside != 0 {
+		castling += "K"
+	}
+	if p.Castling&WhiteQueenside != 0 {
+		castling += "Q"
+	}
+	if p.Castling&BlackKingside != 0 {
+		castling += "k"
+	}
+	if p.Castling&BlackQueenside != 0 {
+		castling += "q"
+	}
+	if castling == "" {
+		castling = "-"
+	}
+
+	return fmt.Sprintf("%s %s %s %s %d %d", p.BoardFEN(), turn, castling, p.EnPassant, p.HalfmoveClock, p.FullmoveNumber)
+}
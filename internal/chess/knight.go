@@ -0,0 +1,51 @@
+package chess
+
+// IsKnightMove reports whether a knight can move from a to b in one hop
+func IsKnightMove(a, b Square) bool {
+	df, dr := abs(a.File()-b.File()), abs(a.Rank()-b.Rank())
+	return (df == 1 && dr == 2) || (df == 2 && dr == 1)
+}
+
+// KnightDistances returns the minimum number of knight moves from sq to
+// every square of an empty board, found by breadth-first search
+func KnightDistances(from Square) [64]int {
+	dist, _ := knightBFS(from)
+	return dist
+}
+
+// KnightPath returns one shortest knight route from a to b on an empty
+// board. The route excludes a and ends with b; it is empty when a == b.
+func KnightPath(from, to Square) []Square {
+	_, parent := knightBFS(from)
+	var path []Square
+	for sq := to; sq != from; sq = parent[sq] {
+		path = append(path, sq)
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+func knightBFS(from Square) (dist [64]int, parent [64]Square) {
+	for i := range dist {
+		dist[i] = -1
+		parent[i] = NoSquare
+	}
+	dist[from] = 0
+	queue := []Square{from}
+	for len(queue) > 0 {
+		sq := queue[0]
+		queue = queue[1:]
+		for _, o := range knightOffsets {
+			next, ok := sq.Offset(o[0], o[1])
+			if !ok || dist[next] >= 0 {
+				continue
+			}
+			dist[next] = dist[sq] + 1
+			parent[next] = sq
+			queue = append(queue, next)
+		}
+	}
+	return dist, parent
+}
@@ -0,0 +1,134 @@
+package chess
+
+import "fmt"
+
+// Move is a move from one square to another, with an optional promotion piece
+type Move struct {
+	From      Square
+	To        Square
+	Promotion PieceType
+}
+
+// ParseUCI converts long algebraic notation such as "e2e4" or "e7e8q" into a Move
+func ParseUCI(s string) (Move, error) {
+	if len(s) != 4 && len(s) != 5 {
+		return Move{}, fmt.Errorf("invalid UCI move %q", s)
+	}
+	from, err := ParseSquare(s[0:2])
+	if err != nil {
+		return Move{}, err
+	}
+	to, err := ParseSquare(s[2:4])
+	if err != nil {
+		return Move{}, err
+	}
+	m := Move{From: from, To: to}
+	if len(s) == 5 {
+		piece, ok := pieceFromLetter(s[4])
+		if !ok || piece.Type == Pawn || piece.Type == King {
+			return Move{}, fmt.Errorf("invalid promotion in UCI move %q", s)
+		}
+		m.Promotion = piece.Type
+	}
+	return m, nil
+}
+
+// UCI returns the move in long algebraic notation
+func (m Move) UCI() string {
+	s := m.From.String() + m.To.String()
+	if m.Promotion != NoPieceType {
+		s += string(pieceLetters[m.Promotion])
+	}
+	return s
+}
+
+func (m Move) String() string {
+	return m.UCI()
+}
+
+// IsCapture reports whether the move captures a piece, including en passant
+func (p *Position) IsCapture(m Move) bool {
+	return !p.board[m.To].IsEmpty() || p.IsEnPassant(m)
+}
+
+// IsEnPassant reports whether the move is an en passant capture
+func (p *Position) IsEnPassant(m Move) bool {
+	return p.board[m.From].Type == Pawn && m.To == p.EnPassant && m.From.File() != m.To.File()
+}
+
+// IsCastling reports whether the move is a castling move
+func (p *Position) IsCastling(m Move) bool {
+	return p.board[m.From].Type == King && abs(m.To.File()-m.From.File()) == 2
+}
+
+// Play returns the position after the move. The move is assumed to be at
+// least pseudo-legal; use IsLegal to check it first.
+func (p *Position) Play(m Move) *Position {
+	next := p.Clone()
+	piece := next.board[m.From]
+	captured := next.board[m.To]
+
+	next.board[m.From] = NoPiece
+	next.EnPassant = NoSquare
+
+	switch {
+	case piece.Type == Pawn && m.To == p.EnPassant && m.From.File() != m.To.File():
+		next.board[NewSquare(m.To.File(), m.From.Rank())] = NoPiece
+	case piece.Type == Pawn && abs(m.To.Rank()-m.From.Rank()) == 2:
+		next.EnPassant = NewSquare(m.From.File(), (m.From.Rank()+m.To.Rank())/2)
+	case piece.Type == King && abs(m.To.File()-m.From.File()) == 2:
+		rank := m.From.Rank()
+		if m.To.File() == 6 {
+			next.board[NewSquare(5, rank)] = next.board[NewSquare(7, rank)]
+			next.board[NewSquare(7, rank)] = NoPiece
+		} else {
+			next.board[NewSquare(3, rank)] = next.board[NewSquare(0, rank)]
+			next.board[NewSquare(0, rank)] = NoPiece
+		}
+	}
+
+	if m.Promotion != NoPieceType {
+		piece.Type = m.Promotion
+	}
+	next.board[m.To] = piece
+
+	next.Castling &^= castlingLostAt(m.From) | castlingLostAt(m.To)
+
+	if piece.Type == Pawn || !captured.IsEmpty() {
+		next.HalfmoveClock = 0
+	} else {
+		next.HalfmoveClock++
+	}
+	if p.Turn == Black {
+		next.FullmoveNumber++
+	}
+	next.Turn = p.Turn.Other()
+
+	return next
+}
+
+// castlingLostAt returns the rights lost when a piece moves from or to sq
+func castlingLostAt(sq Square) CastlingRights {
+	switch sq {
+	case NewSquare(4, 0):
+		return WhiteKingside | WhiteQueenside
+	case NewSquare(7, 0):
+		return WhiteKingside
+	case NewSquare(0, 0):
+		return WhiteQueenside
+	case NewSquare(4, 7):
+		return BlackKingside | BlackQueenside
+	case NewSquare(7, 7):
+		return BlackKingside
+	case NewSquare(0, 7):
+		return BlackQueenside
+	}
+	return NoCastling
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
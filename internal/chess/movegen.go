@@ -0,0 +1,302 @@
+package chess
+
+var (
+	knightOffsets = [8][2]int{{1, 2}, {2, 1}, {2, -1}, {1, -2}, {-1, -2}, {-2, -1}, {-2, 1}, {-1, 2}}
+	kingOffsets   = [8][2]int{{0, 1}, {1, 1}, {1, 0}, {1, -1}, {0, -1}, {-1, -1}, {-1, 0}, {-1, 1}}
+	bishopDirs    = [4][2]int{{1, 1}, {1, -1}, {-1, -1}, {-1, 1}}
+	rookDirs      = [4][2]int{{0, 1}, {1, 0}, {0, -1}, {-1, 0}}
+	promotions    = []PieceType{Queen, Rook, Bishop, Knight}
+)
+
+// pawnDir returns the rank direction pawns of the given color move in
+func pawnDir(c Color) int {
+	if c == White {
+		return 1
+	}
+	return -1
+}
+
+// Attackers returns the squares of all pieces of color by that attack sq
+func (p *Position) Attackers(sq Square, by Color) []Square {
+	var attackers []Square
+	p.eachAttacker(sq, by, func(from Square) bool {
+		attackers = append(attackers, from)
+		return true
+	})
+	return attackers
+}
+
+// eachAttacker calls yield with each square of a piece of color by that
+// attacks sq, until yield returns false. It reports whether it stopped
+// early.
+func (p *Position) eachAttacker(sq Square, by Color, yield func(Square) bool) bool {
+	// A pawn of color by attacks sq if it sits one rank "behind" it diagonally
+	for _, df := range [2]int{-1, 1} {
+		if from, ok := sq.Offset(df, -pawnDir(by)); ok && p.board[from] == (Piece{Type: Pawn, Color: by}) && !yield(from) {
+			return true
+		}
+	}
+
+	for _, o := range knightOffsets {
+		if from, ok := sq.Offset(o[0], o[1]); ok && p.board[from] == (Piece{Type: Knight, Color: by}) && !yield(from) {
+			return true
+		}
+	}
+
+	for _, o := range kingOffsets {
+		if from, ok := sq.Offset(o[0], o[1]); ok && p.board[from] == (Piece{Type: King, Color: by}) && !yield(from) {
+			return true
+		}
+	}
+
+	for _, d := range bishopDirs {
+		if from, ok := p.firstPiece(sq, d); ok {
+			piece := p.board[from]
+			if piece.Color == by && (piece.Type == Bishop || piece.Type == Queen) && !yield(from) {
+				return true
+			}
+		}
+	}
+
+	for _, d := range rookDirs {
+		if from, ok := p.firstPiece(sq, d); ok {
+			piece := p.board[from]
+			if piece.Color == by && (piece.Type == Rook || piece.Type == Queen) && !yield(from) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// firstPiece walks from sq in direction d and returns the first occupied square
+func (p *Position) firstPiece(sq Square, d [2]int) (Square, bool) {
+	cur := sq
+	for {
+		next, ok := cur.Offset(d[0], d[1])
+		if !ok {
+			return NoSquare, false
+		}
+		if !p.board[next].IsEmpty() {
+			return next, true
+		}
+		cur = next
+	}
+}
+
+// IsAttacked reports whether any piece of color by attacks sq. Unlike
+// Attackers it stops at the first attacker and does not allocate.
+func (p *Position) IsAttacked(sq Square, by Color) bool {
+	return p.eachAttacker(sq, by, func(Square) bool { return false })
+}
+
+// Checkers returns the pieces giving check to the side to move
+func (p *Position) Checkers() []Square {
+	king := p.KingSquare(p.Turn)
+	if king == NoSquare {
+		return nil
+	}
+	return p.Attackers(king, p.Turn.Other())
+}
+
+// InCheck reports whether the side to move is in check
+func (p *Position) InCheck() bool {
+	return len(p.Checkers()) > 0
+}
+
+// kingAttacked reports whether the given side's king is attacked. A side
+// without a king is never in check, which lets drills use lone pieces.
+func (p *Position) kingAttacked(c Color) bool {
+	king := p.KingSquare(c)
+	return king != NoSquare && p.IsAttacked(king, c.Other())
+}
+
+// PseudoLegalMoves returns all moves for the side to move that follow piece
+// movement rules, without checking whether they leave the king in check
+func (p *Position) PseudoLegalMoves() []Move {
+	var moves []Move
+	for sq := Square(0); sq < 64; sq++ {
+		if piece := p.board[sq]; !piece.IsEmpty() && piece.Color == p.Turn {
+			moves = p.appendPieceMoves(moves, sq)
+		}
+	}
+	return moves
+}
+
+func (p *Position) appendPieceMoves(moves []Move, from Square) []Move {
+	piece := p.board[from]
+	switch piece.Type {
+	case Pawn:
+		return p.appendPawnMoves(moves, from)
+	case Knight:
+		return p.appendStepMoves(moves, from, knightOffsets[:])
+	case Bishop:
+		return p.appendSlideMoves(moves, from, bishopDirs[:])
+	case Rook:
+		return p.appendSlideMoves(moves, from, rookDirs[:])
+	case Queen:
+		moves = p.appendSlideMoves(moves, from, bishopDirs[:])
+		return p.appendSlideMoves(moves, from, rookDirs[:])
+	case King:
+		moves = p.appendStepMoves(moves, from, kingOffsets[:])
+		return p.appendCastlingMoves(moves, from)
+	}
+	return moves
+}
+
+func (p *Position) appendStepMoves(moves []Move, from Square, offsets [][2]int) []Move {
+	color := p.board[from].Color
+	for _, o := range offsets {
+		to, ok := from.Offset(o[0], o[1])
+		if !ok {
+			continue
+		}
+		if target := p.board[to]; target.IsEmpty() || target.Color != color {
+			moves = append(moves, Move{From: from, To: to})
+		}
+	}
+	return moves
+}
+
+func (p *Position) appendSlideMoves(moves []Move, from Square, dirs [][2]int) []Move {
+	color := p.board[from].Color
+	for _, d := range dirs {
+		cur := from
+		for {
+			to, ok := cur.Offset(d[0], d[1])
+			if !ok {
+				break
+			}
+			target := p.board[to]
+			if target.IsEmpty() {
+				moves = append(moves, Move{From: from, To: to})
+				cur = to
+				continue
+			}
+			if target.Color != color {
+				moves = append(moves, Move{From: from, To: to})
+			}
+			break
+		}
+	}
+	return moves
+}
+
+func (p *Position) appendPawnMoves(moves []Move, from Square) []Move {
+	color := p.board[from].Color
+	dir := pawnDir(color)
+	startRank, lastRank := 1, 7
+	if color == Black {
+		startRank, lastRank = 6, 0
+	}
+
+	add := func(to Square) {
+		if to.Rank() == lastRank {
+			for _, promo := range promotions {
+				moves = append(moves, Move{From: from, To: to, Promotion: promo})
+			}
+			return
+		}
+		moves = append(moves, Move{From: from, To: to})
+	}
+
+	if one, ok := from.Offset(0, dir); ok && p.board[one].IsEmpty() {
+		add(one)
+		if from.Rank() == startRank {
+			if two, ok := from.Offset(0, 2*dir); ok && p.board[two].IsEmpty() {
+				add(two)
+			}
+		}
+	}
+
+	for _, df := range []int{-1, 1} {
+		to, ok := from.Offset(df, dir)
+		if !ok {
+			continue
+		}
+		target := p.board[to]
+		if (!target.IsEmpty() && target.Color != color) || (to == p.EnPassant && target.IsEmpty()) {
+			add(to)
+		}
+	}
+
+	return moves
+}
+
+func (p *Position) appendCastlingMoves(moves []Move, from Square) []Move {
+	color := p.board[from].Color
+	rank := 0
+	if color == Black {
+		rank = 7
+	}
+	if from != NewSquare(4, rank) {
+		return moves
+	}
+
+	if _, err := p.castlingObstacle(CastlingRight(color, true)); err == nil {
+		moves = append(moves, Move{From: from, To: NewSquare(6, rank)})
+	}
+	if _, err := p.castlingObstacle(CastlingRight(color, false)); err == nil {
+		moves = append(moves, Move{From: from, To: NewSquare(2, rank)})
+	}
+	return moves
+}
+
+// LegalMoves returns all legal moves for the side to move
+func (p *Position) LegalMoves() []Move {
+	return p.filterLegal(p.PseudoLegalMoves())
+}
+
+// LegalMovesFrom returns the legal moves of the piece on sq. The piece must
+// belong to the side to move.
+func (p *Position) LegalMovesFrom(sq Square) []Move {
+	piece := p.board[sq]
+	if piece.IsEmpty() || piece.Color != p.Turn {
+		return nil
+	}
+	return p.filterLegal(p.appendPieceMoves(nil, sq))
+}
+
+func (p *Position) filterLegal(moves []Move) []Move {
+	legal := moves[:0]
+	for _, m := range moves {
+		if !p.Play(m).kingAttacked(p.Turn) {
+			legal = append(legal, m)
+		}
+	}
+	return legal
+}
+
+// IsLegal reports whether m is a legal move in this position
+func (p *Position) IsLegal(m Move) bool {
+	for _, lm := range p.LegalMovesFrom(m.From) {
+		if lm == m {
+			return true
+		}
+	}
+	return false
+}
+
+// IsCheckmate reports whether the side to move is checkmated
+func (p *Position) IsCheckmate() bool {
+	return p.InCheck() && len(p.LegalMoves()) == 0
+}
+
+// IsStalemate reports whether the side to move has no legal moves but is not in check
+func (p *Position) IsStalemate() bool {
+	return !p.InCheck() && len(p.LegalMoves()) == 0
+}
+
+// Destinations returns the distinct target squares of the given moves
+func Destinations(moves []Move) []Square {
+	seen := make(map[Square]bool)
+	var squares []Square
+	for _, m := range moves {
+		if !seen[m.To] {
+			seen[m.To] = true
+			squares = append(squares, m.To)
+		}
+	}
+	return squares
+}
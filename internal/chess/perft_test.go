@@ -0,0 +1,86 @@
+package chess
+
+import "testing"
+
+// perft counts the leaf nodes of the legal move tree to a depth
+func perft(p *Position, depth int) int {
+	moves := p.LegalMoves()
+	if depth == 1 {
+		return len(moves)
+	}
+	nodes := 0
+	for _, m := range moves {
+		nodes += perft(p.Play(m), depth-1)
+	}
+	return nodes
+}
+
+// The standard perft positions, with their node counts by depth
+var perftTests = []struct {
+	name  string
+	fen   string
+	nodes []int
+}{
+	{
+		name:  "start",
+		fen:   StartFEN,
+		nodes: []int{20, 400, 8902, 197281},
+	},
+	{
+		name:  "kiwipete",
+		fen:   "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		nodes: []int{48, 2039, 97862},
+	},
+	{
+		name:  "position 3",
+		fen:   "8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+		nodes: []int{14, 191, 2812, 43238},
+	},
+	{
+		name:  "position 4",
+		fen:   "r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1",
+		nodes: []int{6, 264, 9467},
+	},
+	{
+		name:  "position 5",
+		fen:   "rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ - 1 8",
+		nodes: []int{44, 1486, 62379},
+	},
+	{
+		name:  "position 6",
+		fen:   "r4rk1/1pp1qppp/p1np1n2/2b1p1B1/2B1P1b1/P1NP1N2/1PP1QPPP/R4RK1 w - - 0 10",
+		nodes: []int{46, 2079, 89890},
+	},
+}
+
+func TestPerft(t *testing.T) {
+	for _, tt := range perftTests {
+		t.Run(tt.name, func(t *testing.T) {
+			pos, err := ParseFEN(tt.fen)
+			if err != nil {
+				t.Fatalf("ParseFEN: %v", err)
+			}
+			for i, want := range tt.nodes {
+				depth := i + 1
+				if testing.Short() && want > 10000 {
+					break
+				}
+				if got := perft(pos, depth); got != want {
+					t.Errorf("perft(%d) = %d, want %d", depth, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestFENRoundTrip(t *testing.T) {
+	for _, tt := range perftTests {
+		pos, err := ParseFEN(tt.fen)
+		if err != nil {
+			t.Fatalf("ParseFEN(%q): %v", tt.fen, err)
+		}
+		if got := pos.FEN(); got != tt.fen {
+			t.Errorf("FEN() = %q, want %q", got, tt.fen)
+		}
+	}
+}
@@ -0,0 +1,347 @@
+package chess
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidPGN = errors.New("invalid PGN")
+
+// PGNGame is one game of a PGN file. Moves holds the game's first moves:
+// the main line's first, then any alternatives given as variations.
+type PGNGame struct {
+	Tags   map[string]string
+	Moves  []*PGNMove
+	Result string
+}
+
+// PGNMove is a move in a game's tree. Next holds the moves that follow it,
+// the main line first, then its variations.
+type PGNMove struct {
+	Move    Move
+	SAN     string
+	Comment string
+	NAGs    []int
+	Next    []*PGNMove
+}
+
+// ParsePGN parses the games of a PGN file, with their tags, comments, NAGs
+// and nested variations. Every move is checked against the position it is
+// played in, which starts from the game's FEN tag if it has one.
+func ParsePGN(pgn string) ([]*PGNGame, error) {
+	s := &pgnScanner{src: pgn, line: 1}
+	var games []*PGNGame
+	var p *pgnParser
+	for {
+		tok, err := s.next()
+		if err != nil {
+			return nil, err
+		}
+		if tok.kind == pgnEOF {
+			break
+		}
+
+		// Tags after movetext begin the next game, even without a result, as
+		// do tags after a blank line, which ends a game of tags only
+		if tok.kind == pgnTag && p != nil && (p.pos != nil || tok.afterBlank) {
+			if err := p.finish(tok.line); err != nil {
+				return nil, err
+			}
+			games = append(games, p.game)
+			p = nil
+		}
+		if p == nil {
+			p = &pgnParser{game: &PGNGame{Tags: make(map[string]string)}}
+		}
+
+		done, err := p.token(tok)
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidPGN, tok.line, err)
+		}
+		if done {
+			games = append(games, p.game)
+			p = nil
+		}
+	}
+	if p != nil {
+		if err := p.finish(s.line); err != nil {
+			return nil, err
+		}
+		games = append(games, p.game)
+	}
+	return games, nil
+}
+
+// pgnParser builds the move tree of one game. The current line's next move
+// follows parent from pos; starting a variation goes back to the position
+// before the line's last move.
+type pgnParser struct {
+	game *PGNGame
+
+	pos    *Position
+	parent *PGNMove
+
+	last       *PGNMove
+	lastParent *PGNMove
+	lastPos    *Position
+
+	stack []pgnParser
+}
+
+// token applies a token to the game and reports whether it ended the game
+func (p *pgnParser) token(tok pgnToken) (bool, error) {
+	if tok.kind == pgnTag {
+		p.game.Tags[tok.name] = tok.text
+		return false, nil
+	}
+	if p.pos == nil {
+		if err := p.start(); err != nil {
+			return false, err
+		}
+	}
+
+	switch tok.kind {
+	case pgnComment:
+		if p.last != nil {
+			p.last.Comment = strings.TrimSpace(p.last.Comment + " " + tok.text)
+		}
+	case pgnNAG:
+		if p.last != nil {
+			nag, _ := strconv.Atoi(tok.text)
+			p.last.NAGs = append(p.last.NAGs, nag)
+		}
+	case pgnOpen:
+		if p.last == nil {
+			return false, errors.New("variation before any move")
+		}
+		saved := *p
+		saved.stack = nil
+		p.stack = append(p.stack, saved)
+		p.pos, p.parent = p.lastPos, p.lastParent
+		p.last, p.lastParent, p.lastPos = nil, nil, nil
+	case pgnClose:
+		if len(p.stack) == 0 {
+			return false, errors.New("unmatched )")
+		}
+		stack := p.stack[:len(p.stack)-1]
+		*p = p.stack[len(p.stack)-1]
+		p.stack = stack
+	case pgnSymbol:
+		return p.symbol(tok.text)
+	}
+	return false, nil
+}
+
+// symbol handles a move, move number or game result
+func (p *pgnParser) symbol(sym string) (bool, error) {
+	switch sym {
+	case "1-0", "0-1", "1/2-1/2", "*":
+		if len(p.stack) > 0 {
+			return false, errors.New("result inside a variation")
+		}
+		p.game.Result = sym
+		return true, nil
+	case "e.p.":
+		return false, nil
+	}
+
+	// Move numbers may be written against the move, as in "1.e4"
+	digits := len(sym) - len(strings.TrimLeft(sym, "0123456789"))
+	if digits > 0 && strings.HasPrefix(sym[digits:], ".") {
+		sym = strings.TrimLeft(sym[digits:], ".")
+	}
+	// Annotations may stand apart from their move
+	if strings.Trim(sym, "!?") == "" {
+		return false, nil
+	}
+
+	m, err := p.pos.ParseSAN(sym)
+	if err != nil {
+		return false, err
+	}
+	move := &PGNMove{Move: m, SAN: p.pos.SAN(m)}
+	if p.parent == nil {
+		p.game.Moves = append(p.game.Moves, move)
+	} else {
+		p.parent.Next = append(p.parent.Next, move)
+	}
+	p.last, p.lastParent, p.lastPos = move, p.parent, p.pos
+	p.parent, p.pos = move, p.pos.Play(m)
+	return false, nil
+}
+
+// start sets up the game's starting position once its movetext begins
+func (p *pgnParser) start() error {
+	fen, ok := p.game.Tags["FEN"]
+	if !ok {
+		p.pos = StartingPosition()
+		return nil
+	}
+	pos, err := ParseFEN(fen)
+	if err != nil {
+		return err
+	}
+	p.pos = pos
+	return nil
+}
+
+// finish checks a game ended outside any variation
+func (p *pgnParser) finish(line int) error {
+	if len(p.stack) > 0 {
+		return fmt.Errorf("%w: line %d: unclosed variation", ErrInvalidPGN, line)
+	}
+	return nil
+}
+
+type pgnTokenKind int
+
+const (
+	pgnEOF pgnTokenKind = iota
+	pgnTag
+	pgnComment
+	pgnNAG
+	pgnOpen
+	pgnClose
+	pgnSymbol
+)
+
+// pgnToken is a token of PGN text. Tags carry their name and value; other
+// tokens their text. afterBlank is set on a token preceded by a blank line.
+type pgnToken struct {
+	kind       pgnTokenKind
+	name       string
+	text       string
+	line       int
+	afterBlank bool
+}
+
+type pgnScanner struct {
+	src  string
+	pos  int
+	line int
+
+	// newlines counts the line breaks since the last token
+	newlines int
+}
+
+func (s *pgnScanner) next() (pgnToken, error) {
+	tok, err := s.scan()
+	tok.afterBlank = s.newlines > 1
+	s.newlines = 0
+	return tok, err
+}
+
+func (s *pgnScanner) scan() (pgnToken, error) {
+	for s.pos < len(s.src) {
+		c := s.src[s.pos]
+		switch {
+		case c == '\n':
+			s.line++
+			s.newlines++
+			s.pos++
+		case c == ' ' || c == '\t' || c == '\r':
+			s.pos++
+		case c == '%' && (s.pos == 0 || s.src[s.pos-1] == '\n'):
+			// Escaped lines are for other programs
+			s.skipLine()
+		case c == ';':
+			line := s.line
+			start := s.pos + 1
+			s.skipLine()
+			return pgnToken{kind: pgnComment, text: strings.TrimSpace(s.src[start:s.pos]), line: line}, nil
+		case c == '{':
+			return s.comment()
+		case c == '[':
+			return s.tag()
+		case c == '(':
+			s.pos++
+			return pgnToken{kind: pgnOpen, line: s.line}, nil
+		case c == ')':
+			s.pos++
+			return pgnToken{kind: pgnClose, line: s.line}, nil
+		case c == '$':
+			start := s.pos + 1
+			s.pos = start
+			for s.pos < len(s.src) && s.src[s.pos] >= '0' && s.src[s.pos] <= '9' {
+				s.pos++
+			}
+			if s.pos == start {
+				return pgnToken{}, fmt.Errorf("%w: line %d: bad NAG", ErrInvalidPGN, s.line)
+			}
+			return pgnToken{kind: pgnNAG, text: s.src[start:s.pos], line: s.line}, nil
+		default:
+			start := s.pos
+			for s.pos < len(s.src) && !strings.ContainsRune(" \t\r\n{}()[];$", rune(s.src[s.pos])) {
+				s.pos++
+			}
+			return pgnToken{kind: pgnSymbol, text: s.src[start:s.pos], line: s.line}, nil
+		}
+	}
+	return pgnToken{kind: pgnEOF, line: s.line}, nil
+}
+
+// skipLine moves to the end of the current line
+func (s *pgnScanner) skipLine() {
+	if end := strings.IndexByte(s.src[s.pos:], '\n'); end >= 0 {
+		s.pos += end
+	} else {
+		s.pos = len(s.src)
+	}
+}
+
+// comment reads a brace comment, which may span lines
+func (s *pgnScanner) comment() (pgnToken, error) {
+	line := s.line
+	end := strings.IndexByte(s.src[s.pos:], '}')
+	if end < 0 {
+		return pgnToken{}, fmt.Errorf("%w: line %d: unclosed comment", ErrInvalidPGN, line)
+	}
+	text := s.src[s.pos+1 : s.pos+end]
+	s.line += strings.Count(text, "\n")
+	s.pos += end + 1
+	return pgnToken{kind: pgnComment, text: strings.Join(strings.Fields(text), " "), line: line}, nil
+}
+
+// tag reads a tag pair such as [Event "Casual game"]
+func (s *pgnScanner) tag() (pgnToken, error) {
+	line := s.line
+	bad := fmt.Errorf("%w: line %d: bad tag", ErrInvalidPGN, line)
+
+	s.pos++
+	start := s.pos
+	for s.pos < len(s.src) && s.src[s.pos] != '"' && s.src[s.pos] != ']' && s.src[s.pos] != '\n' {
+		s.pos++
+	}
+	name := strings.TrimSpace(s.src[start:s.pos])
+	if name == "" || strings.ContainsAny(name, " \t") || s.pos == len(s.src) || s.src[s.pos] != '"' {
+		return pgnToken{}, bad
+	}
+
+	var value strings.Builder
+	for s.pos++; ; s.pos++ {
+		if s.pos >= len(s.src) || s.src[s.pos] == '\n' {
+			return pgnToken{}, bad
+		}
+		c := s.src[s.pos]
+		if c == '"' {
+			break
+		}
+		if c == '\\' && s.pos+1 < len(s.src) && (s.src[s.pos+1] == '"' || s.src[s.pos+1] == '\\') {
+			s.pos++
+			c = s.src[s.pos]
+		}
+		value.WriteByte(c)
+	}
+
+	s.pos++
+	for s.pos < len(s.src) && (s.src[s.pos] == ' ' || s.src[s.pos] == '\t') {
+		s.pos++
+	}
+	if s.pos == len(s.src) || s.src[s.pos] != ']' {
+		return pgnToken{}, bad
+	}
+	s.pos++
+	return pgnToken{kind: pgnTag, name: name, text: value.String(), line: line}, nil
+}
@@ -0,0 +1,213 @@
+package chess
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// mainLine returns the SAN of a game's main line
+func mainLine(moves []*PGNMove) []string {
+	var sans []string
+	for len(moves) > 0 {
+		sans = append(sans, moves[0].SAN)
+		moves = moves[0].Next
+	}
+	return sans
+}
+
+// follow walks a game's tree by SAN, failing the test if a move is missing
+func follow(t *testing.T, moves []*PGNMove, sans ...string) *PGNMove {
+	t.Helper()
+	var move *PGNMove
+	for _, san := range sans {
+		i := slices.IndexFunc(moves, func(m *PGNMove) bool { return m.SAN == san })
+		if i < 0 {
+			t.Fatalf("no move %s after %v", san, sans)
+		}
+		move = moves[i]
+		moves = move.Next
+	}
+	return move
+}
+
+func TestParsePGNVariations(t *testing.T) {
+	pgn := `[Event "Sicilian prep"]
+
+1. e4 c5 (1... e5 2. Nf3 (2. f4 exf4) 2... Nc6 (2... d6 3. d4)) (1... c6) 2. Nf3 d6 *`
+
+	games, err := ParsePGN(pgn)
+	if err != nil {
+		t.Fatalf("ParsePGN: %v", err)
+	}
+	if len(games) != 1 {
+		t.Fatalf("got %d games, want 1", len(games))
+	}
+	game := games[0]
+
+	if got, want := mainLine(game.Moves), []string{"e4", "c5", "Nf3", "d6"}; !slices.Equal(got, want) {
+		t.Errorf("main line = %v, want %v", got, want)
+	}
+	e4 := follow(t, game.Moves, "e4")
+	var replies []string
+	for _, m := range e4.Next {
+		replies = append(replies, m.SAN)
+	}
+	if want := []string{"c5", "e5", "c6"}; !slices.Equal(replies, want) {
+		t.Errorf("replies to 1.e4 = %v, want %v", replies, want)
+	}
+
+	follow(t, game.Moves, "e4", "e5", "f4", "exf4")
+	follow(t, game.Moves, "e4", "e5", "Nf3", "Nc6")
+	if m := follow(t, game.Moves, "e4", "e5", "Nf3", "d6", "d4"); m.Move.UCI() != "d2d4" {
+		t.Errorf("3.d4 = %s, want d2d4", m.Move.UCI())
+	}
+	if game.Result != "*" {
+		t.Errorf("result = %q, want *", game.Result)
+	}
+}
+
+func TestParsePGNAnnotations(t *testing.T) {
+	pgn := `1.e4 {Best by test} e5 $1 2.Nf3 !? Nc6 ; the main line
+{ again
+over two lines } 3.Bb5 $13 $14 1-0`
+
+	games, err := ParsePGN(pgn)
+	if err != nil {
+		t.Fatalf("ParsePGN: %v", err)
+	}
+	moves := games[0].Moves
+
+	if got := follow(t, moves, "e4").Comment; got != "Best by test" {
+		t.Errorf("1.e4 comment = %q", got)
+	}
+	if got := follow(t, moves, "e4", "e5").NAGs; !slices.Equal(got, []int{1}) {
+		t.Errorf("1...e5 NAGs = %v, want [1]", got)
+	}
+	nc6 := follow(t, moves, "e4", "e5", "Nf3", "Nc6")
+	if want := "the main line again over two lines"; nc6.Comment != want {
+		t.Errorf("2...Nc6 comment = %q, want %q", nc6.Comment, want)
+	}
+	if got := follow(t, moves, "e4", "e5", "Nf3", "Nc6", "Bb5").NAGs; !slices.Equal(got, []int{13, 14}) {
+		t.Errorf("3.Bb5 NAGs = %v, want [13 14]", got)
+	}
+	if games[0].Result != "1-0" {
+		t.Errorf("result = %q, want 1-0", games[0].Result)
+	}
+}
+
+func TestParsePGNGames(t *testing.T) {
+	pgn := `[Event "First"]
+[White "Player \"One\""]
+
+1. d4 d5 1/2-1/2
+
+[Event "Second"]
+
+1. c4 e5 *
+[Event "No result"]
+1. Nf3
+% an escaped line 1. e4
+[Event "Last"]
+1. g3 0-1`
+
+	games, err := ParsePGN(pgn)
+	if err != nil {
+		t.Fatalf("ParsePGN: %v", err)
+	}
+	want := []struct {
+		event string
+		moves []string
+	}{
+		{"First", []string{"d4", "d5"}},
+		{"Second", []string{"c4", "e5"}},
+		{"No result", []string{"Nf3"}},
+		{"Last", []string{"g3"}},
+	}
+	if len(games) != len(want) {
+		t.Fatalf("got %d games, want %d", len(games), len(want))
+	}
+	for i, w := range want {
+		if got := games[i].Tags["Event"]; got != w.event {
+			t.Errorf("game %d event = %q, want %q", i, got, w.event)
+		}
+		if got := mainLine(games[i].Moves); !slices.Equal(got, w.moves) {
+			t.Errorf("game %d moves = %v, want %v", i, got, w.moves)
+		}
+	}
+	if got := games[0].Tags["White"]; got != `Player "One"` {
+		t.Errorf("escaped tag = %q", got)
+	}
+}
+
+func TestParsePGNTagsOnly(t *testing.T) {
+	pgn := `[Event "Empty"]
+[Site "Nowhere"]
+
+[Event "Game"]
+
+1. e4 *`
+
+	games, err := ParsePGN(pgn)
+	if err != nil {
+		t.Fatalf("ParsePGN: %v", err)
+	}
+	if len(games) != 2 {
+		t.Fatalf("got %d games, want 2", len(games))
+	}
+	if games[0].Tags["Event"] != "Empty" || len(games[0].Moves) != 0 {
+		t.Errorf("first game = %v with %d moves, want Empty with none", games[0].Tags, len(games[0].Moves))
+	}
+	if _, ok := games[1].Tags["Site"]; ok || games[1].Tags["Event"] != "Game" {
+		t.Errorf("second game tags = %v, want only Event Game", games[1].Tags)
+	}
+	if got := mainLine(games[1].Moves); !slices.Equal(got, []string{"e4"}) {
+		t.Errorf("second game moves = %v, want [e4]", got)
+	}
+}
+
+func TestParsePGNFEN(t *testing.T) {
+	pgn := `[SetUp "1"]
+[FEN "4k3/8/8/8/8/8/8/R3K3 w Q - 0 1"]
+
+1. O-O-O Kf7 *`
+
+	games, err := ParsePGN(pgn)
+	if err != nil {
+		t.Fatalf("ParsePGN: %v", err)
+	}
+	if got := mainLine(games[0].Moves); !slices.Equal(got, []string{"O-O-O", "Kf7"}) {
+		t.Errorf("moves = %v", got)
+	}
+}
+
+func TestParsePGNErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		pgn  string
+		line string
+	}{
+		{"illegal move", "1. e4 e5 2. Ke3 *", "line 1"},
+		{"illegal move on a later line", "1. e4 e5\n2. Nf3 Nf6\n3. Qh8 *", "line 3"},
+		{"variation before any move", "( 1. e4 ) *", "line 1"},
+		{"unmatched close", "1. e4 ) *", "line 1"},
+		{"unclosed variation", "1. e4 (1. d4", "unclosed variation"},
+		{"result inside a variation", "1. e4 (1. d4 *) *", "line 1"},
+		{"unclosed comment", "1. e4 { no end", "unclosed comment"},
+		{"bad tag", "[Event Casual]\n1. e4 *", "bad tag"},
+		{"bad NAG", "1. e4 $ *", "bad NAG"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParsePGN(tt.pgn)
+			if !errors.Is(err, ErrInvalidPGN) {
+				t.Fatalf("error = %v, want ErrInvalidPGN", err)
+			}
+			if !strings.Contains(err.Error(), tt.line) {
+				t.Errorf("error = %q, want it to mention %q", err, tt.line)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package chess
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrInvalidSAN   = errors.New("invalid SAN")
+	ErrIllegalMove  = errors.New("illegal move")
+	ErrAmbiguousSAN = errors.New("ambiguous SAN")
+)
+
+// SAN returns the move in standard algebraic notation, including
+// disambiguation, capture marker, promotion and check or mate suffix.
+// The move must be legal in the position.
+func (p *Position) SAN(m Move) string {
+	san := p.sanWithoutSuffix(m)
+
+	next := p.Play(m)
+	if next.InCheck() {
+		if len(next.LegalMoves()) == 0 {
+			return san + "#"
+		}
+		return san + "+"
+	}
+	return san
+}
+
+func (p *Position) sanWithoutSuffix(m Move) string {
+	piece := p.board[m.From]
+
+	if p.IsCastling(m) {
+		if m.To.File() == 6 {
+			return "O-O"
+		}
+		return "O-O-O"
+	}
+
+	var sb strings.Builder
+	if piece.Type == Pawn {
+		if p.IsCapture(m) {
+			sb.WriteByte(byte('a' + m.From.File()))
+			sb.WriteByte('x')
+		}
+		sb.WriteString(m.To.String())
+		if m.Promotion != NoPieceType {
+			sb.WriteByte('=')
+			sb.WriteByte(Piece{Type: m.Promotion}.Letter())
+		}
+		return sb.String()
+	}
+
+	sb.WriteByte(Piece{Type: piece.Type}.Letter())
+
+	// Disambiguate against other pieces of the same type that can reach m.To
+	sameFile, sameRank, others := false, false, false
+	for _, other := range p.LegalMoves() {
+		if other.To != m.To || other.From == m.From || p.board[other.From] != piece {
+			continue
+		}
+		others = true
+		if other.From.File() == m.From.File() {
+			sameFile = true
+		}
+		if other.From.Rank() == m.From.Rank() {
+			sameRank = true
+		}
+	}
+	if others {
+		switch {
+		case !sameFile:
+			sb.WriteByte(byte('a' + m.From.File()))
+		case !sameRank:
+			sb.WriteByte(byte('1' + m.From.Rank()))
+		default:
+			sb.WriteString(m.From.String())
+		}
+	}
+
+	if p.IsCapture(m) {
+		sb.WriteByte('x')
+	}
+	sb.WriteString(m.To.String())
+	return sb.String()
+}
+
+// ParseSAN finds the legal move described by a SAN string. It is lenient
+// about equivalent spellings: check and mate suffixes, annotation marks,
+// "e.p.", a missing or extra capture marker, "0-0" for castling, promotion
+// with or without "=", and over-disambiguation such as "Ng1f3" are accepted.
+func (p *Position) ParseSAN(san string) (Move, error) {
+	s := strings.TrimSpace(san)
+	s = strings.TrimSuffix(s, "e.p.")
+	s = strings.TrimSpace(s)
+	s = strings.TrimRight(s, "+#!?")
+	if s == "" {
+		return Move{}, fmt.Errorf("%w: empty move", ErrInvalidSAN)
+	}
+
+	switch strings.ToUpper(strings.ReplaceAll(s, "0", "O")) {
+	case "O-O":
+		return p.findCastling(6, san)
+	case "O-O-O":
+		return p.findCastling(2, san)
+	}
+
+	pieceType := Pawn
+	if idx := strings.IndexByte("NBRQK", s[0]); idx >= 0 {
+		pieceType = []PieceType{Knight, Bishop, Rook, Queen, King}[idx]
+		s = s[1:]
+	}
+
+	promotion := NoPieceType
+	if n := len(s); n >= 3 && pieceType == Pawn {
+		if piece, ok := pieceFromLetter(s[n-1]); ok && s[n-2] != 'x' && (s[n-2] == '=' || (s[n-2] >= '1' && s[n-2] <= '8')) {
+			if piece.Type == Pawn || piece.Type == King {
+				return Move{}, fmt.Errorf("%w: bad promotion in %q", ErrInvalidSAN, san)
+			}
+			promotion = piece.Type
+			s = strings.TrimSuffix(s[:n-1], "=")
+		}
+	}
+
+	s = strings.NewReplacer("x", "", "X", "", "-", "", ":", "").Replace(s)
+	if len(s) < 2 || len(s) > 4 {
+		return Move{}, fmt.Errorf("%w: %q", ErrInvalidSAN, san)
+	}
+	to, err := ParseSquare(s[len(s)-2:])
+	if err != nil {
+		return Move{}, fmt.Errorf("%w: %q", ErrInvalidSAN, san)
+	}
+
+	fromFile, fromRank := -1, -1
+	for _, c := range s[:len(s)-2] {
+		switch {
+		case c >= 'a' && c <= 'h':
+			fromFile = int(c - 'a')
+		case c >= '1' && c <= '8':
+			fromRank = int(c - '1')
+		default:
+			return Move{}, fmt.Errorf("%w: %q", ErrInvalidSAN, san)
+		}
+	}
+
+	var matches []Move
+	for _, m := range p.LegalMoves() {
+		if m.To != to || p.board[m.From].Type != pieceType || m.Promotion != promotion || p.IsCastling(m) {
+			continue
+		}
+		if fromFile >= 0 && m.From.File() != fromFile {
+			continue
+		}
+		if fromRank >= 0 && m.From.Rank() != fromRank {
+			continue
+		}
+		matches = append(matches, m)
+	}
+
+	switch len(matches) {
+	case 0:
+		return Move{}, fmt.Errorf("%w: %q", ErrIllegalMove, san)
+	case 1:
+		return matches[0], nil
+	default:
+		return Move{}, fmt.Errorf("%w: %q", ErrAmbiguousSAN, san)
+	}
+}
+
+func (p *Position) findCastling(toFile int, san string) (Move, error) {
+	for _, m := range p.LegalMoves() {
+		if p.IsCastling(m) && m.To.File() == toFile {
+			return m, nil
+		}
+	}
+	return Move{}, fmt.Errorf("%w: %q", ErrIllegalMove, san)
+}
@@ -0,0 +1,128 @@
+package chess
+
+import (
+	"errors"
+	"testing"
+)
+
+// Every legal move's SAN parses back to the move, two plies deep from each
+// perft position
+func TestSANRoundTrip(t *testing.T) {
+	var check func(t *testing.T, pos *Position, depth int)
+	check = func(t *testing.T, pos *Position, depth int) {
+		for _, m := range pos.LegalMoves() {
+			san := pos.SAN(m)
+			got, err := pos.ParseSAN(san)
+			if err != nil {
+				t.Fatalf("%s: ParseSAN(%q): %v", pos.FEN(), san, err)
+			}
+			if got != m {
+				t.Fatalf("%s: ParseSAN(%q) = %s, want %s", pos.FEN(), san, got, m)
+			}
+			if depth > 1 {
+				check(t, pos.Play(m), depth-1)
+			}
+		}
+	}
+
+	for _, tt := range perftTests {
+		t.Run(tt.name, func(t *testing.T) {
+			pos, err := ParseFEN(tt.fen)
+			if err != nil {
+				t.Fatalf("ParseFEN: %v", err)
+			}
+			check(t, pos, 2)
+		})
+	}
+}
+
+func TestSAN(t *testing.T) {
+	tests := []struct {
+		fen  string
+		uci  string
+		want string
+	}{
+		{StartFEN, "e2e4", "e4"},
+		{StartFEN, "g1f3", "Nf3"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "e1g1", "O-O"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "e1c1", "O-O-O"},
+		{"4k3/8/8/8/8/8/8/R3K2R w KQ - 0 1", "a1a8", "Ra8+"},
+		{"6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1", "a1a8", "Ra8#"},
+		{"4k3/8/8/8/8/8/4K3/R6R w - - 0 1", "a1d1", "Rad1"},
+		{"4k3/8/8/8/8/8/4K3/R6R w - - 0 1", "h1f1", "Rhf1"},
+		{"4k3/8/8/N7/8/8/8/N3K3 w - - 0 1", "a1b3", "N1b3"},
+		{"4k3/8/8/8/Q6Q/8/8/Q3K3 w - - 0 1", "a4d4", "Qa4d4"},
+		{"4k3/8/8/8/8/8/8/N2QK3 w - - 0 1", "d1a4", "Qa4+"},
+		{"4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1", "e5d6", "exd6"},
+		{"3nk3/4P3/8/8/8/8/8/4K3 w - - 0 1", "e7d8q", "exd8=Q+"},
+		{"4k3/P7/8/8/8/8/8/4K3 w - - 0 1", "a7a8n", "a8=N"},
+	}
+
+	for _, tt := range tests {
+		pos, err := ParseFEN(tt.fen)
+		if err != nil {
+			t.Fatalf("ParseFEN(%q): %v", tt.fen, err)
+		}
+		m, err := ParseUCI(tt.uci)
+		if err != nil {
+			t.Fatalf("ParseUCI(%q): %v", tt.uci, err)
+		}
+		if got := pos.SAN(m); got != tt.want {
+			t.Errorf("%s: SAN(%s) = %q, want %q", tt.fen, tt.uci, got, tt.want)
+		}
+	}
+}
+
+func TestParseSANSpellings(t *testing.T) {
+	tests := []struct {
+		fen  string
+		san  string
+		want string
+	}{
+		{StartFEN, "Ng1f3", "g1f3"},
+		{StartFEN, "e4!?", "e2e4"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "0-0", "e1g1"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "O-O-O+", "e1c1"},
+		{"4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1", "exd6 e.p.", "e5d6"},
+		{"4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1", "ed6", "e5d6"},
+		{"4k3/P7/8/8/8/8/8/4K3 w - - 0 1", "a8Q", "a7a8q"},
+	}
+
+	for _, tt := range tests {
+		pos, err := ParseFEN(tt.fen)
+		if err != nil {
+			t.Fatalf("ParseFEN(%q): %v", tt.fen, err)
+		}
+		m, err := pos.ParseSAN(tt.san)
+		if err != nil {
+			t.Errorf("%s: ParseSAN(%q): %v", tt.fen, tt.san, err)
+			continue
+		}
+		if m.UCI() != tt.want {
+			t.Errorf("%s: ParseSAN(%q) = %s, want %s", tt.fen, tt.san, m.UCI(), tt.want)
+		}
+	}
+}
+
+func TestParseSANErrors(t *testing.T) {
+	tests := []struct {
+		fen  string
+		san  string
+		want error
+	}{
+		{StartFEN, "", ErrInvalidSAN},
+		{StartFEN, "e5", ErrIllegalMove},
+		{StartFEN, "O-O", ErrIllegalMove},
+		{"4k3/8/8/8/8/8/4K3/R6R w - - 0 1", "Rf1", ErrAmbiguousSAN},
+	}
+
+	for _, tt := range tests {
+		pos, err := ParseFEN(tt.fen)
+		if err != nil {
+			t.Fatalf("ParseFEN(%q): %v", tt.fen, err)
+		}
+		if _, err := pos.ParseSAN(tt.san); !errors.Is(err, tt.want) {
+			t.Errorf("%s: ParseSAN(%q) error = %v, want %v", tt.fen, tt.san, err, tt.want)
+		}
+	}
+}
@@ -13,6 +13,9 @@ type Config struct {
 	SessionSecret   string
 	SessionMaxAge   int
 	LogLevel        string
+
+	// LeaderboardRefreshMinutes is how often leaderboards are recomputed
+	LeaderboardRefreshMinutes int
 }
 
 func Load() *Config {
@@ -24,6 +27,8 @@ func Load() *Config {
 		SessionSecret:   getEnv("SESSION_SECRET", "change-this-to-a-secure-random-string"),
 		SessionMaxAge:   getEnvInt("SESSION_MAX_AGE", 604800),
 		LogLevel:        getEnv("LOG_LEVEL", "info"),
+
+		LeaderboardRefreshMinutes: getEnvInt("LEADERBOARD_REFRESH_MINUTES", 10),
 	}
 }
 
@@ -0,0 +1,182 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/abdul-hamid-achik/chessdrill/internal/middleware"
+	"github.com/abdul-hamid-achik/chessdrill/internal/model"
+	"github.com/abdul-hamid-achik/chessdrill/internal/service"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+type AssignmentHandler struct {
+	assignmentService *service.AssignmentService
+}
+
+func NewAssignmentHandler(assignmentService *service.AssignmentService) *AssignmentHandler {
+	return &AssignmentHandler{
+		assignmentService: assignmentService,
+	}
+}
+
+type CreateAssignmentRequest struct {
+	Title          string    `json:"title"`
+	DrillType      string    `json:"drill_type"`
+	Perspective    string    `json:"perspective"`
+	TargetAttempts int       `json:"target_attempts"`
+	TargetAccuracy float64   `json:"target_accuracy"`
+	DueAt          time.Time `json:"due_at"`
+}
+
+// CreateAssignment sets an assignment for one of the coach's groups. Forms
+// give a due_date (YYYY-MM-DD), due at the end of that day UTC; JSON gives
+// due_at as an RFC 3339 time.
+func (h *AssignmentHandler) CreateAssignment(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	groupID, err := bson.ObjectIDFromHex(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Group not found", http.StatusNotFound)
+		return
+	}
+
+	var req CreateAssignmentRequest
+	if err := r.ParseForm(); err == nil && r.PostForm.Has("drill_type") {
+		req.Title = r.PostForm.Get("title")
+		req.DrillType = r.PostForm.Get("drill_type")
+		req.Perspective = r.PostForm.Get("perspective")
+		req.TargetAttempts, _ = strconv.Atoi(r.PostForm.Get("target_attempts"))
+		req.TargetAccuracy, _ = strconv.ParseFloat(r.PostForm.Get("target_accuracy"), 64)
+		day, err := time.Parse(time.DateOnly, r.PostForm.Get("due_date"))
+		if err != nil {
+			http.Error(w, "Invalid due date", http.StatusBadRequest)
+			return
+		}
+		req.DueAt = day.AddDate(0, 0, 1)
+	} else if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	assignment, err := h.assignmentService.Create(r.Context(), user, groupID, service.AssignmentOptions{
+		Title:          req.Title,
+		DrillType:      model.DrillType(req.DrillType),
+		Perspective:    req.Perspective,
+		TargetAttempts: req.TargetAttempts,
+		TargetAccuracy: req.TargetAccuracy,
+		DueAt:          req.DueAt,
+	})
+	if err != nil {
+		writeAssignmentError(w, err, "Failed to create assignment")
+		return
+	}
+	writeGroupChange(w, r, assignment)
+}
+
+// ListAssignments returns the assignments of one of the coach's groups
+func (h *AssignmentHandler) ListAssignments(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	groupID, err := bson.ObjectIDFromHex(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Group not found", http.StatusNotFound)
+		return
+	}
+	assignments, err := h.assignmentService.ForGroup(r.Context(), user, groupID)
+	if err != nil {
+		writeAssignmentError(w, err, "Failed to get assignments")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(assignments)
+}
+
+// GetPending returns the user's unfinished assignments with their progress
+func (h *AssignmentHandler) GetPending(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	pending, err := h.assignmentService.Pending(r.Context(), user)
+	if err != nil {
+		http.Error(w, "Failed to get assignments", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(pending)
+}
+
+// GetReport returns who finished an assignment, who is behind and their
+// accuracy
+func (h *AssignmentHandler) GetReport(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	assignmentID, err := bson.ObjectIDFromHex(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Assignment not found", http.StatusNotFound)
+		return
+	}
+	report, err := h.assignmentService.Report(r.Context(), user, assignmentID)
+	if err != nil {
+		writeAssignmentError(w, err, "Failed to get assignment")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+func (h *AssignmentHandler) DeleteAssignment(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	assignmentID, err := bson.ObjectIDFromHex(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Assignment not found", http.StatusNotFound)
+		return
+	}
+	assignment, err := h.assignmentService.Delete(r.Context(), user, assignmentID)
+	if err != nil {
+		writeAssignmentError(w, err, "Failed to delete assignment")
+		return
+	}
+
+	if r.Header.Get("HX-Request") == "true" {
+		w.Header().Set("HX-Redirect", "/groups/"+assignment.GroupID.Hex())
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeAssignmentError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrAssignmentNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrInvalidAssignment):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		writeGroupError(w, err, fallback)
+	}
+}
@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/abdul-hamid-achik/chessdrill/internal/middleware"
+	"github.com/abdul-hamid-achik/chessdrill/internal/model"
+	"github.com/abdul-hamid-achik/chessdrill/internal/service"
+	"github.com/abdul-hamid-achik/chessdrill/templates/partials"
+)
+
+type ChallengeHandler struct {
+	challengeService *service.ChallengeService
+}
+
+func NewChallengeHandler(challengeService *service.ChallengeService) *ChallengeHandler {
+	return &ChallengeHandler{
+		challengeService: challengeService,
+	}
+}
+
+type StartChallengeRequest struct {
+	DrillType   string `json:"drill_type"`
+	Perspective string `json:"perspective"`
+}
+
+type StartChallengeResponse struct {
+	SessionID string          `json:"session_id"`
+	Day       string          `json:"day"`
+	Question  *model.Question `json:"question"`
+}
+
+// StartChallenge starts or resumes the user's ranked attempt at today's
+// challenge. The attempt is answered through the regular drill endpoints.
+func (h *ChallengeHandler) StartChallenge(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req StartChallengeRequest
+	if err := r.ParseForm(); err == nil {
+		req.DrillType = r.FormValue("drill_type")
+		req.Perspective = r.FormValue("perspective")
+	} else {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if req.Perspective == "" {
+		req.Perspective = "white"
+	}
+
+	session, question, err := h.challengeService.Start(r.Context(), user, model.DrillType(req.DrillType), req.Perspective)
+	if err != nil {
+		writeDrillError(w, err, "Failed to start challenge")
+		return
+	}
+
+	// Check if this is an HTMX request
+	if r.Header.Get("HX-Request") == "true" {
+		partials.DrillQuestion(session.ID.Hex(), question).Render(r.Context(), w)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(StartChallengeResponse{
+		SessionID: session.ID.Hex(),
+		Day:       session.ChallengeDay,
+		Question:  question,
+	})
+}
+
+// GetToday returns today's challenge day, its drill types and the user's
+// entries for it
+func (h *ChallengeHandler) GetToday(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	day, entries, err := h.challengeService.Today(r.Context(), user.ID)
+	if err != nil {
+		http.Error(w, "Failed to get challenge", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"day":         day,
+		"drill_types": service.ChallengeDrillTypes(),
+		"entries":     entries,
+	})
+}
+
+// GetLeaderboard returns the ranking of a day's challenge, today's unless
+// day is given as YYYY-MM-DD
+func (h *ChallengeHandler) GetLeaderboard(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	day := r.URL.Query().Get("day")
+	if day == "" {
+		day = service.ChallengeDay(time.Now())
+	} else if _, err := time.Parse(time.DateOnly, day); err != nil {
+		http.Error(w, "Invalid day", http.StatusBadRequest)
+		return
+	}
+	drillType := model.DrillType(r.URL.Query().Get("drill_type"))
+
+	entries, err := h.challengeService.Leaderboard(r.Context(), day, drillType)
+	if err != nil {
+		writeDrillError(w, err, "Failed to get leaderboard")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"day":        day,
+		"drill_type": drillType,
+		"entries":    entries,
+	})
+}
@@ -2,8 +2,11 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
+	"unicode"
 
 	"github.com/abdul-hamid-achik/chessdrill/internal/middleware"
 	"github.com/abdul-hamid-achik/chessdrill/internal/model"
@@ -23,14 +26,99 @@ func NewDrillHandler(drillService *service.DrillService) *DrillHandler {
 }
 
 type StartDrillRequest struct {
-	DrillType   string `json:"drill_type"`
-	InputMethod string `json:"input_method"`
-	Perspective string `json:"perspective"`
+	DrillType     string `json:"drill_type"`
+	InputMethod   string `json:"input_method"`
+	Perspective   string `json:"perspective"`
+	Difficulty    string `json:"difficulty"`
+	Mode          string `json:"mode"`
+	TimeLimitSec  int    `json:"time_limit_sec"`
+	QuestionCount int    `json:"question_count"`
+	MaxMisses     int    `json:"max_misses"`
+	Adaptive      *bool  `json:"adaptive"`
+	Seed          int64  `json:"seed"`
+
+	// Region and RegionValues limit the squares drilled, e.g. "files"
+	// with ["d", "e"]
+	Region       string   `json:"region"`
+	RegionValues []string `json:"region_values"`
+
+	// Piece fixes the piece of piece movement drills
+	Piece string `json:"piece"`
+
+	// PresetID starts one of the user's presets; only Adaptive and Seed
+	// are read alongside it
+	PresetID string `json:"preset_id"`
+}
+
+// drillSetupFromForm reads the drill options form, which both starts
+// drills and saves presets
+func drillSetupFromForm(r *http.Request) StartDrillRequest {
+	req := StartDrillRequest{
+		DrillType:   r.FormValue("drill_type"),
+		InputMethod: r.FormValue("input_method"),
+		Perspective: r.FormValue("perspective"),
+		Difficulty:  r.FormValue("difficulty"),
+		Mode:        r.FormValue("mode"),
+		Piece:       r.FormValue("piece"),
+		PresetID:    r.FormValue("preset_id"),
+	}
+	req.TimeLimitSec, _ = strconv.Atoi(r.FormValue("time_limit_sec"))
+	req.QuestionCount, _ = strconv.Atoi(r.FormValue("question_count"))
+	req.MaxMisses, _ = strconv.Atoi(r.FormValue("max_misses"))
+	if v := r.FormValue("adaptive"); v != "" {
+		adaptive := v == "on" || v == "true"
+		req.Adaptive = &adaptive
+	}
+	req.Seed, _ = strconv.ParseInt(r.FormValue("seed"), 10, 64)
+	// Each kind of region has its own field, so hidden ones don't mix
+	req.Region = r.FormValue("region")
+	for _, v := range r.Form["region_"+req.Region] {
+		req.RegionValues = append(req.RegionValues, strings.FieldsFunc(v, func(c rune) bool {
+			return c == ',' || unicode.IsSpace(c)
+		})...)
+	}
+	return req
+}
+
+// sessionOptions turns the request into session options, filling in the
+// defaults. Adaptive selection is left to the caller.
+func (req *StartDrillRequest) sessionOptions() service.SessionOptions {
+	opts := service.SessionOptions{
+		DrillType:   model.DrillType(req.DrillType),
+		InputMethod: model.InputMethod(req.InputMethod),
+		Perspective: req.Perspective,
+		Difficulty:  model.Difficulty(req.Difficulty),
+		Mode: model.ModeSettings{
+			Mode:          model.SessionMode(req.Mode),
+			TimeLimitSec:  req.TimeLimitSec,
+			QuestionCount: req.QuestionCount,
+			MaxMisses:     req.MaxMisses,
+		},
+		Piece: req.Piece,
+		Seed:  req.Seed,
+	}
+	if opts.DrillType == "" {
+		opts.DrillType = model.DrillTypeNameSquare
+	}
+	if opts.InputMethod == "" {
+		opts.InputMethod = model.InputMethodType
+	}
+	if opts.Perspective == "" {
+		opts.Perspective = "white"
+	}
+	if opts.Difficulty == "" {
+		opts.Difficulty = model.DifficultyEasy
+	}
+	if req.Region != "" {
+		opts.Region = &model.SquareRegion{Kind: model.RegionKind(req.Region), Values: req.RegionValues}
+	}
+	return opts
 }
 
 type StartDrillResponse struct {
 	SessionID string          `json:"session_id"`
 	Question  *model.Question `json:"question"`
+	Seed      int64           `json:"seed"`
 }
 
 func (h *DrillHandler) StartDrill(w http.ResponseWriter, r *http.Request) {
@@ -41,10 +129,8 @@ func (h *DrillHandler) StartDrill(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req StartDrillRequest
-	if err := r.ParseForm(); err == nil {
-		req.DrillType = r.FormValue("drill_type")
-		req.InputMethod = r.FormValue("input_method")
-		req.Perspective = r.FormValue("perspective")
+	if err := r.ParseForm(); err == nil && len(r.PostForm) > 0 {
+		req = drillSetupFromForm(r)
 	} else {
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			http.Error(w, "Invalid request", http.StatusBadRequest)
@@ -52,25 +138,28 @@ func (h *DrillHandler) StartDrill(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	if req.DrillType == "" {
-		req.DrillType = "name_square"
-	}
-	if req.InputMethod == "" {
-		req.InputMethod = "type"
+	opts := req.sessionOptions()
+	if req.PresetID != "" {
+		presetID, err := bson.ObjectIDFromHex(req.PresetID)
+		preset := user.Preset(presetID)
+		if err != nil || preset == nil {
+			http.Error(w, "Preset not found", http.StatusNotFound)
+			return
+		}
+		opts = service.PresetSession(preset)
+		opts.Seed = req.Seed
 	}
-	if req.Perspective == "" {
-		req.Perspective = "white"
+
+	// Adaptive selection follows the user's preference unless requested
+	opts.Adaptive = user.Preferences.Adaptive
+	if req.Adaptive != nil {
+		opts.Adaptive = *req.Adaptive
 	}
+	opts.ExplorationFloor = user.Preferences.ExplorationFloor
 
-	session, question, err := h.drillService.StartSession(
-		r.Context(),
-		user.ID,
-		model.DrillType(req.DrillType),
-		model.InputMethod(req.InputMethod),
-		req.Perspective,
-	)
+	session, question, err := h.drillService.StartSession(r.Context(), user.ID, opts)
 	if err != nil {
-		http.Error(w, "Failed to start drill", http.StatusInternalServerError)
+		writeDrillError(w, err, "Failed to start drill")
 		return
 	}
 
@@ -84,15 +173,14 @@ func (h *DrillHandler) StartDrill(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(StartDrillResponse{
 		SessionID: session.ID.Hex(),
 		Question:  question,
+		Seed:      session.Seed,
 	})
 }
 
 type CheckAnswerRequest struct {
-	SessionID  string `json:"session_id"`
-	Target     string `json:"target"`
+	QuestionID string `json:"question_id"`
 	Answer     string `json:"answer"`
 	ResponseMs int    `json:"response_ms"`
-	DrillType  string `json:"drill_type"`
 }
 
 func (h *DrillHandler) CheckAnswer(w http.ResponseWriter, r *http.Request) {
@@ -104,10 +192,8 @@ func (h *DrillHandler) CheckAnswer(w http.ResponseWriter, r *http.Request) {
 
 	var req CheckAnswerRequest
 	if err := r.ParseForm(); err == nil {
-		req.SessionID = r.FormValue("session_id")
-		req.Target = r.FormValue("target")
+		req.QuestionID = r.FormValue("question_id")
 		req.Answer = r.FormValue("answer")
-		req.DrillType = r.FormValue("drill_type")
 		if ms := r.FormValue("response_ms"); ms != "" {
 			req.ResponseMs, _ = strconv.Atoi(ms)
 		}
@@ -118,48 +204,80 @@ func (h *DrillHandler) CheckAnswer(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	sessionID, err := bson.ObjectIDFromHex(req.SessionID)
+	questionID, err := bson.ObjectIDFromHex(req.QuestionID)
 	if err != nil {
-		http.Error(w, "Invalid session ID", http.StatusBadRequest)
+		http.Error(w, "Invalid question ID", http.StatusBadRequest)
 		return
 	}
 
-	if req.DrillType == "" {
-		req.DrillType = "name_square"
-	}
-
-	correct, nextQuestion, err := h.drillService.CheckAnswer(
+	result, nextQuestion, err := h.drillService.CheckAnswer(
 		r.Context(),
-		sessionID,
 		user.ID,
-		model.DrillType(req.DrillType),
-		req.Target,
-		req.Target, // correctAnswer is the target
+		questionID,
 		req.Answer,
 		req.ResponseMs,
 	)
 	if err != nil {
-		http.Error(w, "Failed to check answer", http.StatusInternalServerError)
+		writeDrillError(w, err, "Failed to check answer")
 		return
 	}
 
 	// Check if this is an HTMX request
 	if r.Header.Get("HX-Request") == "true" {
-		message := "Incorrect!"
-		if correct {
+		correctAnswer := result.CorrectAnswer
+		if correctAnswer == "" {
+			correctAnswer = "no squares"
+		}
+		message := "Incorrect! The answer was " + correctAnswer + "."
+		if result.Correct {
 			message = "Correct!"
 		}
-		partials.Feedback(correct, message, req.SessionID, nextQuestion).Render(r.Context(), w)
+		if result.Explanation != "" {
+			message += " " + result.Explanation
+		}
+
+		// The session ended: replace the drill with its summary
+		if result.Summary != nil {
+			if result.TimeUp {
+				message = "Time's up! That answer arrived after the clock ran out."
+			}
+			w.Header().Set("HX-Retarget", "#drill-active-area")
+			partials.SessionFinished(message, result.Summary).Render(r.Context(), w)
+			return
+		}
+
+		partials.Feedback(result.Correct, message, nextQuestion).Render(r.Context(), w)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"correct":       correct,
-		"next_question": nextQuestion,
+		"correct":        result.Correct,
+		"score":          result.Score,
+		"correct_answer": result.CorrectAnswer,
+		"explanation":    result.Explanation,
+		"next_question":  nextQuestion,
+		"summary":        result.Summary,
+		"time_up":        result.TimeUp,
 	})
 }
 
+// writeDrillError maps drill service errors to HTTP status codes
+func writeDrillError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrQuestionNotFound), errors.Is(err, service.ErrSessionNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrQuestionAnswered), errors.Is(err, service.ErrSessionEnded), errors.Is(err, service.ErrNothingDue), errors.Is(err, service.ErrNoWeakSquares), errors.Is(err, service.ErrChallengeTaken), errors.Is(err, service.ErrRepertoireNotFound):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, service.ErrInvalidMode), errors.Is(err, service.ErrUnknownDrillType), errors.Is(err, service.ErrInvalidRegion), errors.Is(err, service.ErrInvalidPiece):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrNoPuzzles):
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
+
 func (h *DrillHandler) EndDrill(w http.ResponseWriter, r *http.Request) {
 	user := middleware.GetUser(r.Context())
 	if user == nil {
@@ -184,9 +302,9 @@ func (h *DrillHandler) EndDrill(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	summary, err := h.drillService.EndSession(r.Context(), sessionID)
+	summary, err := h.drillService.EndSession(r.Context(), sessionID, user.ID)
 	if err != nil {
-		http.Error(w, "Failed to end drill", http.StatusInternalServerError)
+		writeDrillError(w, err, "Failed to end drill")
 		return
 	}
 
@@ -219,9 +337,9 @@ func (h *DrillHandler) GetNextQuestion(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	question, err := h.drillService.GetNextQuestion(r.Context(), sessionID)
+	question, err := h.drillService.GetNextQuestion(r.Context(), sessionID, user.ID)
 	if err != nil {
-		http.Error(w, "Failed to get next question", http.StatusInternalServerError)
+		writeDrillError(w, err, "Failed to get next question")
 		return
 	}
 
@@ -229,8 +347,6 @@ func (h *DrillHandler) GetNextQuestion(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *DrillHandler) GetLegalMoves(w http.ResponseWriter, r *http.Request) {
-	// This is handled client-side by chessops
-	// But we can provide a server-side fallback
 	fen := r.URL.Query().Get("fen")
 	square := r.URL.Query().Get("square")
 
@@ -239,10 +355,15 @@ func (h *DrillHandler) GetLegalMoves(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// For now, return empty - the client handles this with chessops
+	moves, err := h.drillService.LegalMoves(fen, square)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"moves": []string{},
-		"note":  "Legal moves are calculated client-side using chessops",
+		"square": square,
+		"moves":  moves,
 	})
 }
@@ -0,0 +1,264 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/abdul-hamid-achik/chessdrill/internal/middleware"
+	"github.com/abdul-hamid-achik/chessdrill/internal/model"
+	"github.com/abdul-hamid-achik/chessdrill/internal/service"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+type GroupHandler struct {
+	groupService *service.GroupService
+}
+
+func NewGroupHandler(groupService *service.GroupService) *GroupHandler {
+	return &GroupHandler{
+		groupService: groupService,
+	}
+}
+
+// GroupMembership is a group as seen by one of its students, without the
+// other members
+type GroupMembership struct {
+	ID   bson.ObjectID `json:"id"`
+	Name string        `json:"name"`
+}
+
+// ListGroups returns the groups the user coaches and the ones they joined
+func (h *GroupHandler) ListGroups(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	coaching := []model.Group{}
+	if user.Role == model.RoleCoach {
+		var err error
+		if coaching, err = h.groupService.CoachGroups(r.Context(), user.ID); err != nil {
+			http.Error(w, "Failed to get groups", http.StatusInternalServerError)
+			return
+		}
+	}
+	joined, err := h.groupService.MemberGroups(r.Context(), user.ID)
+	if err != nil {
+		http.Error(w, "Failed to get groups", http.StatusInternalServerError)
+		return
+	}
+	memberOf := make([]GroupMembership, len(joined))
+	for i, group := range joined {
+		memberOf[i] = GroupMembership{ID: group.ID, Name: group.Name}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"coaching":  coaching,
+		"member_of": memberOf,
+	})
+}
+
+// CreateGroup creates a group for a coach
+func (h *GroupHandler) CreateGroup(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := r.ParseForm(); err == nil && r.PostForm.Has("name") {
+		req.Name = r.PostForm.Get("name")
+	} else if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	group, err := h.groupService.CreateGroup(r.Context(), user, req.Name)
+	if err != nil {
+		writeGroupError(w, err, "Failed to create group")
+		return
+	}
+	writeGroupChange(w, r, group)
+}
+
+// JoinGroup adds the user to the group with a join code
+func (h *GroupHandler) JoinGroup(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Code string `json:"code"`
+	}
+	if err := r.ParseForm(); err == nil && r.PostForm.Has("code") {
+		req.Code = r.PostForm.Get("code")
+	} else if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	group, err := h.groupService.Join(r.Context(), user, req.Code)
+	if err != nil {
+		writeGroupError(w, err, "Failed to join group")
+		return
+	}
+	writeGroupChange(w, r, GroupMembership{ID: group.ID, Name: group.Name})
+}
+
+// LeaveGroup takes the user out of a group; its coach loses access to
+// their stats immediately
+func (h *GroupHandler) LeaveGroup(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	groupID, err := bson.ObjectIDFromHex(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Group not found", http.StatusNotFound)
+		return
+	}
+	if err := h.groupService.Leave(r.Context(), user.ID, groupID); err != nil {
+		writeGroupError(w, err, "Failed to leave group")
+		return
+	}
+	writeGroupChange(w, r, nil)
+}
+
+// LeaveAllGroups revokes every coach's access to the user's stats
+func (h *GroupHandler) LeaveAllGroups(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.groupService.LeaveAll(r.Context(), user.ID); err != nil {
+		http.Error(w, "Failed to leave groups", http.StatusInternalServerError)
+		return
+	}
+	writeGroupChange(w, r, nil)
+}
+
+func (h *GroupHandler) DeleteGroup(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	groupID, err := bson.ObjectIDFromHex(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Group not found", http.StatusNotFound)
+		return
+	}
+	if err := h.groupService.DeleteGroup(r.Context(), user, groupID); err != nil {
+		writeGroupError(w, err, "Failed to delete group")
+		return
+	}
+
+	if r.Header.Get("HX-Request") == "true" {
+		w.Header().Set("HX-Redirect", "/groups")
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetGroup returns a coach's group dashboard: every member's overall
+// stats and latest sessions
+func (h *GroupHandler) GetGroup(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	groupID, err := bson.ObjectIDFromHex(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Group not found", http.StatusNotFound)
+		return
+	}
+	group, students, err := h.groupService.Dashboard(r.Context(), user, groupID)
+	if err != nil {
+		writeGroupError(w, err, "Failed to get group")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"group":    group,
+		"students": students,
+	})
+}
+
+// GetStudent returns one member's stats, heatmap and sessions to their
+// coach
+func (h *GroupHandler) GetStudent(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	groupID, err := bson.ObjectIDFromHex(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Group not found", http.StatusNotFound)
+		return
+	}
+	studentID, err := bson.ObjectIDFromHex(r.PathValue("studentID"))
+	if err != nil {
+		http.Error(w, "Student not found", http.StatusNotFound)
+		return
+	}
+	report, err := h.groupService.Student(r.Context(), user, groupID, studentID)
+	if err != nil {
+		writeGroupError(w, err, "Failed to get student")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// writeGroupChange reloads the page for HTMX requests and answers others
+// with v, or no content when v is nil
+func writeGroupChange(w http.ResponseWriter, r *http.Request, v interface{}) {
+	if r.Header.Get("HX-Request") == "true" {
+		w.Header().Set("HX-Refresh", "true")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if v == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeGroupError maps group errors to responses. Groups a coach doesn't
+// run and students outside the group are both reported as not found, so
+// coaches can't probe for other users.
+func writeGroupError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrGroupNotFound), errors.Is(err, service.ErrNotGroupMember):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrNotCoach):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, service.ErrAlreadyInGroup), errors.Is(err, service.ErrOwnGroup):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, service.ErrInvalidGroupName):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/abdul-hamid-achik/chessdrill/internal/middleware"
+	"github.com/abdul-hamid-achik/chessdrill/internal/model"
+	"github.com/abdul-hamid-achik/chessdrill/internal/service"
+)
+
+type LeaderboardHandler struct {
+	leaderboardService *service.LeaderboardService
+}
+
+func NewLeaderboardHandler(leaderboardService *service.LeaderboardService) *LeaderboardHandler {
+	return &LeaderboardHandler{
+		leaderboardService: leaderboardService,
+	}
+}
+
+// leaderboardQuery selects a leaderboard; empty drill type and perspective
+// select the one across all of them
+type leaderboardQuery struct {
+	Metric      model.LeaderboardMetric
+	DrillType   model.DrillType
+	Perspective string
+	Period      model.LeaderboardPeriod
+}
+
+// leaderboardQueryFrom reads a leaderboard query from the URL, defaulting
+// to this week's timed scores
+func leaderboardQueryFrom(r *http.Request) leaderboardQuery {
+	q := r.URL.Query()
+	query := leaderboardQuery{
+		Metric:      model.LeaderboardMetric(q.Get("metric")),
+		DrillType:   model.DrillType(q.Get("drill_type")),
+		Perspective: q.Get("perspective"),
+		Period:      model.LeaderboardPeriod(q.Get("period")),
+	}
+	if query.Metric == "" {
+		query.Metric = model.LeaderboardTimedScore
+	}
+	if query.Period == "" {
+		query.Period = model.LeaderboardWeek
+	}
+	return query
+}
+
+func (h *LeaderboardHandler) GetLeaderboard(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	query := leaderboardQueryFrom(r)
+	board, err := h.leaderboardService.Get(r.Context(), query.Metric, query.DrillType, query.Perspective, query.Period)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidLeaderboard) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Failed to get leaderboard", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(board)
+}
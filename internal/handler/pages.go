@@ -1,23 +1,40 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
+	"strings"
 
 	"github.com/abdul-hamid-achik/chessdrill/internal/middleware"
 	"github.com/abdul-hamid-achik/chessdrill/internal/model"
 	"github.com/abdul-hamid-achik/chessdrill/internal/service"
 	"github.com/abdul-hamid-achik/chessdrill/templates/pages"
+	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
 type PageHandler struct {
-	statsService *service.StatsService
-	drillService *service.DrillService
+	statsService       *service.StatsService
+	drillService       *service.DrillService
+	reviewService      *service.ReviewService
+	challengeService   *service.ChallengeService
+	leaderboardService *service.LeaderboardService
+	raceService        *service.RaceService
+	groupService       *service.GroupService
+	assignmentService  *service.AssignmentService
+	repertoireService  *service.RepertoireService
 }
 
-func NewPageHandler(statsService *service.StatsService, drillService *service.DrillService) *PageHandler {
+func NewPageHandler(statsService *service.StatsService, drillService *service.DrillService, reviewService *service.ReviewService, challengeService *service.ChallengeService, leaderboardService *service.LeaderboardService, raceService *service.RaceService, groupService *service.GroupService, assignmentService *service.AssignmentService, repertoireService *service.RepertoireService) *PageHandler {
 	return &PageHandler{
-		statsService: statsService,
-		drillService: drillService,
+		statsService:       statsService,
+		drillService:       drillService,
+		reviewService:      reviewService,
+		challengeService:   challengeService,
+		leaderboardService: leaderboardService,
+		raceService:        raceService,
+		groupService:       groupService,
+		assignmentService:  assignmentService,
+		repertoireService:  repertoireService,
 	}
 }
 
@@ -46,7 +63,11 @@ func (h *PageHandler) Dashboard(w http.ResponseWriter, r *http.Request) {
 		stats = &model.OverallStats{}
 	}
 
-	pages.Dashboard(user, stats).Render(r.Context(), w)
+	// The review panel is left out if the counts cannot be loaded
+	due, _ := h.reviewService.DueToday(r.Context(), user.ID)
+	pending, _ := h.assignmentService.Pending(r.Context(), user)
+
+	pages.Dashboard(user, stats, due, pending).Render(r.Context(), w)
 }
 
 func (h *PageHandler) DrillSelect(w http.ResponseWriter, r *http.Request) {
@@ -66,7 +87,205 @@ func (h *PageHandler) Drill(w http.ResponseWriter, r *http.Request) {
 		drillType = "name_square"
 	}
 
-	pages.Drill(user, drillType).Render(r.Context(), w)
+	query := r.URL.Query()
+	opts := pages.DrillOptions{
+		Mode: query.Get("mode"),
+		Seed: query.Get("seed"),
+	}
+	if id := query.Get("preset"); id != "" {
+		presetID, err := bson.ObjectIDFromHex(id)
+		opts.Preset = user.Preset(presetID)
+		if err != nil || opts.Preset == nil {
+			http.Redirect(w, r, "/drill", http.StatusSeeOther)
+			return
+		}
+		drillType = string(opts.Preset.DrillType)
+	}
+	pages.Drill(user, drillType, opts).Render(r.Context(), w)
+}
+
+func (h *PageHandler) Challenge(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	selected := model.DrillType(r.URL.Query().Get("type"))
+	if selected == "" {
+		selected = model.DrillTypeNameSquare
+	}
+
+	day, entries, err := h.challengeService.Today(r.Context(), user.ID)
+	if err != nil {
+		entries = map[model.DrillType]model.ChallengeEntry{}
+	}
+	leaderboard, _ := h.challengeService.Leaderboard(r.Context(), day, selected)
+
+	pages.Challenge(user, day, service.ChallengeDrillTypes(), entries, string(selected), leaderboard).Render(r.Context(), w)
+}
+
+func (h *PageHandler) ChallengeDrill(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	pages.Drill(user, r.PathValue("type"), pages.DrillOptions{
+		Challenge:     true,
+		QuestionCount: service.ChallengeQuestionCount,
+	}).Render(r.Context(), w)
+}
+
+func (h *PageHandler) Leaderboards(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	query := leaderboardQueryFrom(r)
+	board, err := h.leaderboardService.Get(r.Context(), query.Metric, query.DrillType, query.Perspective, query.Period)
+	if err != nil {
+		// Fall back to the default board for unknown filters
+		query = leaderboardQuery{Metric: model.LeaderboardTimedScore, Period: model.LeaderboardWeek}
+		board, err = h.leaderboardService.Get(r.Context(), query.Metric, query.DrillType, query.Perspective, query.Period)
+	}
+	if err != nil {
+		board = &model.Leaderboard{Metric: query.Metric, Period: query.Period}
+	}
+
+	pages.Leaderboards(user, board, service.DrillTypes(), service.MinLeaderboardAnswers).Render(r.Context(), w)
+}
+
+func (h *PageHandler) Race(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	pages.Race(user, service.RaceDrillTypes(), service.MinRacePlayers, service.MaxRacePlayers).Render(r.Context(), w)
+}
+
+func (h *PageHandler) RaceRoom(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	code := strings.ToUpper(r.PathValue("code"))
+	_, err := h.raceService.State(user.ID, code)
+	if errors.Is(err, service.ErrRaceNotFound) {
+		h.NotFound(w, r)
+		return
+	}
+
+	pages.RaceRoom(user, code, err == nil).Render(r.Context(), w)
+}
+
+func (h *PageHandler) Groups(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	var coaching []model.Group
+	if user.Role == model.RoleCoach {
+		coaching, _ = h.groupService.CoachGroups(r.Context(), user.ID)
+	}
+	memberOf, _ := h.groupService.MemberGroups(r.Context(), user.ID)
+
+	pages.Groups(user, coaching, memberOf).Render(r.Context(), w)
+}
+
+func (h *PageHandler) GroupDashboard(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	groupID, err := bson.ObjectIDFromHex(r.PathValue("id"))
+	if err != nil {
+		h.NotFound(w, r)
+		return
+	}
+	group, students, err := h.groupService.Dashboard(r.Context(), user, groupID)
+	if err != nil {
+		h.groupError(w, r, err)
+		return
+	}
+	assignments, _ := h.assignmentService.ForGroup(r.Context(), user, groupID)
+
+	pages.GroupDashboard(user, group, students, assignments, service.DrillTypes()).Render(r.Context(), w)
+}
+
+func (h *PageHandler) GroupStudent(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	groupID, err := bson.ObjectIDFromHex(r.PathValue("id"))
+	if err != nil {
+		h.NotFound(w, r)
+		return
+	}
+	studentID, err := bson.ObjectIDFromHex(r.PathValue("studentID"))
+	if err != nil {
+		h.NotFound(w, r)
+		return
+	}
+	report, err := h.groupService.Student(r.Context(), user, groupID, studentID)
+	if err != nil {
+		h.groupError(w, r, err)
+		return
+	}
+
+	pages.StudentReport(user, report).Render(r.Context(), w)
+}
+
+func (h *PageHandler) AssignmentReport(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	assignmentID, err := bson.ObjectIDFromHex(r.PathValue("id"))
+	if err != nil {
+		h.NotFound(w, r)
+		return
+	}
+	report, err := h.assignmentService.Report(r.Context(), user, assignmentID)
+	if errors.Is(err, service.ErrAssignmentNotFound) {
+		h.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		h.groupError(w, r, err)
+		return
+	}
+
+	pages.AssignmentReport(user, report).Render(r.Context(), w)
+}
+
+// groupError renders the page for a failed group lookup. Groups the user
+// doesn't coach look the same as missing ones.
+func (h *PageHandler) groupError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, service.ErrNotCoach):
+		http.Redirect(w, r, "/groups", http.StatusSeeOther)
+	case errors.Is(err, service.ErrGroupNotFound), errors.Is(err, service.ErrNotGroupMember):
+		h.NotFound(w, r)
+	default:
+		h.InternalError(w, r)
+	}
 }
 
 func (h *PageHandler) Stats(w http.ResponseWriter, r *http.Request) {
@@ -89,6 +308,21 @@ func (h *PageHandler) Stats(w http.ResponseWriter, r *http.Request) {
 	pages.Stats(user, stats, heatmap).Render(r.Context(), w)
 }
 
+func (h *PageHandler) Repertoire(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	overviews, err := h.repertoireService.Overview(r.Context(), user.ID)
+	if err != nil {
+		overviews = nil
+	}
+
+	pages.Repertoire(user, overviews).Render(r.Context(), w)
+}
+
 func (h *PageHandler) Settings(w http.ResponseWriter, r *http.Request) {
 	user := middleware.GetUser(r.Context())
 	if user == nil {
@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/abdul-hamid-achik/chessdrill/internal/middleware"
+	"github.com/abdul-hamid-achik/chessdrill/internal/model"
+	"github.com/abdul-hamid-achik/chessdrill/internal/service"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+type PresetHandler struct {
+	userService *service.UserService
+}
+
+func NewPresetHandler(userService *service.UserService) *PresetHandler {
+	return &PresetHandler{
+		userService: userService,
+	}
+}
+
+// CreatePresetRequest is a named drill setup, given the same way as when
+// starting a drill
+type CreatePresetRequest struct {
+	Name string `json:"name"`
+	StartDrillRequest
+}
+
+func (h *PresetHandler) ListPresets(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	presets := user.Presets
+	if presets == nil {
+		presets = []model.DrillPreset{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(presets)
+}
+
+// CreatePreset saves a drill setup as a preset. HTMX requests are sent to
+// /drill, where presets are started from.
+func (h *PresetHandler) CreatePreset(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req CreatePresetRequest
+	if err := r.ParseForm(); err == nil && r.PostForm.Has("name") {
+		req.Name = r.PostForm.Get("name")
+		req.StartDrillRequest = drillSetupFromForm(r)
+	} else if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	opts := req.sessionOptions()
+	preset, err := h.userService.CreatePreset(r.Context(), user.ID, model.DrillPreset{
+		Name:         req.Name,
+		DrillType:    opts.DrillType,
+		Perspective:  opts.Perspective,
+		InputMethod:  opts.InputMethod,
+		Difficulty:   opts.Difficulty,
+		ModeSettings: opts.Mode,
+		Region:       opts.Region,
+		Piece:        opts.Piece,
+	})
+	if err != nil {
+		writePresetError(w, err, "Failed to save preset")
+		return
+	}
+
+	if r.Header.Get("HX-Request") == "true" {
+		w.Header().Set("HX-Redirect", "/drill")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(preset)
+}
+
+func (h *PresetHandler) DeletePreset(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	presetID, err := bson.ObjectIDFromHex(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Preset not found", http.StatusNotFound)
+		return
+	}
+	if err := h.userService.DeletePreset(r.Context(), user.ID, presetID); err != nil {
+		writePresetError(w, err, "Failed to delete preset")
+		return
+	}
+
+	if r.Header.Get("HX-Request") == "true" {
+		w.Header().Set("HX-Refresh", "true")
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writePresetError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrPresetNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrInvalidPreset):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrTooManyPresets):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,234 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/abdul-hamid-achik/chessdrill/internal/middleware"
+	"github.com/abdul-hamid-achik/chessdrill/internal/model"
+	"github.com/abdul-hamid-achik/chessdrill/internal/service"
+)
+
+// raceHeartbeat keeps idle event streams open through proxies
+const raceHeartbeat = 25 * time.Second
+
+type RaceHandler struct {
+	raceService *service.RaceService
+}
+
+func NewRaceHandler(raceService *service.RaceService) *RaceHandler {
+	return &RaceHandler{
+		raceService: raceService,
+	}
+}
+
+type CreateRaceRequest struct {
+	DrillType string `json:"drill_type"`
+	Rounds    int    `json:"rounds"`
+}
+
+// CreateRace opens a room hosted by the user
+func (h *RaceHandler) CreateRace(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req CreateRaceRequest
+	if err := r.ParseForm(); err == nil {
+		req.DrillType = r.FormValue("drill_type")
+		req.Rounds, _ = strconv.Atoi(r.FormValue("rounds"))
+	} else {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request", http.StatusBadRequest)
+			return
+		}
+	}
+	if req.DrillType == "" {
+		req.DrillType = "name_square"
+	}
+
+	code, err := h.raceService.Create(user, model.DrillType(req.DrillType), req.Rounds)
+	if err != nil {
+		writeRaceError(w, err, "Failed to create race")
+		return
+	}
+	writeRaceRoom(w, code)
+}
+
+// JoinRace adds the user to a room still in the lobby
+func (h *RaceHandler) JoinRace(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	code := r.PathValue("code")
+	if err := h.raceService.Join(user, code); err != nil {
+		writeRaceError(w, err, "Failed to join race")
+		return
+	}
+	writeRaceRoom(w, code)
+}
+
+// writeRaceRoom answers with the code of the room the user is now in
+func writeRaceRoom(w http.ResponseWriter, code string) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"code": strings.ToUpper(code)})
+}
+
+func (h *RaceHandler) LeaveRace(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.raceService.Leave(user.ID, r.PathValue("code")); err != nil {
+		writeRaceError(w, err, "Failed to leave race")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// StartRace starts the countdown; only the host may start a room
+func (h *RaceHandler) StartRace(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.raceService.Start(r.Context(), user.ID, r.PathValue("code")); err != nil {
+		writeRaceError(w, err, "Failed to start race")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type RaceAnswerRequest struct {
+	Round  int    `json:"round"`
+	Answer string `json:"answer"`
+}
+
+// AnswerRace grades an answer to the current round. Response times are
+// measured by the server.
+func (h *RaceHandler) AnswerRace(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req RaceAnswerRequest
+	if err := r.ParseForm(); err == nil {
+		req.Round, _ = strconv.Atoi(r.FormValue("round"))
+		req.Answer = r.FormValue("answer")
+	} else {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request", http.StatusBadRequest)
+			return
+		}
+	}
+
+	correct, err := h.raceService.Answer(r.Context(), user.ID, r.PathValue("code"), req.Round, req.Answer)
+	if err != nil {
+		writeRaceError(w, err, "Failed to check answer")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]bool{"correct": correct})
+}
+
+// GetRace returns a room's current state
+func (h *RaceHandler) GetRace(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	state, err := h.raceService.State(user.ID, r.PathValue("code"))
+	if err != nil {
+		writeRaceError(w, err, "Failed to get race")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(state)
+}
+
+// Events streams a room's state to a player as server-sent events, one
+// "state" event per change
+func (h *RaceHandler) Events(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	states, cancel, err := h.raceService.Subscribe(user.ID, r.PathValue("code"))
+	if err != nil {
+		writeRaceError(w, err, "Failed to follow race")
+		return
+	}
+	defer cancel()
+
+	// The stream outlives the server's write timeout
+	rc := http.NewResponseController(w)
+	rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(raceHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case state := <-states:
+			data, err := json.Marshal(state)
+			if err != nil {
+				return
+			}
+			if _, err := fmt.Fprintf(w, "event: state\ndata: %s\n\n", data); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func writeRaceError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrRaceNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrNotInRace), errors.Is(err, service.ErrNotRaceHost):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, service.ErrRaceFull), errors.Is(err, service.ErrRaceStarted), errors.Is(err, service.ErrRaceTooFewPlayers), errors.Is(err, service.ErrRoundClosed):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		writeDrillError(w, err, fallback)
+	}
+}
@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/abdul-hamid-achik/chessdrill/internal/middleware"
+	"github.com/abdul-hamid-achik/chessdrill/internal/model"
+	"github.com/abdul-hamid-achik/chessdrill/internal/service"
+)
+
+// maxPGNBytes bounds the size of an uploaded repertoire
+const maxPGNBytes = 1 << 20
+
+type RepertoireHandler struct {
+	repertoireService *service.RepertoireService
+}
+
+func NewRepertoireHandler(repertoireService *service.RepertoireService) *RepertoireHandler {
+	return &RepertoireHandler{
+		repertoireService: repertoireService,
+	}
+}
+
+type UploadRepertoireRequest struct {
+	PGN string `json:"pgn"`
+}
+
+// ListRepertoires returns the user's repertoires with the stats of each line
+func (h *RepertoireHandler) ListRepertoires(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	overviews, err := h.repertoireService.Overview(r.Context(), user.ID)
+	if err != nil {
+		http.Error(w, "Failed to get repertoires", http.StatusInternalServerError)
+		return
+	}
+	if overviews == nil {
+		overviews = []model.RepertoireOverview{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(overviews)
+}
+
+// GetRepertoire returns the move tree of the user's repertoire for a color
+func (h *RepertoireHandler) GetRepertoire(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	repertoire, err := h.repertoireService.Get(r.Context(), user.ID, r.PathValue("color"))
+	if err != nil {
+		writeRepertoireError(w, err, "Failed to get repertoire")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(repertoire)
+}
+
+// UploadRepertoire replaces the user's repertoire for a color with the
+// games of a PGN, given as a "pgn" file or form field, or as JSON
+func (h *RepertoireHandler) UploadRepertoire(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxPGNBytes)
+	var req UploadRepertoireRequest
+	err := r.ParseMultipartForm(maxPGNBytes)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		http.Error(w, "PGN is too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err == nil || (errors.Is(err, http.ErrNotMultipart) && len(r.PostForm) > 0) {
+		req.PGN = r.PostForm.Get("pgn")
+		if file, _, err := r.FormFile("pgn"); err == nil {
+			data, err := io.ReadAll(file)
+			file.Close()
+			if err != nil {
+				http.Error(w, "Invalid request", http.StatusBadRequest)
+				return
+			}
+			req.PGN = string(data)
+		}
+	} else if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	repertoire, err := h.repertoireService.Import(r.Context(), user.ID, r.PathValue("color"), req.PGN)
+	if err != nil {
+		writeRepertoireError(w, err, "Failed to save repertoire")
+		return
+	}
+
+	if r.Header.Get("HX-Request") == "true" {
+		w.Header().Set("HX-Refresh", "true")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(repertoire)
+}
+
+func (h *RepertoireHandler) DeleteRepertoire(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.repertoireService.Delete(r.Context(), user.ID, r.PathValue("color")); err != nil {
+		writeRepertoireError(w, err, "Failed to delete repertoire")
+		return
+	}
+
+	if r.Header.Get("HX-Request") == "true" {
+		w.Header().Set("HX-Refresh", "true")
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeRepertoireError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrRepertoireNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrInvalidRepertoire):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
@@ -2,6 +2,7 @@ package handler
 
 import (
 	"net/http"
+	"strconv"
 
 	"github.com/abdul-hamid-achik/chessdrill/internal/middleware"
 	"github.com/abdul-hamid-achik/chessdrill/internal/model"
@@ -35,7 +36,11 @@ func (h *SettingsHandler) UpdatePreferences(w http.ResponseWriter, r *http.Reque
 		Perspective:     r.FormValue("perspective"),
 		ShowCoordinates: r.FormValue("show_coordinates") == "on",
 		Theme:           r.FormValue("theme"),
+		Adaptive:        r.FormValue("adaptive") == "on",
+
+		HideFromLeaderboards: r.FormValue("hide_from_leaderboards") == "on",
 	}
+	prefs.ExplorationFloor, _ = strconv.ParseFloat(r.FormValue("exploration_floor"), 64)
 
 	// Set defaults if empty
 	if prefs.Perspective == "" {
@@ -44,6 +49,9 @@ func (h *SettingsHandler) UpdatePreferences(w http.ResponseWriter, r *http.Reque
 	if prefs.Theme == "" {
 		prefs.Theme = "light"
 	}
+	if prefs.ExplorationFloor <= 0 || prefs.ExplorationFloor > 1 {
+		prefs.ExplorationFloor = service.DefaultExplorationFloor
+	}
 
 	if err := h.userService.UpdatePreferences(r.Context(), user.ID, prefs); err != nil {
 		http.Error(w, "Failed to update preferences", http.StatusInternalServerError)
@@ -52,3 +60,23 @@ func (h *SettingsHandler) UpdatePreferences(w http.ResponseWriter, r *http.Reque
 
 	w.WriteHeader(http.StatusOK)
 }
+
+// BecomeCoach turns the user's account into a coach account, which can
+// create student groups
+func (h *SettingsHandler) BecomeCoach(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.userService.BecomeCoach(r.Context(), user.ID); err != nil {
+		http.Error(w, "Failed to update account", http.StatusInternalServerError)
+		return
+	}
+
+	if r.Header.Get("HX-Request") == "true" {
+		w.Header().Set("HX-Redirect", "/groups")
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
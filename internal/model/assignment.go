@@ -0,0 +1,67 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// AssignmentStatus is how far a student is with an assignment
+type AssignmentStatus string
+
+const (
+	AssignmentCompleted AssignmentStatus = "completed" // targets met
+	AssignmentOnTrack   AssignmentStatus = "on_track"  // keeping pace with the due date
+	AssignmentBehind    AssignmentStatus = "behind"    // short of the pace or the accuracy
+	AssignmentMissed    AssignmentStatus = "missed"    // due date passed without meeting the targets
+)
+
+// Assignment is work a coach sets a group, e.g. 100 find_square attempts
+// at 90% from black's perspective by Friday. Only attempts answered
+// between CreatedAt and DueAt count towards it.
+type Assignment struct {
+	ID        bson.ObjectID `bson:"_id,omitempty" json:"id"`
+	GroupID   bson.ObjectID `bson:"group_id" json:"group_id"`
+	CoachID   bson.ObjectID `bson:"coach_id" json:"coach_id"`
+	Title     string        `bson:"title" json:"title"`
+	DrillType DrillType     `bson:"drill_type" json:"drill_type"`
+	// Perspective restricts the attempts counted; empty counts both
+	Perspective    string    `bson:"perspective,omitempty" json:"perspective,omitempty"`
+	TargetAttempts int       `bson:"target_attempts" json:"target_attempts"`
+	TargetAccuracy float64   `bson:"target_accuracy" json:"target_accuracy"` // percent
+	CreatedAt      time.Time `bson:"created_at" json:"created_at"`
+	DueAt          time.Time `bson:"due_at" json:"due_at"`
+}
+
+// AttemptTally counts a user's attempts matching an assignment
+type AttemptTally struct {
+	UserID   bson.ObjectID `bson:"_id"`
+	Attempts int           `bson:"attempts"`
+	Correct  int           `bson:"correct"`
+	Credit   float64       `bson:"credit"`
+}
+
+// AssignmentProgress is a student's standing on an assignment
+type AssignmentProgress struct {
+	UserID   bson.ObjectID    `json:"user_id"`
+	Username string           `json:"username"`
+	Attempts int              `json:"attempts"`
+	Correct  int              `json:"correct"`
+	Accuracy float64          `json:"accuracy"`
+	Status   AssignmentStatus `json:"status"`
+}
+
+// AssignmentReport is an assignment's progress across its group
+type AssignmentReport struct {
+	Assignment *Assignment              `json:"assignment"`
+	Group      *Group                   `json:"group"`
+	Students   []AssignmentProgress     `json:"students"`
+	Counts     map[AssignmentStatus]int `json:"counts"`
+}
+
+// PendingAssignment is an unfinished assignment on a student's dashboard
+type PendingAssignment struct {
+	Assignment Assignment         `json:"assignment"`
+	GroupName  string             `json:"group_name"`
+	Progress   AssignmentProgress `json:"progress"`
+}
@@ -0,0 +1,24 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// ChallengeEntry is a user's ranked attempt at a daily challenge. Day is
+// the UTC date the challenge belongs to, as YYYY-MM-DD. The score and
+// duration are set once the attempt's session ends.
+type ChallengeEntry struct {
+	ID          bson.ObjectID `bson:"_id,omitempty" json:"id"`
+	Day         string        `bson:"day" json:"day"`
+	DrillType   DrillType     `bson:"drill_type" json:"drill_type"`
+	UserID      bson.ObjectID `bson:"user_id" json:"user_id"`
+	Username    string        `bson:"username" json:"username"`
+	SessionID   bson.ObjectID `bson:"session_id,omitempty" json:"session_id"`
+	StartedAt   time.Time     `bson:"started_at" json:"started_at"`
+	CompletedAt *time.Time    `bson:"completed_at,omitempty" json:"completed_at,omitempty"`
+	Score       float64       `bson:"score" json:"score"`
+	Correct     int           `bson:"correct" json:"correct"`
+	DurationMs  int64         `bson:"duration_ms" json:"duration_ms"`
+}
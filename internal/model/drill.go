@@ -1,6 +1,7 @@
 package model
 
 import (
+	"encoding/json"
 	"time"
 
 	"go.mongodb.org/mongo-driver/v2/bson"
@@ -10,10 +11,19 @@ import (
 type DrillType string
 
 const (
-	DrillTypeNameSquare    DrillType = "name_square"
-	DrillTypeFindSquare    DrillType = "find_square"
-	DrillTypePieceMovement DrillType = "piece_movement"
-	DrillTypeMoveNotation  DrillType = "move_notation"
+	DrillTypeNameSquare       DrillType = "name_square"
+	DrillTypeFindSquare       DrillType = "find_square"
+	DrillTypePieceMovement    DrillType = "piece_movement"
+	DrillTypeMoveNotation     DrillType = "move_notation"
+	DrillTypePawnRules        DrillType = "pawn_rules"
+	DrillTypeCastling         DrillType = "castling"
+	DrillTypeCheckRecognition DrillType = "check_recognition"
+	DrillTypeSquareColor      DrillType = "square_color"
+	DrillTypeKnightDistance   DrillType = "knight_distance"
+	DrillTypeGeometry         DrillType = "board_geometry"
+	DrillTypeBlindfold        DrillType = "blindfold"
+	DrillTypeTactics          DrillType = "tactics"
+	DrillTypeRepertoire       DrillType = "repertoire"
 )
 
 // InputMethod represents how user provides answers
@@ -26,12 +36,75 @@ const (
 	InputMethodBoardClick InputMethod = "board_click"
 )
 
-// DrillSessionSummary contains aggregated stats for a session
+// Difficulty controls how demanding generated questions are
+type Difficulty string
+
+const (
+	DifficultyEasy   Difficulty = "easy"
+	DifficultyMedium Difficulty = "medium"
+	DifficultyHard   Difficulty = "hard"
+)
+
+// SessionMode controls when a drill session ends
+type SessionMode string
+
+const (
+	SessionModeOpen     SessionMode = "open"     // until the user ends it
+	SessionModeTimed    SessionMode = "timed"    // after a fixed time
+	SessionModeCount    SessionMode = "count"    // after a fixed number of questions
+	SessionModeSurvival SessionMode = "survival" // after a number of misses
+	SessionModeReview   SessionMode = "review"   // once no review items are due
+	SessionModeRace     SessionMode = "race"     // when its race room finishes
+)
+
+// ModeSettings holds a session's mode and the limit that applies to it;
+// only the limit matching the mode is set
+type ModeSettings struct {
+	Mode          SessionMode `bson:"mode,omitempty" json:"mode,omitempty"`
+	TimeLimitSec  int         `bson:"time_limit_sec,omitempty" json:"time_limit_sec,omitempty"`
+	QuestionCount int         `bson:"question_count,omitempty" json:"question_count,omitempty"`
+	MaxMisses     int         `bson:"max_misses,omitempty" json:"max_misses,omitempty"`
+}
+
+// RegionKind is how a practice region's squares are chosen
+type RegionKind string
+
+const (
+	RegionFiles     RegionKind = "files"     // whole files, e.g. the d- and e-files
+	RegionRanks     RegionKind = "ranks"     // whole ranks
+	RegionQuadrant  RegionKind = "quadrant"  // a 4x4 corner, named by its corner square
+	RegionCenter    RegionKind = "center"    // the extended center, c3-f6
+	RegionQueenside RegionKind = "queenside" // the a- to d-files
+	RegionKingside  RegionKind = "kingside"  // the e- to h-files
+	RegionSquares   RegionKind = "squares"   // a hand-picked list
+	RegionWeakest   RegionKind = "weakest"   // the user's weakest squares when the session started
+)
+
+// SquareRegion limits the target squares of a drill session. Values are
+// the files, ranks, corner or squares the region was requested with;
+// Squares are the squares it resolved to and Label describes it.
+type SquareRegion struct {
+	Kind    RegionKind `bson:"kind" json:"kind"`
+	Values  []string   `bson:"values,omitempty" json:"values,omitempty"`
+	Squares []string   `bson:"squares" json:"squares"`
+	Label   string     `bson:"label" json:"label"`
+}
+
+// DrillSessionSummary contains aggregated stats for a session. For limited
+// modes, ModeScore is the result the mode is ranked by (credit for timed
+// and fixed-count sessions, correct answers for survival, review and
+// races) and ModeLimit is the session's limit, or the items reviewed.
+// Region is the label of the session's practice region, if it had one.
 type DrillSessionSummary struct {
-	TotalAttempts int `bson:"total_attempts" json:"total_attempts"`
-	Correct       int `bson:"correct" json:"correct"`
-	AvgResponseMs int `bson:"avg_response_ms" json:"avg_response_ms"`
-	StreakBest    int `bson:"streak_best" json:"streak_best"`
+	TotalAttempts int         `bson:"total_attempts" json:"total_attempts"`
+	Correct       int         `bson:"correct" json:"correct"`
+	Credit        float64     `bson:"credit" json:"credit"`
+	AvgResponseMs int         `bson:"avg_response_ms" json:"avg_response_ms"`
+	StreakBest    int         `bson:"streak_best" json:"streak_best"`
+	Mode          SessionMode `bson:"mode,omitempty" json:"mode,omitempty"`
+	ModeScore     float64     `bson:"mode_score,omitempty" json:"mode_score,omitempty"`
+	ModeLimit     int         `bson:"mode_limit,omitempty" json:"mode_limit,omitempty"`
+	Region        string      `bson:"region,omitempty" json:"region,omitempty"`
 }
 
 // DrillSession represents a practice session
@@ -41,45 +114,170 @@ type DrillSession struct {
 	DrillType   DrillType           `bson:"drill_type" json:"drill_type"`
 	InputMethod InputMethod         `bson:"input_method" json:"input_method"`
 	Perspective string              `bson:"perspective" json:"perspective"`
+	Difficulty  Difficulty          `bson:"difficulty,omitempty" json:"difficulty,omitempty"`
 	StartedAt   time.Time           `bson:"started_at" json:"started_at"`
 	EndedAt     *time.Time          `bson:"ended_at,omitempty" json:"ended_at"`
 	Summary     DrillSessionSummary `bson:"summary" json:"summary"`
+
+	ModeSettings `bson:",inline"`
+
+	// Adaptive sessions weight square selection by the user's weakness,
+	// keeping ExplorationFloor of the picks uniform
+	Adaptive         bool    `bson:"adaptive,omitempty" json:"adaptive,omitempty"`
+	ExplorationFloor float64 `bson:"exploration_floor,omitempty" json:"exploration_floor,omitempty"`
+
+	// Region limits the squares drilled; nil drills the whole board
+	Region *SquareRegion `bson:"region,omitempty" json:"region,omitempty"`
+
+	// Piece fixes the piece of a piece movement session; empty varies it
+	Piece string `bson:"piece,omitempty" json:"piece,omitempty"`
+
+	// Seed determines the session's question stream; QuestionsGenerated
+	// is the index of the next generated question within it
+	Seed               int64 `bson:"seed" json:"seed,omitempty"`
+	QuestionsGenerated int   `bson:"questions_generated" json:"-"`
+
+	// ChallengeDay is set on the ranked attempt at that day's challenge
+	ChallengeDay string `bson:"challenge_day,omitempty" json:"challenge_day,omitempty"`
+
+	// RaceID is set on a player's session in a multiplayer race
+	RaceID bson.ObjectID `bson:"race_id,omitempty" json:"race_id,omitempty"`
+
+	// Unranked sessions, and their attempts, are left off the leaderboards
+	Unranked bool `bson:"unranked,omitempty" json:"unranked,omitempty"`
+}
+
+// MarshalJSON leaves out the seed of a challenge attempt, which would let
+// whoever sees it, such as a coach, practise the day's questions before
+// their own attempt
+func (s DrillSession) MarshalJSON() ([]byte, error) {
+	type drillSession DrillSession
+	out := drillSession(s)
+	if out.ChallengeDay != "" {
+		out.Seed = 0
+	}
+	return json.Marshal(out)
 }
 
-func NewDrillSession(userID bson.ObjectID, drillType DrillType, inputMethod InputMethod, perspective string) *DrillSession {
+func NewDrillSession(userID bson.ObjectID, drillType DrillType, inputMethod InputMethod, perspective string, difficulty Difficulty, mode ModeSettings) *DrillSession {
 	return &DrillSession{
-		UserID:      userID,
-		DrillType:   drillType,
-		InputMethod: inputMethod,
-		Perspective: perspective,
-		StartedAt:   time.Now(),
-		Summary:     DrillSessionSummary{},
+		UserID:       userID,
+		DrillType:    drillType,
+		InputMethod:  inputMethod,
+		Perspective:  perspective,
+		Difficulty:   difficulty,
+		StartedAt:    time.Now(),
+		Summary:      DrillSessionSummary{},
+		ModeSettings: mode,
 	}
 }
 
+// Deadline returns when a timed session runs out, or the zero time for
+// other modes
+func (s *DrillSession) Deadline() time.Time {
+	if s.Mode != SessionModeTimed {
+		return time.Time{}
+	}
+	return s.StartedAt.Add(time.Duration(s.TimeLimitSec) * time.Second)
+}
+
 // AttemptMetadata contains additional info for certain drill types
 type AttemptMetadata struct {
 	PieceType  string `bson:"piece_type,omitempty" json:"piece_type,omitempty"`
+	PieceColor string `bson:"piece_color,omitempty" json:"piece_color,omitempty"`
 	FromSquare string `bson:"from_square,omitempty" json:"from_square,omitempty"`
 	FEN        string `bson:"fen,omitempty" json:"fen,omitempty"`
+
+	// Category is the drill-specific kind of question, e.g. "write_san" or
+	// the pawn rule being drilled; stats are broken down by it
+	Category string `bson:"category,omitempty" json:"category,omitempty"`
+
+	// Move answers (move notation), in UCI and SAN
+	Move string `bson:"move,omitempty" json:"move,omitempty"`
+	SAN  string `bson:"san,omitempty" json:"san,omitempty"`
+
+	// Set-based answers (piece movement)
+	ExpectedSquares []string `bson:"expected_squares,omitempty" json:"expected_squares,omitempty"`
+	MissedSquares   []string `bson:"missed_squares,omitempty" json:"missed_squares,omitempty"`
+	ExtraSquares    []string `bson:"extra_squares,omitempty" json:"extra_squares,omitempty"`
+	Precision       float64  `bson:"precision,omitempty" json:"precision,omitempty"`
+	Recall          float64  `bson:"recall,omitempty" json:"recall,omitempty"`
+
+	// Obstacle concepts (piece movement with blockers): captures available,
+	// captures the user missed, and selected squares that are blocked
+	CaptureSquares []string `bson:"capture_squares,omitempty" json:"capture_squares,omitempty"`
+	MissedCaptures []string `bson:"missed_captures,omitempty" json:"missed_captures,omitempty"`
+	BlockedSquares []string `bson:"blocked_squares,omitempty" json:"blocked_squares,omitempty"`
+
+	// Castling drill: which side was asked about ("kingside"/"queenside")
+	CastlingSide string `bson:"castling_side,omitempty" json:"castling_side,omitempty"`
+
+	// Check recognition: the classification the user gave, and the squares
+	// of the pieces actually giving check. The true classification is the
+	// Category; checker selections are graded into the set-based fields.
+	Classification string   `bson:"classification,omitempty" json:"classification,omitempty"`
+	Checkers       []string `bson:"checkers,omitempty" json:"checkers,omitempty"`
+
+	// Knight drill: the true minimum number of knight moves, the distance the
+	// user estimated, and the route the user clicked in path mode
+	KnightDistance int      `bson:"knight_distance,omitempty" json:"knight_distance,omitempty"`
+	GivenDistance  int      `bson:"given_distance,omitempty" json:"given_distance,omitempty"`
+	KnightPath     []string `bson:"knight_path,omitempty" json:"knight_path,omitempty"`
+
+	// Blindfold drill: the numbered SAN sequence that was read out and its
+	// length in half-moves
+	Moves          string `bson:"moves,omitempty" json:"moves,omitempty"`
+	SequenceLength int    `bson:"sequence_length,omitempty" json:"sequence_length,omitempty"`
+
+	// Tactics drill: the puzzle, its rating and theme tags, the index of the
+	// graded move in the puzzle's move list, and whether it completed the
+	// puzzle
+	PuzzleID     string   `bson:"puzzle_id,omitempty" json:"puzzle_id,omitempty"`
+	PuzzleRating int      `bson:"puzzle_rating,omitempty" json:"puzzle_rating,omitempty"`
+	Themes       []string `bson:"themes,omitempty" json:"themes,omitempty"`
+	PuzzlePly    int      `bson:"puzzle_ply,omitempty" json:"puzzle_ply,omitempty"`
+	PuzzleSolved bool     `bson:"puzzle_solved,omitempty" json:"puzzle_solved,omitempty"`
+
+	// Repertoire drill: the line being played, the index of the graded move
+	// in it, whether it was the line's last move for the user, and the other
+	// prepared move the user played instead of the line's, if any
+	Line            string `bson:"line,omitempty" json:"line,omitempty"`
+	LinePly         int    `bson:"line_ply,omitempty" json:"line_ply,omitempty"`
+	LineComplete    bool   `bson:"line_complete,omitempty" json:"line_complete,omitempty"`
+	AlternativeMove string `bson:"alternative_move,omitempty" json:"alternative_move,omitempty"`
 }
 
-// Attempt represents a single question-answer attempt
+// Attempt represents a single question-answer attempt. Square is the board
+// square the question was about and keys the heat map; Score is the credit
+// earned between 0 and 1, which is fractional for set-based answers.
+// Perspective is the board orientation of the session and Region the
+// label of its practice region.
 type Attempt struct {
 	ID            bson.ObjectID   `bson:"_id,omitempty" json:"id"`
 	SessionID     bson.ObjectID   `bson:"session_id" json:"session_id"`
 	UserID        bson.ObjectID   `bson:"user_id" json:"user_id"`
 	DrillType     DrillType       `bson:"drill_type" json:"drill_type"`
 	Question      string          `bson:"question" json:"question"`
+	Square        string          `bson:"square,omitempty" json:"square,omitempty"`
+	Perspective   string          `bson:"perspective,omitempty" json:"perspective,omitempty"`
+	Region        string          `bson:"region,omitempty" json:"region,omitempty"`
+	Piece         string          `bson:"piece,omitempty" json:"piece,omitempty"`
+	Unranked      bool            `bson:"unranked,omitempty" json:"unranked,omitempty"`
 	CorrectAnswer string          `bson:"correct_answer" json:"correct_answer"`
 	UserAnswer    string          `bson:"user_answer" json:"user_answer"`
 	Correct       bool            `bson:"correct" json:"correct"`
+	Score         float64         `bson:"score" json:"score"`
 	ResponseMs    int             `bson:"response_ms" json:"response_ms"`
 	AnsweredAt    time.Time       `bson:"answered_at" json:"answered_at"`
 	Metadata      AttemptMetadata `bson:"metadata,omitempty" json:"metadata,omitempty"`
 }
 
 func NewAttempt(sessionID, userID bson.ObjectID, drillType DrillType, question, correctAnswer, userAnswer string, responseMs int) *Attempt {
+	correct := correctAnswer == userAnswer
+	score := 0.0
+	if correct {
+		score = 1
+	}
 	return &Attempt{
 		SessionID:     sessionID,
 		UserID:        userID,
@@ -87,17 +285,41 @@ func NewAttempt(sessionID, userID bson.ObjectID, drillType DrillType, question,
 		Question:      question,
 		CorrectAnswer: correctAnswer,
 		UserAnswer:    userAnswer,
-		Correct:       correctAnswer == userAnswer,
+		Correct:       correct,
+		Score:         score,
 		ResponseMs:    responseMs,
 		AnsweredAt:    time.Now(),
 	}
 }
 
-// Question represents a drill question sent to the client
+// Question represents a drill question issued to the client. The expected
+// answer, and any AnswerData needed to grade it, is stored server-side only
+// and never serialized to JSON.
 type Question struct {
-	Type     DrillType         `json:"type"`
-	Target   string            `json:"target"`
-	Prompt   string            `json:"prompt"`
-	FEN      string            `json:"fen,omitempty"`
-	Metadata map[string]string `json:"metadata,omitempty"`
+	ID         bson.ObjectID     `bson:"_id,omitempty" json:"id"`
+	SessionID  bson.ObjectID     `bson:"session_id" json:"session_id"`
+	UserID     bson.ObjectID     `bson:"user_id" json:"-"`
+	Type       DrillType         `bson:"type" json:"type"`
+	Target     string            `bson:"target" json:"target"`
+	Prompt     string            `bson:"prompt" json:"prompt"`
+	FEN        string            `bson:"fen,omitempty" json:"fen,omitempty"`
+	Metadata   map[string]string `bson:"metadata,omitempty" json:"metadata,omitempty"`
+	Answer     string            `bson:"answer" json:"-"`
+	AnswerData map[string]string `bson:"answer_data,omitempty" json:"-"`
+	IssuedAt   time.Time         `bson:"issued_at" json:"-"`
+	AnsweredAt *time.Time        `bson:"answered_at,omitempty" json:"-"`
+}
+
+// AnswerResult is the graded outcome of a submitted answer
+type AnswerResult struct {
+	Correct       bool    `json:"correct"`
+	Score         float64 `json:"score"`
+	CorrectAnswer string  `json:"correct_answer"`
+	Explanation   string  `json:"explanation,omitempty"`
+
+	// Summary is set when the answer ended the session, in which case no
+	// further question is issued. TimeUp marks an answer that arrived after
+	// a timed session ran out and was not graded.
+	Summary *DrillSessionSummary `json:"summary,omitempty"`
+	TimeUp  bool                 `json:"time_up,omitempty"`
 }
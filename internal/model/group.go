@@ -0,0 +1,50 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Group is a coach's class of students. Students join with the group's
+// code; while they are members the coach can see their stats and sessions.
+type Group struct {
+	ID        bson.ObjectID `bson:"_id,omitempty" json:"id"`
+	CoachID   bson.ObjectID `bson:"coach_id" json:"coach_id"`
+	Name      string        `bson:"name" json:"name"`
+	JoinCode  string        `bson:"join_code" json:"join_code"`
+	Members   []GroupMember `bson:"members" json:"members"`
+	CreatedAt time.Time     `bson:"created_at" json:"created_at"`
+}
+
+type GroupMember struct {
+	UserID   bson.ObjectID `bson:"user_id" json:"user_id"`
+	Username string        `bson:"username" json:"username"`
+	JoinedAt time.Time     `bson:"joined_at" json:"joined_at"`
+}
+
+func NewGroup(coachID bson.ObjectID, name, joinCode string) *Group {
+	return &Group{
+		CoachID:   coachID,
+		Name:      name,
+		JoinCode:  joinCode,
+		Members:   []GroupMember{},
+		CreatedAt: time.Now(),
+	}
+}
+
+// StudentOverview is a member's row on a group dashboard
+type StudentOverview struct {
+	Member         GroupMember    `json:"member"`
+	Stats          *OverallStats  `json:"stats"`
+	RecentSessions []DrillSession `json:"recent_sessions"`
+}
+
+// StudentReport is everything a coach sees about one member
+type StudentReport struct {
+	Group          *Group         `json:"group"`
+	Member         GroupMember    `json:"member"`
+	Stats          *OverallStats  `json:"stats"`
+	Heatmap        *HeatmapData   `json:"heatmap"`
+	RecentSessions []DrillSession `json:"recent_sessions"`
+}
@@ -0,0 +1,57 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// LeaderboardMetric is what a leaderboard ranks users by
+type LeaderboardMetric string
+
+const (
+	LeaderboardTimedScore LeaderboardMetric = "timed_score" // best timed session score
+	LeaderboardBestStreak LeaderboardMetric = "best_streak" // longest streak within a session
+	LeaderboardFastestAvg LeaderboardMetric = "fastest_avg" // lowest average response to correct answers
+)
+
+// LeaderboardPeriod is how far back a leaderboard looks
+type LeaderboardPeriod string
+
+const (
+	LeaderboardWeek  LeaderboardPeriod = "week"
+	LeaderboardMonth LeaderboardPeriod = "month"
+	LeaderboardAll   LeaderboardPeriod = "all"
+)
+
+// Leaderboard is a precomputed ranking. An empty DrillType or Perspective
+// covers all of them.
+type Leaderboard struct {
+	ID          bson.ObjectID      `bson:"_id,omitempty" json:"-"`
+	Metric      LeaderboardMetric  `bson:"metric" json:"metric"`
+	DrillType   DrillType          `bson:"drill_type" json:"drill_type"`
+	Perspective string             `bson:"perspective" json:"perspective"`
+	Period      LeaderboardPeriod  `bson:"period" json:"period"`
+	Entries     []LeaderboardEntry `bson:"entries" json:"entries"`
+	ComputedAt  time.Time          `bson:"computed_at" json:"computed_at"`
+}
+
+// LeaderboardEntry is a user's ranked result. Count is the number of
+// sessions, or of correct answers for the fastest average, behind it.
+type LeaderboardEntry struct {
+	UserID   bson.ObjectID `bson:"user_id" json:"user_id"`
+	Username string        `bson:"username" json:"username"`
+	Value    float64       `bson:"value" json:"value"`
+	Count    int           `bson:"count" json:"count"`
+}
+
+// LeaderboardRow is a user's aggregated result for one drill type and
+// perspective, before rankings are built. Value is a maximum or, for
+// averages, a sum over Count.
+type LeaderboardRow struct {
+	UserID      bson.ObjectID `bson:"user_id"`
+	DrillType   DrillType     `bson:"drill_type"`
+	Perspective string        `bson:"perspective"`
+	Value       float64       `bson:"value"`
+	Count       int           `bson:"count"`
+}
@@ -0,0 +1,25 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Puzzle is a tactics puzzle imported from the Lichess puzzle database. FEN
+// is the position before the opponent's move; Moves holds that move followed
+// by the solution, alternating between the solver and the opponent, in UCI.
+type Puzzle struct {
+	ID              bson.ObjectID `bson:"_id,omitempty" json:"id"`
+	PuzzleID        string        `bson:"puzzle_id" json:"puzzle_id"`
+	FEN             string        `bson:"fen" json:"fen"`
+	Moves           []string      `bson:"moves" json:"moves"`
+	Rating          int           `bson:"rating" json:"rating"`
+	RatingDeviation int           `bson:"rating_deviation" json:"rating_deviation"`
+	Popularity      int           `bson:"popularity" json:"popularity"`
+	NbPlays         int           `bson:"nb_plays" json:"nb_plays"`
+	Themes          []string      `bson:"themes" json:"themes"`
+	GameURL         string        `bson:"game_url,omitempty" json:"game_url,omitempty"`
+	OpeningTags     []string      `bson:"opening_tags,omitempty" json:"opening_tags,omitempty"`
+	ImportedAt      time.Time     `bson:"imported_at" json:"imported_at"`
+}
@@ -0,0 +1,81 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// RaceStatus is the stage a race room is in
+type RaceStatus string
+
+const (
+	RaceStatusLobby    RaceStatus = "lobby"    // waiting for players
+	RaceStatusRunning  RaceStatus = "running"  // questions are being played
+	RaceStatusFinished RaceStatus = "finished" // results are final
+)
+
+// RaceResult is the stored outcome of a finished race. Each player's
+// answers are recorded in their own drill session, which links back to
+// the result through its RaceID.
+type RaceResult struct {
+	ID         bson.ObjectID      `bson:"_id" json:"id"`
+	Code       string             `bson:"code" json:"code"`
+	DrillType  DrillType          `bson:"drill_type" json:"drill_type"`
+	Rounds     int                `bson:"rounds" json:"rounds"`
+	Players    []RacePlayerResult `bson:"players" json:"players"`
+	StartedAt  time.Time          `bson:"started_at" json:"started_at"`
+	FinishedAt time.Time          `bson:"finished_at" json:"finished_at"`
+}
+
+// RacePlayerResult is a player's final standing in a race
+type RacePlayerResult struct {
+	UserID    bson.ObjectID `bson:"user_id" json:"user_id"`
+	Username  string        `bson:"username" json:"username"`
+	SessionID bson.ObjectID `bson:"session_id" json:"session_id"`
+	Points    int           `bson:"points" json:"points"`
+	Rank      int           `bson:"rank" json:"rank"`
+}
+
+// RaceState is the snapshot of a room sent to its players whenever it
+// changes. Times are the server's, so clients can correct for clock skew.
+type RaceState struct {
+	Code        string           `json:"code"`
+	DrillType   DrillType        `json:"drill_type"`
+	Status      RaceStatus       `json:"status"`
+	HostID      bson.ObjectID    `json:"host_id"`
+	Players     []RaceStanding   `json:"players"`
+	Round       int              `json:"round"`
+	Rounds      int              `json:"rounds"`
+	Question    *RaceQuestion    `json:"question,omitempty"`
+	StartsAt    *time.Time       `json:"starts_at,omitempty"`
+	RoundEndsAt *time.Time       `json:"round_ends_at,omitempty"`
+	LastRound   *RaceRoundResult `json:"last_round,omitempty"`
+	ServerTime  time.Time        `json:"server_time"`
+}
+
+// RaceStanding is a player's place in a room's live standings
+type RaceStanding struct {
+	UserID    bson.ObjectID `json:"user_id"`
+	Username  string        `json:"username"`
+	Points    int           `json:"points"`
+	Answered  bool          `json:"answered"`
+	Connected bool          `json:"connected"`
+}
+
+// RaceQuestion is the part of a round's question every player sees
+type RaceQuestion struct {
+	Type   DrillType `json:"type"`
+	Target string    `json:"target"`
+	Prompt string    `json:"prompt"`
+	FEN    string    `json:"fen"`
+}
+
+// RaceRoundResult is how the previous round ended; WinnerID is zero when
+// nobody answered correctly in time
+type RaceRoundResult struct {
+	Round      int           `json:"round"`
+	WinnerID   bson.ObjectID `json:"winner_id"`
+	WinnerName string        `json:"winner_name,omitempty"`
+	Answer     string        `json:"answer"`
+}
@@ -0,0 +1,36 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Repertoire is the opening moves a user has prepared for one color, merged
+// from the games and variations of an uploaded PGN into one tree. Moves is
+// the tree stored flat: each move names the index of the move it follows.
+type Repertoire struct {
+	ID         bson.ObjectID    `bson:"_id,omitempty" json:"id"`
+	UserID     bson.ObjectID    `bson:"user_id" json:"user_id"`
+	Color      string           `bson:"color" json:"color"` // the side the user plays
+	Moves      []RepertoireMove `bson:"moves" json:"moves"`
+	UploadedAt time.Time        `bson:"uploaded_at" json:"uploaded_at"`
+}
+
+// RepertoireMove is a move of a repertoire's tree. Parent is the index of
+// the move it follows, or -1 for a first move.
+type RepertoireMove struct {
+	Parent  int    `bson:"parent" json:"parent"`
+	UCI     string `bson:"uci" json:"uci"`
+	SAN     string `bson:"san" json:"san"`
+	Comment string `bson:"comment,omitempty" json:"comment,omitempty"`
+}
+
+// RepertoireOverview is a repertoire's size with the stats of each of its
+// lines
+type RepertoireOverview struct {
+	Color      string      `json:"color"`
+	Moves      int         `json:"moves"`
+	UploadedAt time.Time   `json:"uploaded_at"`
+	Lines      []LineStats `json:"lines"`
+}
@@ -0,0 +1,39 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// ReviewItem is a user's spaced repetition state for one drill item, kept
+// with the SM-2 algorithm. Square drills are keyed by the square, piece
+// drills by the square and the position. The question the item was first
+// seen in is stored so review sessions can serve it again.
+type ReviewItem struct {
+	ID        bson.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID    bson.ObjectID `bson:"user_id" json:"user_id"`
+	DrillType DrillType     `bson:"drill_type" json:"drill_type"`
+	Key       string        `bson:"key" json:"key"`
+
+	Target     string            `bson:"target" json:"target"`
+	Prompt     string            `bson:"prompt" json:"prompt"`
+	FEN        string            `bson:"fen,omitempty" json:"fen,omitempty"`
+	Metadata   map[string]string `bson:"metadata,omitempty" json:"metadata,omitempty"`
+	Answer     string            `bson:"answer" json:"-"`
+	AnswerData map[string]string `bson:"answer_data,omitempty" json:"-"`
+
+	Easiness       float64   `bson:"easiness" json:"easiness"`
+	IntervalDays   int       `bson:"interval_days" json:"interval_days"`
+	Repetitions    int       `bson:"repetitions" json:"repetitions"`
+	Lapses         int       `bson:"lapses" json:"lapses"`
+	DueAt          time.Time `bson:"due_at" json:"due_at"`
+	LastReviewedAt time.Time `bson:"last_reviewed_at" json:"last_reviewed_at"`
+	CreatedAt      time.Time `bson:"created_at" json:"created_at"`
+}
+
+// ReviewDue counts the review items of a drill type that are due
+type ReviewDue struct {
+	DrillType string `bson:"_id" json:"drill_type"`
+	Count     int    `bson:"count" json:"count"`
+}
@@ -1,32 +1,71 @@
 package model
 
-// SquareAccuracy represents accuracy data for a specific square
+// SquareAccuracy represents accuracy data for a specific square. Accuracy
+// is based on earned credit, so partially correct answers count partially.
 type SquareAccuracy struct {
 	Square   string  `json:"square"`
 	Total    int     `json:"total"`
 	Correct  int     `json:"correct"`
+	Credit   float64 `json:"credit"`
 	Accuracy float64 `json:"accuracy"`
+
+	AvgResponseMs int `json:"avg_response_ms"`
+}
+
+// CategoryStats represents aggregated stats for one category of questions
+// within a drill type, such as a single pawn rule
+type CategoryStats struct {
+	Category        string  `json:"category"`
+	TotalAttempts   int     `json:"total_attempts"`
+	CorrectAttempts int     `json:"correct_attempts"`
+	Accuracy        float64 `json:"accuracy"`
+	AvgResponseMs   int     `json:"avg_response_ms"`
 }
 
 // DrillStats represents aggregated stats for a drill type
 type DrillStats struct {
-	DrillType       string  `json:"drill_type"`
+	DrillType       string          `json:"drill_type"`
+	TotalAttempts   int             `json:"total_attempts"`
+	CorrectAttempts int             `json:"correct_attempts"`
+	Accuracy        float64         `json:"accuracy"`
+	AvgResponseMs   int             `json:"avg_response_ms"`
+	BestStreak      int             `json:"best_streak"`
+	CurrentStreak   int             `json:"current_streak"`
+	Categories      []CategoryStats `json:"categories,omitempty"`
+}
+
+// RegionStats represents aggregated stats for the attempts made while
+// practising one region
+type RegionStats struct {
+	Region          string  `json:"region"`
+	TotalAttempts   int     `json:"total_attempts"`
+	CorrectAttempts int     `json:"correct_attempts"`
+	Accuracy        float64 `json:"accuracy"`
+	AvgResponseMs   int     `json:"avg_response_ms"`
+}
+
+// LineStats represents aggregated stats for the moves asked in one line
+// of a repertoire, written as its numbered moves, e.g. "1.e4 c5 2.Nf3"
+type LineStats struct {
+	Perspective     string  `json:"perspective"`
+	Line            string  `json:"line"`
 	TotalAttempts   int     `json:"total_attempts"`
 	CorrectAttempts int     `json:"correct_attempts"`
+	Credit          float64 `json:"credit"`
 	Accuracy        float64 `json:"accuracy"`
 	AvgResponseMs   int     `json:"avg_response_ms"`
-	BestStreak      int     `json:"best_streak"`
-	CurrentStreak   int     `json:"current_streak"`
 }
 
 // OverallStats represents user's overall performance
 type OverallStats struct {
-	TotalSessions   int          `json:"total_sessions"`
-	TotalAttempts   int          `json:"total_attempts"`
-	OverallAccuracy float64      `json:"overall_accuracy"`
-	AvgResponseMs   int          `json:"avg_response_ms"`
-	BestStreak      int          `json:"best_streak"`
-	DrillStats      []DrillStats `json:"drill_stats"`
+	TotalSessions   int           `json:"total_sessions"`
+	TotalAttempts   int           `json:"total_attempts"`
+	OverallAccuracy float64       `json:"overall_accuracy"`
+	AvgResponseMs   int           `json:"avg_response_ms"`
+	BestStreak      int           `json:"best_streak"`
+	DrillStats      []DrillStats  `json:"drill_stats"`
+	RegionStats     []RegionStats `json:"region_stats,omitempty"`
+	LineStats       []LineStats   `json:"line_stats,omitempty"`
 }
 
 // HeatmapData represents accuracy data for the heat map visualization
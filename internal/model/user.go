@@ -10,6 +10,41 @@ type Preferences struct {
 	Perspective     string `bson:"perspective" json:"perspective"`
 	ShowCoordinates bool   `bson:"show_coordinates" json:"show_coordinates"`
 	Theme           string `bson:"theme" json:"theme"`
+
+	// Adaptive practice favors squares the user is weak or slow on;
+	// ExplorationFloor is the share of picks that stay uniform
+	Adaptive         bool    `bson:"adaptive" json:"adaptive"`
+	ExplorationFloor float64 `bson:"exploration_floor" json:"exploration_floor"`
+
+	// HideFromLeaderboards keeps the user off every leaderboard
+	HideFromLeaderboards bool `bson:"hide_from_leaderboards" json:"hide_from_leaderboards"`
+}
+
+// UserRole decides what a user may do beyond their own practice
+type UserRole string
+
+const (
+	RoleStudent UserRole = ""      // every user starts as a student
+	RoleCoach   UserRole = "coach" // can create groups and follow the members who join them
+)
+
+// DrillPreset is a named drill setup the user can start with one click.
+// Its region holds only the kind, values and label; the squares are worked
+// out when a session starts. Piece picks the piece of piece movement
+// drills, or a random one if empty.
+type DrillPreset struct {
+	ID          bson.ObjectID `bson:"_id" json:"id"`
+	Name        string        `bson:"name" json:"name"`
+	DrillType   DrillType     `bson:"drill_type" json:"drill_type"`
+	Perspective string        `bson:"perspective" json:"perspective"`
+	InputMethod InputMethod   `bson:"input_method" json:"input_method"`
+	Difficulty  Difficulty    `bson:"difficulty,omitempty" json:"difficulty,omitempty"`
+
+	ModeSettings `bson:",inline"`
+
+	Region    *SquareRegion `bson:"region,omitempty" json:"region,omitempty"`
+	Piece     string        `bson:"piece,omitempty" json:"piece,omitempty"`
+	CreatedAt time.Time     `bson:"created_at" json:"created_at"`
 }
 
 type User struct {
@@ -17,7 +52,9 @@ type User struct {
 	Email        string        `bson:"email" json:"email"`
 	Username     string        `bson:"username" json:"username"`
 	PasswordHash string        `bson:"password_hash" json:"-"`
+	Role         UserRole      `bson:"role,omitempty" json:"role,omitempty"`
 	Preferences  Preferences   `bson:"preferences" json:"preferences"`
+	Presets      []DrillPreset `bson:"presets,omitempty" json:"presets,omitempty"`
 	CreatedAt    time.Time     `bson:"created_at" json:"created_at"`
 	UpdatedAt    time.Time     `bson:"updated_at" json:"updated_at"`
 }
@@ -29,11 +66,22 @@ func NewUser(email, username, passwordHash string) *User {
 		Username:     username,
 		PasswordHash: passwordHash,
 		Preferences: Preferences{
-			Perspective:     "white",
-			ShowCoordinates: true,
-			Theme:           "light",
+			Perspective:      "white",
+			ShowCoordinates:  true,
+			Theme:            "light",
+			ExplorationFloor: 0.25,
 		},
 		CreatedAt: now,
 		UpdatedAt: now,
 	}
 }
+
+// Preset returns the user's preset with an ID, or nil if there is none
+func (u *User) Preset(id bson.ObjectID) *DrillPreset {
+	for i := range u.Presets {
+		if u.Presets[i].ID == id {
+			return &u.Presets[i]
+		}
+	}
+	return nil
+}
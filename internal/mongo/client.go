@@ -91,8 +91,19 @@ func (c *Client) CreateIndexes(ctx context.Context) error {
 
 	// Drill sessions collection indexes
 	drillSessionsCollection := c.Collection("drill_sessions")
-	_, err = drillSessionsCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
-		Keys: bson.D{{Key: "user_id", Value: 1}},
+	_, err = drillSessionsCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "started_at", Value: -1}},
+		},
+		{
+			Keys: bson.D{
+				{Key: "mode", Value: 1},
+				{Key: "started_at", Value: 1},
+			},
+		},
 	})
 	if err != nil {
 		return fmt.Errorf("failed to create drill_sessions indexes: %w", err)
@@ -120,11 +131,176 @@ func (c *Client) CreateIndexes(ctx context.Context) error {
 				{Key: "correct_answer", Value: 1},
 			},
 		},
+		{
+			Keys: bson.D{
+				{Key: "user_id", Value: 1},
+				{Key: "square", Value: 1},
+			},
+		},
+		{
+			Keys: bson.D{
+				{Key: "correct", Value: 1},
+				{Key: "answered_at", Value: -1},
+			},
+		},
 	})
 	if err != nil {
 		return fmt.Errorf("failed to create attempts indexes: %w", err)
 	}
 
+	// Questions collection indexes
+	questionsCollection := c.Collection("questions")
+	_, err = questionsCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "session_id", Value: 1},
+			{Key: "issued_at", Value: -1},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create questions indexes: %w", err)
+	}
+
+	// Puzzles collection indexes
+	puzzlesCollection := c.Collection("puzzles")
+	_, err = puzzlesCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "puzzle_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{
+				{Key: "rating", Value: 1},
+				{Key: "puzzle_id", Value: 1},
+			},
+		},
+		{
+			Keys: bson.D{{Key: "themes", Value: 1}},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create puzzles indexes: %w", err)
+	}
+
+	// Review items collection indexes
+	reviewItemsCollection := c.Collection("review_items")
+	_, err = reviewItemsCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "user_id", Value: 1},
+				{Key: "drill_type", Value: 1},
+				{Key: "key", Value: 1},
+			},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{
+				{Key: "user_id", Value: 1},
+				{Key: "drill_type", Value: 1},
+				{Key: "due_at", Value: 1},
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create review_items indexes: %w", err)
+	}
+
+	// Challenge entries collection indexes
+	challengeEntriesCollection := c.Collection("challenge_entries")
+	_, err = challengeEntriesCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "user_id", Value: 1},
+				{Key: "day", Value: 1},
+				{Key: "drill_type", Value: 1},
+			},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{
+				{Key: "day", Value: 1},
+				{Key: "drill_type", Value: 1},
+				{Key: "score", Value: -1},
+				{Key: "duration_ms", Value: 1},
+			},
+		},
+		{
+			Keys: bson.D{{Key: "session_id", Value: 1}},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create challenge_entries indexes: %w", err)
+	}
+
+	// Race results collection indexes
+	raceResultsCollection := c.Collection("race_results")
+	_, err = raceResultsCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "players.user_id", Value: 1},
+			{Key: "finished_at", Value: -1},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create race_results indexes: %w", err)
+	}
+
+	// Leaderboards collection indexes
+	leaderboardsCollection := c.Collection("leaderboards")
+	_, err = leaderboardsCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "metric", Value: 1},
+			{Key: "drill_type", Value: 1},
+			{Key: "perspective", Value: 1},
+			{Key: "period", Value: 1},
+		},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create leaderboards indexes: %w", err)
+	}
+
+	// Groups collection indexes
+	groupsCollection := c.Collection("groups")
+	_, err = groupsCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "join_code", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "coach_id", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "members.user_id", Value: 1}},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create groups indexes: %w", err)
+	}
+
+	// Assignments collection indexes
+	assignmentsCollection := c.Collection("assignments")
+	_, err = assignmentsCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "group_id", Value: 1},
+			{Key: "due_at", Value: -1},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create assignments indexes: %w", err)
+	}
+
+	// Repertoires collection indexes
+	repertoiresCollection := c.Collection("repertoires")
+	_, err = repertoiresCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "user_id", Value: 1},
+			{Key: "color", Value: 1},
+		},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create repertoires indexes: %w", err)
+	}
+
 	log.Println("MongoDB indexes created successfully")
 	return nil
 }
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/abdul-hamid-achik/chessdrill/internal/model"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+var ErrAssignmentNotFound = errors.New("assignment not found")
+
+type AssignmentRepository struct {
+	collection *mongo.Collection
+}
+
+func NewAssignmentRepository(db *mongo.Database) *AssignmentRepository {
+	return &AssignmentRepository{
+		collection: db.Collection("assignments"),
+	}
+}
+
+func (r *AssignmentRepository) Create(ctx context.Context, assignment *model.Assignment) error {
+	result, err := r.collection.InsertOne(ctx, assignment)
+	if err != nil {
+		return err
+	}
+	assignment.ID = result.InsertedID.(bson.ObjectID)
+	return nil
+}
+
+func (r *AssignmentRepository) FindByID(ctx context.Context, id bson.ObjectID) (*model.Assignment, error) {
+	var assignment model.Assignment
+	if err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&assignment); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrAssignmentNotFound
+		}
+		return nil, err
+	}
+	return &assignment, nil
+}
+
+// FindByGroup returns a group's assignments, latest due first
+func (r *AssignmentRepository) FindByGroup(ctx context.Context, groupID bson.ObjectID) ([]model.Assignment, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "due_at", Value: -1}})
+	return r.find(ctx, bson.M{"group_id": groupID}, opts)
+}
+
+// FindOpen returns the assignments of any of the groups that are due after
+// a time, soonest first
+func (r *AssignmentRepository) FindOpen(ctx context.Context, groupIDs []bson.ObjectID, dueAfter time.Time) ([]model.Assignment, error) {
+	if len(groupIDs) == 0 {
+		return nil, nil
+	}
+	filter := bson.M{"group_id": bson.M{"$in": groupIDs}, "due_at": bson.M{"$gt": dueAfter}}
+	opts := options.Find().SetSort(bson.D{{Key: "due_at", Value: 1}})
+	return r.find(ctx, filter, opts)
+}
+
+func (r *AssignmentRepository) find(ctx context.Context, filter bson.M, opts *options.FindOptionsBuilder) ([]model.Assignment, error) {
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	assignments := []model.Assignment{}
+	if err := cursor.All(ctx, &assignments); err != nil {
+		return nil, err
+	}
+	return assignments, nil
+}
+
+// Delete removes an assignment set by the coach and returns it
+func (r *AssignmentRepository) Delete(ctx context.Context, coachID, id bson.ObjectID) (*model.Assignment, error) {
+	var assignment model.Assignment
+	err := r.collection.FindOneAndDelete(ctx, bson.M{"_id": id, "coach_id": coachID}).Decode(&assignment)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrAssignmentNotFound
+		}
+		return nil, err
+	}
+	return &assignment, nil
+}
+
+// DeleteByGroup removes every assignment of a group
+func (r *AssignmentRepository) DeleteByGroup(ctx context.Context, groupID bson.ObjectID) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"group_id": groupID})
+	return err
+}
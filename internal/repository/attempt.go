@@ -2,12 +2,17 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/abdul-hamid-achik/chessdrill/internal/model"
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
 )
 
+// creditExpr is the credit earned by an attempt. Attempts recorded before
+// partial credit existed have no score field and fall back to 1 or 0.
+var creditExpr = bson.M{"$ifNull": []interface{}{"$score", bson.M{"$cond": []interface{}{"$correct", 1, 0}}}}
+
 type AttemptRepository struct {
 	collection *mongo.Collection
 }
@@ -48,6 +53,7 @@ func (r *AttemptRepository) GetSessionSummary(ctx context.Context, sessionID bso
 			"_id":             nil,
 			"total_attempts":  bson.M{"$sum": 1},
 			"correct":         bson.M{"$sum": bson.M{"$cond": []interface{}{"$correct", 1, 0}}},
+			"credit":          bson.M{"$sum": creditExpr},
 			"avg_response_ms": bson.M{"$avg": "$response_ms"},
 		}},
 	}
@@ -61,6 +67,7 @@ func (r *AttemptRepository) GetSessionSummary(ctx context.Context, sessionID bso
 	var results []struct {
 		TotalAttempts int     `bson:"total_attempts"`
 		Correct       int     `bson:"correct"`
+		Credit        float64 `bson:"credit"`
 		AvgResponseMs float64 `bson:"avg_response_ms"`
 	}
 	if err := cursor.All(ctx, &results); err != nil {
@@ -77,6 +84,7 @@ func (r *AttemptRepository) GetSessionSummary(ctx context.Context, sessionID bso
 	return &model.DrillSessionSummary{
 		TotalAttempts: results[0].TotalAttempts,
 		Correct:       results[0].Correct,
+		Credit:        results[0].Credit,
 		AvgResponseMs: int(results[0].AvgResponseMs),
 		StreakBest:    bestStreak,
 	}, nil
@@ -109,14 +117,33 @@ func (r *AttemptRepository) calculateBestStreak(ctx context.Context, sessionID b
 	return bestStreak
 }
 
-// GetSquareAccuracy returns accuracy stats for each square
+// GetSquareAccuracy returns accuracy stats for each square. Attempts are keyed
+// by the square they were about, falling back to the correct answer for
+// attempts recorded before the square field existed.
 func (r *AttemptRepository) GetSquareAccuracy(ctx context.Context, userID bson.ObjectID) ([]model.SquareAccuracy, error) {
+	return r.squareAccuracy(ctx, bson.M{"user_id": userID})
+}
+
+// GetDrillSquareAccuracy returns accuracy stats for each square within one
+// drill type and perspective. Attempts recorded before the perspective was
+// stored count as white, the default.
+func (r *AttemptRepository) GetDrillSquareAccuracy(ctx context.Context, userID bson.ObjectID, drillType model.DrillType, perspective string) ([]model.SquareAccuracy, error) {
+	match := bson.M{"user_id": userID, "drill_type": drillType, "perspective": perspective}
+	if perspective == "white" {
+		match["perspective"] = bson.M{"$in": []interface{}{"white", nil}}
+	}
+	return r.squareAccuracy(ctx, match)
+}
+
+func (r *AttemptRepository) squareAccuracy(ctx context.Context, match bson.M) ([]model.SquareAccuracy, error) {
 	pipeline := []bson.M{
-		{"$match": bson.M{"user_id": userID}},
+		{"$match": match},
 		{"$group": bson.M{
-			"_id":     "$correct_answer",
-			"total":   bson.M{"$sum": 1},
-			"correct": bson.M{"$sum": bson.M{"$cond": []interface{}{"$correct", 1, 0}}},
+			"_id":             bson.M{"$ifNull": []interface{}{"$square", "$correct_answer"}},
+			"total":           bson.M{"$sum": 1},
+			"correct":         bson.M{"$sum": bson.M{"$cond": []interface{}{"$correct", 1, 0}}},
+			"credit":          bson.M{"$sum": creditExpr},
+			"avg_response_ms": bson.M{"$avg": "$response_ms"},
 		}},
 	}
 
@@ -127,9 +154,11 @@ func (r *AttemptRepository) GetSquareAccuracy(ctx context.Context, userID bson.O
 	defer cursor.Close(ctx)
 
 	var results []struct {
-		Square  string `bson:"_id"`
-		Total   int    `bson:"total"`
-		Correct int    `bson:"correct"`
+		Square        string  `bson:"_id"`
+		Total         int     `bson:"total"`
+		Correct       int     `bson:"correct"`
+		Credit        float64 `bson:"credit"`
+		AvgResponseMs float64 `bson:"avg_response_ms"`
 	}
 	if err := cursor.All(ctx, &results); err != nil {
 		return nil, err
@@ -137,20 +166,30 @@ func (r *AttemptRepository) GetSquareAccuracy(ctx context.Context, userID bson.O
 
 	var accuracies []model.SquareAccuracy
 	for _, r := range results {
+		if !isSquare(r.Square) {
+			continue
+		}
 		accuracy := 0.0
 		if r.Total > 0 {
-			accuracy = float64(r.Correct) / float64(r.Total) * 100
+			accuracy = r.Credit / float64(r.Total) * 100
 		}
 		accuracies = append(accuracies, model.SquareAccuracy{
 			Square:   r.Square,
 			Total:    r.Total,
 			Correct:  r.Correct,
+			Credit:   r.Credit,
 			Accuracy: accuracy,
+
+			AvgResponseMs: int(r.AvgResponseMs),
 		})
 	}
 	return accuracies, nil
 }
 
+func isSquare(s string) bool {
+	return len(s) == 2 && s[0] >= 'a' && s[0] <= 'h' && s[1] >= '1' && s[1] <= '8'
+}
+
 // GetDrillStats returns stats for a specific drill type
 func (r *AttemptRepository) GetDrillStats(ctx context.Context, userID bson.ObjectID, drillType model.DrillType) (*model.DrillStats, error) {
 	pipeline := []bson.M{
@@ -159,6 +198,7 @@ func (r *AttemptRepository) GetDrillStats(ctx context.Context, userID bson.Objec
 			"_id":             nil,
 			"total_attempts":  bson.M{"$sum": 1},
 			"correct":         bson.M{"$sum": bson.M{"$cond": []interface{}{"$correct", 1, 0}}},
+			"credit":          bson.M{"$sum": creditExpr},
 			"avg_response_ms": bson.M{"$avg": "$response_ms"},
 		}},
 	}
@@ -172,6 +212,7 @@ func (r *AttemptRepository) GetDrillStats(ctx context.Context, userID bson.Objec
 	var results []struct {
 		TotalAttempts int     `bson:"total_attempts"`
 		Correct       int     `bson:"correct"`
+		Credit        float64 `bson:"credit"`
 		AvgResponseMs float64 `bson:"avg_response_ms"`
 	}
 	if err := cursor.All(ctx, &results); err != nil {
@@ -184,7 +225,7 @@ func (r *AttemptRepository) GetDrillStats(ctx context.Context, userID bson.Objec
 
 	accuracy := 0.0
 	if results[0].TotalAttempts > 0 {
-		accuracy = float64(results[0].Correct) / float64(results[0].TotalAttempts) * 100
+		accuracy = results[0].Credit / float64(results[0].TotalAttempts) * 100
 	}
 
 	return &model.DrillStats{
@@ -196,6 +237,137 @@ func (r *AttemptRepository) GetDrillStats(ctx context.Context, userID bson.Objec
 	}, nil
 }
 
+// accuracyRow is one group of attempts from groupedAccuracy, with the
+// fields of its group key by name
+type accuracyRow struct {
+	Key           map[string]string `bson:"_id"`
+	TotalAttempts int               `bson:"total_attempts"`
+	Correct       int               `bson:"correct"`
+	Credit        float64           `bson:"credit"`
+	AvgResponseMs float64           `bson:"avg_response_ms"`
+}
+
+// accuracy is the percentage of credit earned
+func (row accuracyRow) accuracy() float64 {
+	if row.TotalAttempts == 0 {
+		return 0
+	}
+	return row.Credit / float64(row.TotalAttempts) * 100
+}
+
+// groupedAccuracy totals the attempts matching match in groups of
+// groupKey, a document of named field paths, sorted by sort. If unwind is
+// set, each element of that array field counts once.
+func (r *AttemptRepository) groupedAccuracy(ctx context.Context, match bson.M, unwind string, groupKey bson.M, sort bson.D) ([]accuracyRow, error) {
+	pipeline := []bson.M{{"$match": match}}
+	if unwind != "" {
+		pipeline = append(pipeline, bson.M{"$unwind": unwind})
+	}
+	pipeline = append(pipeline,
+		bson.M{"$group": bson.M{
+			"_id":             groupKey,
+			"total_attempts":  bson.M{"$sum": 1},
+			"correct":         bson.M{"$sum": bson.M{"$cond": []interface{}{"$correct", 1, 0}}},
+			"credit":          bson.M{"$sum": creditExpr},
+			"avg_response_ms": bson.M{"$avg": "$response_ms"},
+		}},
+		bson.M{"$sort": sort},
+	)
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var rows []accuracyRow
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// GetCategoryStats returns stats for each metadata category of a drill type.
+// Array-valued category fields are unwound so each element counts once.
+func (r *AttemptRepository) GetCategoryStats(ctx context.Context, userID bson.ObjectID, drillType model.DrillType, field string) ([]model.CategoryStats, error) {
+	path := "$metadata." + field
+	rows, err := r.groupedAccuracy(ctx,
+		bson.M{"user_id": userID, "drill_type": drillType, "metadata." + field: bson.M{"$exists": true}},
+		path,
+		bson.M{"category": path},
+		bson.D{{Key: "_id.category", Value: 1}},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var stats []model.CategoryStats
+	for _, row := range rows {
+		stats = append(stats, model.CategoryStats{
+			Category:        row.Key["category"],
+			TotalAttempts:   row.TotalAttempts,
+			CorrectAttempts: row.Correct,
+			Accuracy:        row.accuracy(),
+			AvgResponseMs:   int(row.AvgResponseMs),
+		})
+	}
+	return stats, nil
+}
+
+// GetRegionStats returns stats for each practice region the user has
+// drilled, most practised first
+func (r *AttemptRepository) GetRegionStats(ctx context.Context, userID bson.ObjectID) ([]model.RegionStats, error) {
+	rows, err := r.groupedAccuracy(ctx,
+		bson.M{"user_id": userID, "region": bson.M{"$exists": true}},
+		"",
+		bson.M{"region": "$region"},
+		bson.D{{Key: "total_attempts", Value: -1}, {Key: "_id.region", Value: 1}},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var stats []model.RegionStats
+	for _, row := range rows {
+		stats = append(stats, model.RegionStats{
+			Region:          row.Key["region"],
+			TotalAttempts:   row.TotalAttempts,
+			CorrectAttempts: row.Correct,
+			Accuracy:        row.accuracy(),
+			AvgResponseMs:   int(row.AvgResponseMs),
+		})
+	}
+	return stats, nil
+}
+
+// GetLineStats returns stats for each repertoire line the user has
+// drilled, by perspective and then line
+func (r *AttemptRepository) GetLineStats(ctx context.Context, userID bson.ObjectID) ([]model.LineStats, error) {
+	rows, err := r.groupedAccuracy(ctx,
+		bson.M{"user_id": userID, "drill_type": model.DrillTypeRepertoire, "metadata.line": bson.M{"$exists": true}},
+		"",
+		bson.M{"perspective": "$perspective", "line": "$metadata.line"},
+		bson.D{{Key: "_id.perspective", Value: -1}, {Key: "_id.line", Value: 1}},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var stats []model.LineStats
+	for _, row := range rows {
+		stats = append(stats, model.LineStats{
+			Perspective:     row.Key["perspective"],
+			Line:            row.Key["line"],
+			TotalAttempts:   row.TotalAttempts,
+			CorrectAttempts: row.Correct,
+			Credit:          row.Credit,
+			Accuracy:        row.accuracy(),
+			AvgResponseMs:   int(row.AvgResponseMs),
+		})
+	}
+	return stats, nil
+}
+
 // GetOverallStats returns overall stats for a user
 func (r *AttemptRepository) GetOverallStats(ctx context.Context, userID bson.ObjectID) (*model.OverallStats, error) {
 	pipeline := []bson.M{
@@ -204,6 +376,7 @@ func (r *AttemptRepository) GetOverallStats(ctx context.Context, userID bson.Obj
 			"_id":             nil,
 			"total_attempts":  bson.M{"$sum": 1},
 			"correct":         bson.M{"$sum": bson.M{"$cond": []interface{}{"$correct", 1, 0}}},
+			"credit":          bson.M{"$sum": creditExpr},
 			"avg_response_ms": bson.M{"$avg": "$response_ms"},
 		}},
 	}
@@ -217,6 +390,7 @@ func (r *AttemptRepository) GetOverallStats(ctx context.Context, userID bson.Obj
 	var results []struct {
 		TotalAttempts int     `bson:"total_attempts"`
 		Correct       int     `bson:"correct"`
+		Credit        float64 `bson:"credit"`
 		AvgResponseMs float64 `bson:"avg_response_ms"`
 	}
 	if err := cursor.All(ctx, &results); err != nil {
@@ -227,10 +401,79 @@ func (r *AttemptRepository) GetOverallStats(ctx context.Context, userID bson.Obj
 	if len(results) > 0 {
 		stats.TotalAttempts = results[0].TotalAttempts
 		if stats.TotalAttempts > 0 {
-			stats.OverallAccuracy = float64(results[0].Correct) / float64(stats.TotalAttempts) * 100
+			stats.OverallAccuracy = results[0].Credit / float64(stats.TotalAttempts) * 100
 		}
 		stats.AvgResponseMs = int(results[0].AvgResponseMs)
 	}
 
 	return stats, nil
 }
+
+// GetResponseTimeRows sums the response times of each user's correct
+// answers since a time, per drill type and perspective. Attempts recorded
+// before perspectives were stored count as white; unranked attempts and
+// attempts made while practising a region or a single piece are left out.
+func (r *AttemptRepository) GetResponseTimeRows(ctx context.Context, since time.Time) ([]model.LeaderboardRow, error) {
+	pipeline := []bson.M{
+		{"$match": bson.M{"correct": true, "answered_at": bson.M{"$gte": since}, "region": bson.M{"$exists": false}, "piece": bson.M{"$exists": false}, "unranked": bson.M{"$ne": true}}},
+		{"$group": bson.M{
+			"_id": bson.M{
+				"user_id":     "$user_id",
+				"drill_type":  "$drill_type",
+				"perspective": bson.M{"$ifNull": []interface{}{"$perspective", "white"}},
+			},
+			"value": bson.M{"$sum": "$response_ms"},
+			"count": bson.M{"$sum": 1},
+		}},
+		{"$project": leaderboardRowProjection},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var rows []model.LeaderboardRow
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// TallyAttempts counts each user's attempts at a drill type answered in
+// [from, to), optionally from one perspective
+func (r *AttemptRepository) TallyAttempts(ctx context.Context, userIDs []bson.ObjectID, drillType model.DrillType, perspective string, from, to time.Time) ([]model.AttemptTally, error) {
+	if len(userIDs) == 0 {
+		return nil, nil
+	}
+	match := bson.M{
+		"user_id":     bson.M{"$in": userIDs},
+		"drill_type":  drillType,
+		"answered_at": bson.M{"$gte": from, "$lt": to},
+	}
+	if perspective != "" {
+		match["perspective"] = perspective
+	}
+	pipeline := []bson.M{
+		{"$match": match},
+		{"$group": bson.M{
+			"_id":      "$user_id",
+			"attempts": bson.M{"$sum": 1},
+			"correct":  bson.M{"$sum": bson.M{"$cond": []interface{}{"$correct", 1, 0}}},
+			"credit":   bson.M{"$sum": creditExpr},
+		}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var tallies []model.AttemptTally
+	if err := cursor.All(ctx, &tallies); err != nil {
+		return nil, err
+	}
+	return tallies, nil
+}
@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/abdul-hamid-achik/chessdrill/internal/model"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+var (
+	ErrChallengeEntryNotFound = errors.New("challenge entry not found")
+	ErrChallengeEntryExists   = errors.New("challenge entry already exists")
+)
+
+type ChallengeRepository struct {
+	collection *mongo.Collection
+}
+
+func NewChallengeRepository(db *mongo.Database) *ChallengeRepository {
+	return &ChallengeRepository{
+		collection: db.Collection("challenge_entries"),
+	}
+}
+
+// Create inserts an entry, failing with ErrChallengeEntryExists if the user
+// already has one for the day and drill type
+func (r *ChallengeRepository) Create(ctx context.Context, entry *model.ChallengeEntry) error {
+	result, err := r.collection.InsertOne(ctx, entry)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return ErrChallengeEntryExists
+		}
+		return err
+	}
+	entry.ID = result.InsertedID.(bson.ObjectID)
+	return nil
+}
+
+func (r *ChallengeRepository) SetSession(ctx context.Context, id, sessionID bson.ObjectID) error {
+	result, err := r.collection.UpdateByID(ctx, id, bson.M{"$set": bson.M{"session_id": sessionID}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrChallengeEntryNotFound
+	}
+	return nil
+}
+
+func (r *ChallengeRepository) Delete(ctx context.Context, id bson.ObjectID) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
+
+func (r *ChallengeRepository) FindEntry(ctx context.Context, userID bson.ObjectID, day string, drillType model.DrillType) (*model.ChallengeEntry, error) {
+	var entry model.ChallengeEntry
+	filter := bson.M{"user_id": userID, "day": day, "drill_type": drillType}
+	if err := r.collection.FindOne(ctx, filter).Decode(&entry); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrChallengeEntryNotFound
+		}
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// FindByUserDay returns a user's entries for every drill type of a day
+func (r *ChallengeRepository) FindByUserDay(ctx context.Context, userID bson.ObjectID, day string) ([]model.ChallengeEntry, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"user_id": userID, "day": day})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var entries []model.ChallengeEntry
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// Complete records the result of the entry played in a session. Only the
+// first result counts.
+func (r *ChallengeRepository) Complete(ctx context.Context, sessionID bson.ObjectID, score float64, correct int, duration time.Duration) error {
+	filter := bson.M{"session_id": sessionID, "completed_at": bson.M{"$exists": false}}
+	update := bson.M{
+		"$set": bson.M{
+			"completed_at": time.Now(),
+			"score":        score,
+			"correct":      correct,
+			"duration_ms":  duration.Milliseconds(),
+		},
+	}
+	_, err := r.collection.UpdateOne(ctx, filter, update)
+	return err
+}
+
+// Leaderboard returns the completed entries of a day's challenge, ranked by
+// score and then by the fastest time
+func (r *ChallengeRepository) Leaderboard(ctx context.Context, day string, drillType model.DrillType, limit int) ([]model.ChallengeEntry, error) {
+	filter := bson.M{"day": day, "drill_type": drillType, "completed_at": bson.M{"$exists": true}}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "score", Value: -1}, {Key: "duration_ms", Value: 1}, {Key: "completed_at", Value: 1}}).
+		SetLimit(int64(limit))
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var entries []model.ChallengeEntry
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
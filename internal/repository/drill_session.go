@@ -8,9 +8,24 @@ import (
 	"github.com/abdul-hamid-achik/chessdrill/internal/model"
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
 )
 
-var ErrDrillSessionNotFound = errors.New("drill session not found")
+var (
+	ErrDrillSessionNotFound = errors.New("drill session not found")
+	ErrDrillSessionEnded    = errors.New("drill session already ended")
+)
+
+// leaderboardRowProjection flattens a group keyed by user, drill type and
+// perspective into a model.LeaderboardRow
+var leaderboardRowProjection = bson.M{
+	"_id":         0,
+	"user_id":     "$_id.user_id",
+	"drill_type":  "$_id.drill_type",
+	"perspective": "$_id.perspective",
+	"value":       1,
+	"count":       1,
+}
 
 type DrillSessionRepository struct {
 	collection *mongo.Collection
@@ -43,40 +58,76 @@ func (r *DrillSessionRepository) FindByID(ctx context.Context, id bson.ObjectID)
 	return &session, nil
 }
 
-func (r *DrillSessionRepository) EndSession(ctx context.Context, id bson.ObjectID, summary model.DrillSessionSummary) error {
-	now := time.Now()
+// NextQuestionIndex atomically claims the index of the session's next
+// generated question
+func (r *DrillSessionRepository) NextQuestionIndex(ctx context.Context, id bson.ObjectID) (int, error) {
+	var session model.DrillSession
+	err := r.collection.FindOneAndUpdate(ctx,
+		bson.M{"_id": id},
+		bson.M{"$inc": bson.M{"questions_generated": 1}},
+		options.FindOneAndUpdate().SetReturnDocument(options.Before),
+	).Decode(&session)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return 0, ErrDrillSessionNotFound
+		}
+		return 0, err
+	}
+	return session.QuestionsGenerated, nil
+}
+
+// EndSession stores when a session ended and its summary, unless the
+// session has already ended
+func (r *DrillSessionRepository) EndSession(ctx context.Context, id bson.ObjectID, endedAt time.Time, summary model.DrillSessionSummary) error {
+	filter := bson.M{"_id": id, "ended_at": bson.M{"$exists": false}}
 	update := bson.M{
 		"$set": bson.M{
-			"ended_at": now,
+			"ended_at": endedAt,
 			"summary":  summary,
 		},
 	}
-	result, err := r.collection.UpdateByID(ctx, id, update)
+	result, err := r.collection.UpdateOne(ctx, filter, update)
 	if err != nil {
 		return err
 	}
 	if result.MatchedCount == 0 {
-		return ErrDrillSessionNotFound
+		return ErrDrillSessionEnded
 	}
 	return nil
 }
 
+// FindByUserID returns a user's most recent sessions, newest first
 func (r *DrillSessionRepository) FindByUserID(ctx context.Context, userID bson.ObjectID, limit int) ([]model.DrillSession, error) {
-	opts := &struct {
-		Sort  bson.M
-		Limit int64
-	}{
-		Sort:  bson.M{"started_at": -1},
-		Limit: int64(limit),
-	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "started_at", Value: -1}}).
+		SetLimit(int64(limit))
 
-	cursor, err := r.collection.Find(ctx, bson.M{"user_id": userID})
+	cursor, err := r.collection.Find(ctx, bson.M{"user_id": userID}, opts)
 	if err != nil {
 		return nil, err
 	}
 	defer cursor.Close(ctx)
 
-	_ = opts // We'll use aggregation for sorting/limiting in production
+	var sessions []model.DrillSession
+	if err := cursor.All(ctx, &sessions); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// FindOpenTimed returns the timed sessions started before a time that have
+// not ended
+func (r *DrillSessionRepository) FindOpenTimed(ctx context.Context, startedBefore time.Time) ([]model.DrillSession, error) {
+	filter := bson.M{
+		"mode":       model.SessionModeTimed,
+		"started_at": bson.M{"$lt": startedBefore},
+		"ended_at":   bson.M{"$exists": false},
+	}
+	cursor, err := r.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
 
 	var sessions []model.DrillSession
 	if err := cursor.All(ctx, &sessions); err != nil {
@@ -88,3 +139,52 @@ func (r *DrillSessionRepository) FindByUserID(ctx context.Context, userID bson.O
 func (r *DrillSessionRepository) CountByUserID(ctx context.Context, userID bson.ObjectID) (int64, error) {
 	return r.collection.CountDocuments(ctx, bson.M{"user_id": userID})
 }
+
+// GetBestTimedScoreRows returns each user's best score in ended timed
+// sessions of a time limit started since a time, per drill type and
+// perspective. Unranked sessions and sessions limited to a region or fixed
+// to a piece are left out.
+func (r *DrillSessionRepository) GetBestTimedScoreRows(ctx context.Context, since time.Time, timeLimitSec int) ([]model.LeaderboardRow, error) {
+	match := bson.M{"mode": model.SessionModeTimed, "time_limit_sec": timeLimitSec}
+	return r.bestSummaryRows(ctx, since, match, "$summary.mode_score")
+}
+
+// GetBestStreakRows returns each user's best streak in ended sessions
+// started since a time, per drill type and perspective, leaving out
+// unranked sessions and sessions limited to a region or fixed to a piece
+func (r *DrillSessionRepository) GetBestStreakRows(ctx context.Context, since time.Time) ([]model.LeaderboardRow, error) {
+	return r.bestSummaryRows(ctx, since, bson.M{}, "$summary.streak_best")
+}
+
+func (r *DrillSessionRepository) bestSummaryRows(ctx context.Context, since time.Time, match bson.M, field string) ([]model.LeaderboardRow, error) {
+	match["started_at"] = bson.M{"$gte": since}
+	match["ended_at"] = bson.M{"$ne": nil}
+	match["region"] = bson.M{"$exists": false}
+	match["piece"] = bson.M{"$exists": false}
+	match["unranked"] = bson.M{"$ne": true}
+	pipeline := []bson.M{
+		{"$match": match},
+		{"$group": bson.M{
+			"_id": bson.M{
+				"user_id":     "$user_id",
+				"drill_type":  "$drill_type",
+				"perspective": "$perspective",
+			},
+			"value": bson.M{"$max": field},
+			"count": bson.M{"$sum": 1},
+		}},
+		{"$project": leaderboardRowProjection},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var rows []model.LeaderboardRow
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/abdul-hamid-achik/chessdrill/internal/model"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+var (
+	ErrGroupNotFound  = errors.New("group not found")
+	ErrJoinCodeExists = errors.New("join code already in use")
+	ErrAlreadyInGroup = errors.New("already a member of this group")
+	ErrNotGroupMember = errors.New("not a member of this group")
+)
+
+type GroupRepository struct {
+	collection *mongo.Collection
+}
+
+func NewGroupRepository(db *mongo.Database) *GroupRepository {
+	return &GroupRepository{
+		collection: db.Collection("groups"),
+	}
+}
+
+// Create inserts a group, failing with ErrJoinCodeExists if its join code
+// is taken
+func (r *GroupRepository) Create(ctx context.Context, group *model.Group) error {
+	result, err := r.collection.InsertOne(ctx, group)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return ErrJoinCodeExists
+		}
+		return err
+	}
+	group.ID = result.InsertedID.(bson.ObjectID)
+	return nil
+}
+
+func (r *GroupRepository) FindByID(ctx context.Context, id bson.ObjectID) (*model.Group, error) {
+	return r.findOne(ctx, bson.M{"_id": id})
+}
+
+func (r *GroupRepository) FindByJoinCode(ctx context.Context, code string) (*model.Group, error) {
+	return r.findOne(ctx, bson.M{"join_code": code})
+}
+
+// FindCoachGroup returns a group only if it belongs to the coach
+func (r *GroupRepository) FindCoachGroup(ctx context.Context, coachID, id bson.ObjectID) (*model.Group, error) {
+	return r.findOne(ctx, bson.M{"_id": id, "coach_id": coachID})
+}
+
+func (r *GroupRepository) findOne(ctx context.Context, filter bson.M) (*model.Group, error) {
+	var group model.Group
+	if err := r.collection.FindOne(ctx, filter).Decode(&group); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrGroupNotFound
+		}
+		return nil, err
+	}
+	return &group, nil
+}
+
+// FindByCoach returns a coach's groups, newest first
+func (r *GroupRepository) FindByCoach(ctx context.Context, coachID bson.ObjectID) ([]model.Group, error) {
+	return r.find(ctx, bson.M{"coach_id": coachID})
+}
+
+// FindByMember returns the groups a user has joined, newest first
+func (r *GroupRepository) FindByMember(ctx context.Context, userID bson.ObjectID) ([]model.Group, error) {
+	return r.find(ctx, bson.M{"members.user_id": userID})
+}
+
+func (r *GroupRepository) find(ctx context.Context, filter bson.M) ([]model.Group, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	groups := []model.Group{}
+	if err := cursor.All(ctx, &groups); err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+// AddMember adds a user to a group, failing with ErrAlreadyInGroup if they
+// are already in it
+func (r *GroupRepository) AddMember(ctx context.Context, id bson.ObjectID, member model.GroupMember) error {
+	filter := bson.M{"_id": id, "members.user_id": bson.M{"$ne": member.UserID}}
+	result, err := r.collection.UpdateOne(ctx, filter, bson.M{"$push": bson.M{"members": member}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		if _, err := r.FindByID(ctx, id); err != nil {
+			return err
+		}
+		return ErrAlreadyInGroup
+	}
+	return nil
+}
+
+// RemoveMember takes a user out of a group
+func (r *GroupRepository) RemoveMember(ctx context.Context, id, userID bson.ObjectID) error {
+	filter := bson.M{"_id": id, "members.user_id": userID}
+	result, err := r.collection.UpdateOne(ctx, filter, bson.M{"$pull": bson.M{"members": bson.M{"user_id": userID}}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotGroupMember
+	}
+	return nil
+}
+
+// RemoveMemberFromAll takes a user out of every group they joined
+func (r *GroupRepository) RemoveMemberFromAll(ctx context.Context, userID bson.ObjectID) error {
+	filter := bson.M{"members.user_id": userID}
+	_, err := r.collection.UpdateMany(ctx, filter, bson.M{"$pull": bson.M{"members": bson.M{"user_id": userID}}})
+	return err
+}
+
+// Delete removes a group owned by the coach
+func (r *GroupRepository) Delete(ctx context.Context, coachID, id bson.ObjectID) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id, "coach_id": coachID})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrGroupNotFound
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/abdul-hamid-achik/chessdrill/internal/model"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+var ErrLeaderboardNotFound = errors.New("leaderboard not found")
+
+type LeaderboardRepository struct {
+	collection *mongo.Collection
+}
+
+func NewLeaderboardRepository(db *mongo.Database) *LeaderboardRepository {
+	return &LeaderboardRepository{
+		collection: db.Collection("leaderboards"),
+	}
+}
+
+// Save replaces the stored leaderboard for the same metric, drill type,
+// perspective and period
+func (r *LeaderboardRepository) Save(ctx context.Context, board *model.Leaderboard) error {
+	filter := bson.M{
+		"metric":      board.Metric,
+		"drill_type":  board.DrillType,
+		"perspective": board.Perspective,
+		"period":      board.Period,
+	}
+	_, err := r.collection.ReplaceOne(ctx, filter, board, options.Replace().SetUpsert(true))
+	return err
+}
+
+func (r *LeaderboardRepository) Find(ctx context.Context, metric model.LeaderboardMetric, drillType model.DrillType, perspective string, period model.LeaderboardPeriod) (*model.Leaderboard, error) {
+	var board model.Leaderboard
+	filter := bson.M{
+		"metric":      metric,
+		"drill_type":  drillType,
+		"perspective": perspective,
+		"period":      period,
+	}
+	if err := r.collection.FindOne(ctx, filter).Decode(&board); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrLeaderboardNotFound
+		}
+		return nil, err
+	}
+	return &board, nil
+}
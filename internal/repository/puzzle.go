@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/abdul-hamid-achik/chessdrill/internal/model"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+var ErrPuzzleNotFound = errors.New("puzzle not found")
+
+type PuzzleRepository struct {
+	collection *mongo.Collection
+}
+
+func NewPuzzleRepository(db *mongo.Database) *PuzzleRepository {
+	return &PuzzleRepository{
+		collection: db.Collection("puzzles"),
+	}
+}
+
+// UpsertMany inserts puzzles, replacing any already stored under the same
+// Lichess puzzle ID, and returns the number inserted and updated
+func (r *PuzzleRepository) UpsertMany(ctx context.Context, puzzles []*model.Puzzle) (inserted, updated int64, err error) {
+	if len(puzzles) == 0 {
+		return 0, 0, nil
+	}
+
+	writes := make([]mongo.WriteModel, 0, len(puzzles))
+	for _, p := range puzzles {
+		writes = append(writes, mongo.NewReplaceOneModel().
+			SetFilter(bson.M{"puzzle_id": p.PuzzleID}).
+			SetReplacement(p).
+			SetUpsert(true))
+	}
+
+	result, err := r.collection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+	if err != nil {
+		return 0, 0, err
+	}
+	return result.UpsertedCount, result.ModifiedCount, nil
+}
+
+// FindFrom returns the first puzzle rated within [minRating, maxRating]
+// that comes at or after (rating, puzzleID) in rating then ID order
+func (r *PuzzleRepository) FindFrom(ctx context.Context, minRating, maxRating, rating int, puzzleID string) (*model.Puzzle, error) {
+	filter := bson.M{
+		"rating": bson.M{"$gte": minRating, "$lte": maxRating},
+		"$or": []bson.M{
+			{"rating": rating, "puzzle_id": bson.M{"$gte": puzzleID}},
+			{"rating": bson.M{"$gt": rating}},
+		},
+	}
+	opts := options.FindOne().SetSort(bson.D{{Key: "rating", Value: 1}, {Key: "puzzle_id", Value: 1}})
+
+	var puzzle model.Puzzle
+	if err := r.collection.FindOne(ctx, filter, opts).Decode(&puzzle); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrPuzzleNotFound
+		}
+		return nil, err
+	}
+	return &puzzle, nil
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/abdul-hamid-achik/chessdrill/internal/model"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+var (
+	ErrQuestionNotFound = errors.New("question not found")
+	ErrQuestionAnswered = errors.New("question already answered")
+)
+
+type QuestionRepository struct {
+	collection *mongo.Collection
+}
+
+func NewQuestionRepository(db *mongo.Database) *QuestionRepository {
+	return &QuestionRepository{
+		collection: db.Collection("questions"),
+	}
+}
+
+func (r *QuestionRepository) Create(ctx context.Context, question *model.Question) error {
+	result, err := r.collection.InsertOne(ctx, question)
+	if err != nil {
+		return err
+	}
+	question.ID = result.InsertedID.(bson.ObjectID)
+	return nil
+}
+
+func (r *QuestionRepository) FindByID(ctx context.Context, id bson.ObjectID) (*model.Question, error) {
+	var question model.Question
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&question)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrQuestionNotFound
+		}
+		return nil, err
+	}
+	return &question, nil
+}
+
+// FindPending returns the most recently issued unanswered question of a session
+func (r *QuestionRepository) FindPending(ctx context.Context, sessionID bson.ObjectID) (*model.Question, error) {
+	opts := options.FindOne().SetSort(bson.D{{Key: "issued_at", Value: -1}})
+
+	var question model.Question
+	err := r.collection.FindOne(ctx, bson.M{
+		"session_id":  sessionID,
+		"answered_at": bson.M{"$exists": false},
+	}, opts).Decode(&question)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrQuestionNotFound
+		}
+		return nil, err
+	}
+	return &question, nil
+}
+
+// MarkAnswered atomically flags a question as answered. It fails with
+// ErrQuestionAnswered if the question was already answered, so each question
+// can only be graded once.
+func (r *QuestionRepository) MarkAnswered(ctx context.Context, id bson.ObjectID) error {
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": id, "answered_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"answered_at": time.Now()}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrQuestionAnswered
+	}
+	return nil
+}
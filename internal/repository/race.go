@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/abdul-hamid-achik/chessdrill/internal/model"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+type RaceRepository struct {
+	collection *mongo.Collection
+}
+
+func NewRaceRepository(db *mongo.Database) *RaceRepository {
+	return &RaceRepository{
+		collection: db.Collection("race_results"),
+	}
+}
+
+// Create stores a finished race under the ID its sessions already refer to
+func (r *RaceRepository) Create(ctx context.Context, result *model.RaceResult) error {
+	_, err := r.collection.InsertOne(ctx, result)
+	return err
+}
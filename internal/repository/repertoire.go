@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/abdul-hamid-achik/chessdrill/internal/model"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+var ErrRepertoireNotFound = errors.New("repertoire not found")
+
+type RepertoireRepository struct {
+	collection *mongo.Collection
+}
+
+func NewRepertoireRepository(db *mongo.Database) *RepertoireRepository {
+	return &RepertoireRepository{
+		collection: db.Collection("repertoires"),
+	}
+}
+
+// Save stores a user's repertoire for a color, replacing any earlier one
+func (r *RepertoireRepository) Save(ctx context.Context, repertoire *model.Repertoire) error {
+	filter := bson.M{"user_id": repertoire.UserID, "color": repertoire.Color}
+	opts := options.FindOneAndReplace().SetUpsert(true).SetReturnDocument(options.After)
+	return r.collection.FindOneAndReplace(ctx, filter, repertoire, opts).Decode(repertoire)
+}
+
+func (r *RepertoireRepository) FindByUser(ctx context.Context, userID bson.ObjectID, color string) (*model.Repertoire, error) {
+	var repertoire model.Repertoire
+	if err := r.collection.FindOne(ctx, bson.M{"user_id": userID, "color": color}).Decode(&repertoire); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrRepertoireNotFound
+		}
+		return nil, err
+	}
+	return &repertoire, nil
+}
+
+// FindAllByUser returns a user's repertoires, white first
+func (r *RepertoireRepository) FindAllByUser(ctx context.Context, userID bson.ObjectID) ([]model.Repertoire, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "color", Value: -1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"user_id": userID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var repertoires []model.Repertoire
+	if err := cursor.All(ctx, &repertoires); err != nil {
+		return nil, err
+	}
+	return repertoires, nil
+}
+
+func (r *RepertoireRepository) Delete(ctx context.Context, userID bson.ObjectID, color string) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"user_id": userID, "color": color})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrRepertoireNotFound
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/abdul-hamid-achik/chessdrill/internal/model"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+var ErrReviewItemNotFound = errors.New("review item not found")
+
+type ReviewRepository struct {
+	collection *mongo.Collection
+}
+
+func NewReviewRepository(db *mongo.Database) *ReviewRepository {
+	return &ReviewRepository{
+		collection: db.Collection("review_items"),
+	}
+}
+
+func (r *ReviewRepository) FindByKey(ctx context.Context, userID bson.ObjectID, drillType model.DrillType, key string) (*model.ReviewItem, error) {
+	var item model.ReviewItem
+	filter := bson.M{"user_id": userID, "drill_type": drillType, "key": key}
+	if err := r.collection.FindOne(ctx, filter).Decode(&item); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrReviewItemNotFound
+		}
+		return nil, err
+	}
+	return &item, nil
+}
+
+// Save inserts or replaces the item stored under the same user, drill type
+// and key
+func (r *ReviewRepository) Save(ctx context.Context, item *model.ReviewItem) error {
+	filter := bson.M{"user_id": item.UserID, "drill_type": item.DrillType, "key": item.Key}
+	result, err := r.collection.ReplaceOne(ctx, filter, item, options.Replace().SetUpsert(true))
+	if err != nil {
+		return err
+	}
+	if id, ok := result.UpsertedID.(bson.ObjectID); ok {
+		item.ID = id
+	}
+	return nil
+}
+
+// FindNextDue returns the most overdue item of a drill type due before until
+func (r *ReviewRepository) FindNextDue(ctx context.Context, userID bson.ObjectID, drillType model.DrillType, until time.Time) (*model.ReviewItem, error) {
+	var item model.ReviewItem
+	filter := bson.M{"user_id": userID, "drill_type": drillType, "due_at": bson.M{"$lt": until}}
+	opts := options.FindOne().SetSort(bson.D{{Key: "due_at", Value: 1}})
+	if err := r.collection.FindOne(ctx, filter, opts).Decode(&item); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrReviewItemNotFound
+		}
+		return nil, err
+	}
+	return &item, nil
+}
+
+// CountDue counts the items of a drill type due before until
+func (r *ReviewRepository) CountDue(ctx context.Context, userID bson.ObjectID, drillType model.DrillType, until time.Time) (int64, error) {
+	return r.collection.CountDocuments(ctx, bson.M{"user_id": userID, "drill_type": drillType, "due_at": bson.M{"$lt": until}})
+}
+
+// DueByDrillType counts the items due before until for each drill type
+func (r *ReviewRepository) DueByDrillType(ctx context.Context, userID bson.ObjectID, until time.Time) ([]model.ReviewDue, error) {
+	pipeline := []bson.M{
+		{"$match": bson.M{"user_id": userID, "due_at": bson.M{"$lt": until}}},
+		{"$group": bson.M{"_id": "$drill_type", "count": bson.M{"$sum": 1}}},
+		{"$sort": bson.M{"count": -1}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []model.ReviewDue
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/abdul-hamid-achik/chessdrill/internal/model"
@@ -12,6 +13,8 @@ import (
 
 var ErrUserNotFound = errors.New("user not found")
 var ErrUserExists = errors.New("user already exists")
+var ErrPresetNotFound = errors.New("preset not found")
+var ErrPresetLimit = errors.New("too many presets")
 
 type UserRepository struct {
 	collection *mongo.Collection
@@ -86,3 +89,68 @@ func (r *UserRepository) UpdatePreferences(ctx context.Context, userID bson.Obje
 	}
 	return nil
 }
+
+func (r *UserRepository) SetRole(ctx context.Context, userID bson.ObjectID, role model.UserRole) error {
+	update := bson.M{
+		"$set": bson.M{
+			"role":       role,
+			"updated_at": time.Now(),
+		},
+	}
+	result, err := r.collection.UpdateByID(ctx, userID, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// AddPreset appends a preset to the user's presets unless they already
+// have limit of them
+func (r *UserRepository) AddPreset(ctx context.Context, userID bson.ObjectID, preset model.DrillPreset, limit int) error {
+	filter := bson.M{"_id": userID, fmt.Sprintf("presets.%d", limit-1): bson.M{"$exists": false}}
+	update := bson.M{
+		"$push": bson.M{"presets": preset},
+		"$set":  bson.M{"updated_at": time.Now()},
+	}
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrPresetLimit
+	}
+	return nil
+}
+
+func (r *UserRepository) DeletePreset(ctx context.Context, userID, presetID bson.ObjectID) error {
+	update := bson.M{
+		"$pull": bson.M{"presets": bson.M{"_id": presetID}},
+		"$set":  bson.M{"updated_at": time.Now()},
+	}
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": userID, "presets._id": presetID}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrPresetNotFound
+	}
+	return nil
+}
+
+// FindByIDs returns the users with the given IDs, in no particular order
+func (r *UserRepository) FindByIDs(ctx context.Context, ids []bson.ObjectID) ([]model.User, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var users []model.User
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
@@ -10,13 +10,20 @@ import (
 )
 
 type Server struct {
-	router          *chi.Mux
-	pageHandler     *handler.PageHandler
-	authHandler     *handler.AuthHandler
-	drillHandler    *handler.DrillHandler
-	statsHandler    *handler.StatsHandler
-	settingsHandler *handler.SettingsHandler
-	authMiddleware  *middleware.AuthMiddleware
+	router             *chi.Mux
+	pageHandler        *handler.PageHandler
+	authHandler        *handler.AuthHandler
+	drillHandler       *handler.DrillHandler
+	statsHandler       *handler.StatsHandler
+	settingsHandler    *handler.SettingsHandler
+	challengeHandler   *handler.ChallengeHandler
+	leaderboardHandler *handler.LeaderboardHandler
+	raceHandler        *handler.RaceHandler
+	groupHandler       *handler.GroupHandler
+	assignmentHandler  *handler.AssignmentHandler
+	presetHandler      *handler.PresetHandler
+	repertoireHandler  *handler.RepertoireHandler
+	authMiddleware     *middleware.AuthMiddleware
 }
 
 func New(
@@ -25,16 +32,30 @@ func New(
 	drillHandler *handler.DrillHandler,
 	statsHandler *handler.StatsHandler,
 	settingsHandler *handler.SettingsHandler,
+	challengeHandler *handler.ChallengeHandler,
+	leaderboardHandler *handler.LeaderboardHandler,
+	raceHandler *handler.RaceHandler,
+	groupHandler *handler.GroupHandler,
+	assignmentHandler *handler.AssignmentHandler,
+	presetHandler *handler.PresetHandler,
+	repertoireHandler *handler.RepertoireHandler,
 	authMiddleware *middleware.AuthMiddleware,
 ) *Server {
 	s := &Server{
-		router:          chi.NewRouter(),
-		pageHandler:     pageHandler,
-		authHandler:     authHandler,
-		drillHandler:    drillHandler,
-		statsHandler:    statsHandler,
-		settingsHandler: settingsHandler,
-		authMiddleware:  authMiddleware,
+		router:             chi.NewRouter(),
+		pageHandler:        pageHandler,
+		authHandler:        authHandler,
+		drillHandler:       drillHandler,
+		statsHandler:       statsHandler,
+		settingsHandler:    settingsHandler,
+		challengeHandler:   challengeHandler,
+		leaderboardHandler: leaderboardHandler,
+		raceHandler:        raceHandler,
+		groupHandler:       groupHandler,
+		assignmentHandler:  assignmentHandler,
+		presetHandler:      presetHandler,
+		repertoireHandler:  repertoireHandler,
+		authMiddleware:     authMiddleware,
 	}
 	s.setupRoutes()
 	return s
@@ -67,7 +88,17 @@ func (s *Server) setupRoutes() {
 		r.Get("/dashboard", s.pageHandler.Dashboard)
 		r.Get("/drill", s.pageHandler.DrillSelect)
 		r.Get("/drill/{type}", s.pageHandler.Drill)
+		r.Get("/challenge", s.pageHandler.Challenge)
+		r.Get("/challenge/{type}", s.pageHandler.ChallengeDrill)
+		r.Get("/leaderboards", s.pageHandler.Leaderboards)
+		r.Get("/race", s.pageHandler.Race)
+		r.Get("/race/{code}", s.pageHandler.RaceRoom)
+		r.Get("/groups", s.pageHandler.Groups)
+		r.Get("/groups/{id}", s.pageHandler.GroupDashboard)
+		r.Get("/groups/{id}/students/{studentID}", s.pageHandler.GroupStudent)
+		r.Get("/assignments/{id}", s.pageHandler.AssignmentReport)
 		r.Get("/stats", s.pageHandler.Stats)
+		r.Get("/repertoire", s.pageHandler.Repertoire)
 		r.Get("/settings", s.pageHandler.Settings)
 	})
 
@@ -80,10 +111,49 @@ func (s *Server) setupRoutes() {
 		r.Get("/drill/moves", s.drillHandler.GetLegalMoves)
 		r.Get("/drill/question", s.drillHandler.GetNextQuestion)
 
+		r.Get("/presets", s.presetHandler.ListPresets)
+		r.Post("/presets", s.presetHandler.CreatePreset)
+		r.Delete("/presets/{id}", s.presetHandler.DeletePreset)
+
+		r.Get("/repertoire", s.repertoireHandler.ListRepertoires)
+		r.Get("/repertoire/{color}", s.repertoireHandler.GetRepertoire)
+		r.Post("/repertoire/{color}", s.repertoireHandler.UploadRepertoire)
+		r.Delete("/repertoire/{color}", s.repertoireHandler.DeleteRepertoire)
+
+		r.Get("/challenge", s.challengeHandler.GetToday)
+		r.Post("/challenge/start", s.challengeHandler.StartChallenge)
+		r.Get("/challenge/leaderboard", s.challengeHandler.GetLeaderboard)
+
+		r.Get("/leaderboards", s.leaderboardHandler.GetLeaderboard)
+
+		r.Post("/race", s.raceHandler.CreateRace)
+		r.Get("/race/{code}", s.raceHandler.GetRace)
+		r.Get("/race/{code}/events", s.raceHandler.Events)
+		r.Post("/race/{code}/join", s.raceHandler.JoinRace)
+		r.Post("/race/{code}/leave", s.raceHandler.LeaveRace)
+		r.Post("/race/{code}/start", s.raceHandler.StartRace)
+		r.Post("/race/{code}/answer", s.raceHandler.AnswerRace)
+
+		r.Get("/groups", s.groupHandler.ListGroups)
+		r.Post("/groups", s.groupHandler.CreateGroup)
+		r.Post("/groups/join", s.groupHandler.JoinGroup)
+		r.Post("/groups/leave-all", s.groupHandler.LeaveAllGroups)
+		r.Get("/groups/{id}", s.groupHandler.GetGroup)
+		r.Delete("/groups/{id}", s.groupHandler.DeleteGroup)
+		r.Post("/groups/{id}/leave", s.groupHandler.LeaveGroup)
+		r.Get("/groups/{id}/students/{studentID}", s.groupHandler.GetStudent)
+		r.Get("/groups/{id}/assignments", s.assignmentHandler.ListAssignments)
+		r.Post("/groups/{id}/assignments", s.assignmentHandler.CreateAssignment)
+
+		r.Get("/assignments", s.assignmentHandler.GetPending)
+		r.Get("/assignments/{id}", s.assignmentHandler.GetReport)
+		r.Delete("/assignments/{id}", s.assignmentHandler.DeleteAssignment)
+
 		r.Get("/stats/heatmap", s.statsHandler.GetHeatmap)
 		r.Get("/stats/overall", s.statsHandler.GetOverall)
 
 		r.Patch("/settings", s.settingsHandler.UpdatePreferences)
+		r.Post("/settings/coach", s.settingsHandler.BecomeCoach)
 	})
 }
 
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+
+	"github.com/abdul-hamid-achik/chessdrill/internal/chess"
+	"github.com/abdul-hamid-achik/chessdrill/internal/model"
+)
+
+// DefaultExplorationFloor is the share of adaptive picks that stay uniform
+// when the user has not chosen one
+const DefaultExplorationFloor = 0.25
+
+// Bounds on how much slower or faster than usual a square's answers may
+// make it count
+const (
+	minSlowness = 0.5
+	maxSlowness = 2.0
+)
+
+// SquareWeights is a sampling distribution over the 64 squares. A nil
+// *SquareWeights samples uniformly.
+type SquareWeights struct {
+	cumulative [64]float64
+}
+
+// Pick samples a square
+func (w *SquareWeights) Pick(rng RandomSource) string {
+	if w == nil {
+		return files[rng.IntN(8)] + ranks[rng.IntN(8)]
+	}
+	return chess.Square(pickCumulative(rng, w.cumulative[:])).String()
+}
+
+// weaknessWeights builds a distribution favoring weak and slow squares.
+// floor of the probability mass is spread evenly so known squares still
+// come up.
+func weaknessWeights(stats []model.SquareAccuracy, floor float64) *SquareWeights {
+	weakness := squareWeakness(stats)
+	weights := &SquareWeights{}
+	copy(weights.cumulative[:], cumulativeWeights(weakness[:], floor))
+	return weights
+}
+
+// squareWeakness scores how weak the user is on each square
+func squareWeakness(stats []model.SquareAccuracy) [64]float64 {
+	var records [64]answerRecord
+	for _, st := range stats {
+		if sq, err := chess.ParseSquare(st.Square); err == nil {
+			records[sq] = answerRecord{attempts: st.Total, credit: st.Credit, avgResponseMs: st.AvgResponseMs}
+		}
+	}
+	var weakness [64]float64
+	copy(weakness[:], weaknessScores(records[:]))
+	return weakness
+}
+
+// answerRecord is the answer history of a square, line or other item the
+// user is drilled on. The zero value is an item never answered.
+type answerRecord struct {
+	attempts      int
+	credit        float64
+	avgResponseMs int
+}
+
+// weaknessScores scores how weak the user is on each item. Accuracy is
+// smoothed so unseen items count as half known; an item's weakness is its
+// error rate scaled by how much slower than the user's average it is
+// answered.
+func weaknessScores(records []answerRecord) []float64 {
+	var totalMs, answered float64
+	for _, r := range records {
+		totalMs += float64(r.avgResponseMs) * float64(r.attempts)
+		answered += float64(r.attempts)
+	}
+	avgMs := 0.0
+	if answered > 0 {
+		avgMs = totalMs / answered
+	}
+
+	weakness := make([]float64, len(records))
+	for i, r := range records {
+		w := 1 - (r.credit+1)/(float64(r.attempts)+2)
+		if avgMs > 0 && r.avgResponseMs > 0 {
+			w *= min(max(float64(r.avgResponseMs)/avgMs, minSlowness), maxSlowness)
+		}
+		weakness[i] = w
+	}
+	return weakness
+}
+
+// pickWeighted samples an index in proportion to its weight, with floor of
+// the probability mass spread evenly
+func pickWeighted(rng RandomSource, weights []float64, floor float64) int {
+	return pickCumulative(rng, cumulativeWeights(weights, floor))
+}
+
+// cumulativeWeights builds the cumulative distribution that spreads floor
+// of the probability mass evenly and the rest in proportion to weights.
+// All-zero weights are picked uniformly.
+func cumulativeWeights(weights []float64, floor float64) []float64 {
+	floor = min(max(floor, 0), 1)
+	var sum float64
+	for _, w := range weights {
+		sum += w
+	}
+	if sum == 0 {
+		floor = 1
+	}
+
+	cumulative := make([]float64, len(weights))
+	running := 0.0
+	for i, w := range weights {
+		running += floor / float64(len(weights))
+		if sum > 0 {
+			running += (1 - floor) * w / sum
+		}
+		cumulative[i] = running
+	}
+	return cumulative
+}
+
+// pickCumulative samples an index from a cumulative distribution, which
+// need not total 1
+func pickCumulative(rng RandomSource, cumulative []float64) int {
+	x := float64(rng.IntN(1<<30)) / (1 << 30) * cumulative[len(cumulative)-1]
+	for i, c := range cumulative {
+		if x < c {
+			return i
+		}
+	}
+	return len(cumulative) - 1
+}
+
+// usesSquareWeights reports whether a drill type picks its target square
+// through GenerateQuestion's weights
+func usesSquareWeights(drillType model.DrillType) bool {
+	return recordsSquares(drillType) || drillType == model.DrillTypeKnightDistance || drillType == model.DrillTypeGeometry
+}
+
+// recordsSquares reports whether a drill type's attempts are recorded
+// against their target square, so its weak squares are known
+func recordsSquares(drillType model.DrillType) bool {
+	switch drillType {
+	case model.DrillTypeNameSquare, model.DrillTypeFindSquare, model.DrillTypeSquareColor, model.DrillTypePieceMovement:
+		return true
+	default:
+		return false
+	}
+}
+
+// squareWeights returns the distribution a session picks target squares
+// from: adaptive weights, narrowed to the session's region if it has one.
+// It returns nil when the session samples the whole board uniformly.
+func (s *DrillService) squareWeights(ctx context.Context, session *model.DrillSession) (*SquareWeights, error) {
+	if !usesSquareWeights(session.DrillType) {
+		return nil, nil
+	}
+
+	var weights *SquareWeights
+	if session.Adaptive && recordsSquares(session.DrillType) {
+		stats, err := s.attemptRepo.GetDrillSquareAccuracy(ctx, session.UserID, session.DrillType, session.Perspective)
+		if err != nil {
+			return nil, err
+		}
+		weights = weaknessWeights(stats, session.ExplorationFloor)
+	}
+	if session.Region != nil {
+		weights = weights.Within(session.Region.Squares)
+	}
+	return weights, nil
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/abdul-hamid-achik/chessdrill/internal/chess"
+	"github.com/abdul-hamid-achik/chessdrill/internal/model"
+)
+
+// fractionSource is a RandomSource that always draws the same fraction of
+// its range
+type fractionSource float64
+
+func (f fractionSource) IntN(n int) int {
+	return int(float64(f) * float64(n))
+}
+
+func TestWeaknessScores(t *testing.T) {
+	tests := []struct {
+		name    string
+		records []answerRecord
+		want    []float64
+	}{
+		{
+			name:    "unseen items count as half known",
+			records: []answerRecord{{}, {}},
+			want:    []float64{0.5, 0.5},
+		},
+		{
+			name:    "accuracy is smoothed",
+			records: []answerRecord{{attempts: 8, credit: 8}, {attempts: 8, credit: 0}, {attempts: 2, credit: 1}},
+			want:    []float64{0.1, 0.9, 0.5},
+		},
+		{
+			name:    "partial credit counts partially",
+			records: []answerRecord{{attempts: 6, credit: 4.5}},
+			want:    []float64{1 - 5.5/8},
+		},
+		{
+			name: "slower than average scales up, faster scales down",
+			records: []answerRecord{
+				{attempts: 2, credit: 1, avgResponseMs: 1000},
+				{attempts: 2, credit: 1, avgResponseMs: 3000},
+			},
+			want: []float64{0.25, 0.75},
+		},
+		{
+			name: "slowness is bounded",
+			records: []answerRecord{
+				{attempts: 98, credit: 49, avgResponseMs: 100},
+				{attempts: 2, credit: 1, avgResponseMs: 100000},
+			},
+			want: []float64{0.5 * minSlowness, 0.5 * maxSlowness},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := weaknessScores(tt.records)
+			if len(got) != len(tt.want) {
+				t.Fatalf("weaknessScores = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if math.Abs(got[i]-tt.want[i]) > 1e-9 {
+					t.Fatalf("weaknessScores = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestCumulativeWeights(t *testing.T) {
+	tests := []struct {
+		name    string
+		weights []float64
+		floor   float64
+		want    []float64
+	}{
+		{"proportional without a floor", []float64{1, 3}, 0, []float64{0.25, 1}},
+		{"all uniform at a full floor", []float64{1, 3}, 1, []float64{0.5, 1}},
+		{"a floor mixes in uniform picks", []float64{0, 1}, 0.5, []float64{0.25, 1}},
+		{"floor is clamped", []float64{0, 1}, 2, []float64{0.5, 1}},
+		{"all-zero weights are uniform", []float64{0, 0, 0, 0}, 0, []float64{0.25, 0.5, 0.75, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := cumulativeWeights(tt.weights, tt.floor)
+			for i := range got {
+				if math.Abs(got[i]-tt.want[i]) > 1e-9 {
+					t.Fatalf("cumulativeWeights = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestPickWeighted(t *testing.T) {
+	weights := []float64{0, 2, 0, 6}
+	tests := []struct {
+		draw  fractionSource
+		floor float64
+		want  int
+	}{
+		{0, 0, 1},
+		{0.24, 0, 1},
+		{0.26, 0, 3},
+		{0.99, 0, 3},
+		// With the floor, every item keeps 1/8 of the picks
+		{0.1, 0.5, 0},
+		{0.3, 0.5, 1},
+		{0.45, 0.5, 2},
+		{0.9, 0.5, 3},
+	}
+
+	for _, tt := range tests {
+		if got := pickWeighted(tt.draw, weights, tt.floor); got != tt.want {
+			t.Errorf("pickWeighted(draw %v, floor %v) = %d, want %d", float64(tt.draw), tt.floor, got, tt.want)
+		}
+	}
+}
+
+func TestWeaknessWeightsFavorWeakSquares(t *testing.T) {
+	stats := []model.SquareAccuracy{
+		{Square: "e4", Total: 20, Credit: 2},
+		{Square: "a1", Total: 20, Credit: 20},
+	}
+	weights := weaknessWeights(stats, 0)
+
+	e4, a1, d4 := chess.NewSquare(4, 3), chess.NewSquare(0, 0), chess.NewSquare(3, 3)
+	if !(weights.mass(e4) > weights.mass(d4) && weights.mass(d4) > weights.mass(a1)) {
+		t.Errorf("mass e4 %v, unseen d4 %v, a1 %v: want missed > unseen > known", weights.mass(e4), weights.mass(d4), weights.mass(a1))
+	}
+
+	uniform := weaknessWeights(stats, 1)
+	if math.Abs(uniform.mass(e4)-uniform.mass(a1)) > 1e-9 {
+		t.Errorf("a full exploration floor is not uniform: e4 %v, a1 %v", uniform.mass(e4), uniform.mass(a1))
+	}
+}
+
+func TestWithinRegion(t *testing.T) {
+	region := []string{"d4", "e4", "d5", "e5"}
+	for _, weights := range []*SquareWeights{nil, weaknessWeights([]model.SquareAccuracy{{Square: "h8", Total: 10}}, 0)} {
+		within := weights.Within(region)
+		for i := range 200 {
+			if sq := within.Pick(NewSeededSource(1, i)); !slices.Contains(region, sq) {
+				t.Fatalf("picked %s outside %v", sq, region)
+			}
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/abdul-hamid-achik/chessdrill/internal/model"
+	"github.com/abdul-hamid-achik/chessdrill/internal/repository"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+var (
+	ErrAssignmentNotFound = errors.New("assignment not found")
+	ErrInvalidAssignment  = errors.New("invalid assignment")
+)
+
+const (
+	maxAssignmentTitleLength = 100
+	maxAssignmentAttempts    = 10000
+)
+
+// AssignmentService sets coaches' assignments and evaluates them from the
+// students' attempts whenever they are viewed
+type AssignmentService struct {
+	assignmentRepo *repository.AssignmentRepository
+	groupRepo      *repository.GroupRepository
+	attemptRepo    *repository.AttemptRepository
+}
+
+func NewAssignmentService(assignmentRepo *repository.AssignmentRepository, groupRepo *repository.GroupRepository, attemptRepo *repository.AttemptRepository) *AssignmentService {
+	return &AssignmentService{
+		assignmentRepo: assignmentRepo,
+		groupRepo:      groupRepo,
+		attemptRepo:    attemptRepo,
+	}
+}
+
+// AssignmentOptions describes a new assignment
+type AssignmentOptions struct {
+	Title          string
+	DrillType      model.DrillType
+	Perspective    string
+	TargetAttempts int
+	TargetAccuracy float64
+	DueAt          time.Time
+}
+
+// Create sets an assignment for one of the coach's groups. Attempts count
+// from now until the due date.
+func (s *AssignmentService) Create(ctx context.Context, coach *model.User, groupID bson.ObjectID, opts AssignmentOptions) (*model.Assignment, error) {
+	group, err := s.coachGroup(ctx, coach, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	opts.Title = strings.TrimSpace(opts.Title)
+	if !slices.Contains(drillTypes, opts.DrillType) ||
+		(opts.Perspective != "" && opts.Perspective != "white" && opts.Perspective != "black") ||
+		opts.TargetAttempts < 1 || opts.TargetAttempts > maxAssignmentAttempts ||
+		opts.TargetAccuracy < 0 || opts.TargetAccuracy > 100 ||
+		!opts.DueAt.After(now) ||
+		utf8.RuneCountInString(opts.Title) > maxAssignmentTitleLength {
+		return nil, ErrInvalidAssignment
+	}
+
+	assignment := &model.Assignment{
+		GroupID:        group.ID,
+		CoachID:        coach.ID,
+		Title:          opts.Title,
+		DrillType:      opts.DrillType,
+		Perspective:    opts.Perspective,
+		TargetAttempts: opts.TargetAttempts,
+		TargetAccuracy: opts.TargetAccuracy,
+		CreatedAt:      now,
+		DueAt:          opts.DueAt,
+	}
+	if err := s.assignmentRepo.Create(ctx, assignment); err != nil {
+		return nil, err
+	}
+	return assignment, nil
+}
+
+// ForGroup returns the assignments of one of the coach's groups
+func (s *AssignmentService) ForGroup(ctx context.Context, coach *model.User, groupID bson.ObjectID) ([]model.Assignment, error) {
+	if _, err := s.coachGroup(ctx, coach, groupID); err != nil {
+		return nil, err
+	}
+	return s.assignmentRepo.FindByGroup(ctx, groupID)
+}
+
+// Delete removes an assignment the coach set and returns it
+func (s *AssignmentService) Delete(ctx context.Context, coach *model.User, assignmentID bson.ObjectID) (*model.Assignment, error) {
+	if coach.Role != model.RoleCoach {
+		return nil, ErrNotCoach
+	}
+	assignment, err := s.assignmentRepo.Delete(ctx, coach.ID, assignmentID)
+	if errors.Is(err, repository.ErrAssignmentNotFound) {
+		return nil, ErrAssignmentNotFound
+	}
+	return assignment, err
+}
+
+// Report evaluates an assignment for every current member of its group.
+// The assignment is only visible to the coach of that group.
+func (s *AssignmentService) Report(ctx context.Context, coach *model.User, assignmentID bson.ObjectID) (*model.AssignmentReport, error) {
+	if coach.Role != model.RoleCoach {
+		return nil, ErrNotCoach
+	}
+	assignment, err := s.assignmentRepo.FindByID(ctx, assignmentID)
+	if errors.Is(err, repository.ErrAssignmentNotFound) {
+		return nil, ErrAssignmentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	group, err := s.coachGroup(ctx, coach, assignment.GroupID)
+	if errors.Is(err, ErrGroupNotFound) {
+		return nil, ErrAssignmentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	progress, err := s.evaluate(ctx, assignment, group.Members, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	// Students furthest along first
+	slices.SortStableFunc(progress, func(a, b model.AssignmentProgress) int {
+		return b.Attempts - a.Attempts
+	})
+
+	counts := make(map[model.AssignmentStatus]int)
+	for _, p := range progress {
+		counts[p.Status]++
+	}
+	return &model.AssignmentReport{
+		Assignment: assignment,
+		Group:      group,
+		Students:   progress,
+		Counts:     counts,
+	}, nil
+}
+
+// Pending returns the user's unfinished assignments that are not yet due,
+// soonest first
+func (s *AssignmentService) Pending(ctx context.Context, user *model.User) ([]model.PendingAssignment, error) {
+	groups, err := s.groupRepo.FindByMember(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	groupNames := make(map[bson.ObjectID]string, len(groups))
+	groupIDs := make([]bson.ObjectID, len(groups))
+	for i, group := range groups {
+		groupIDs[i] = group.ID
+		groupNames[group.ID] = group.Name
+	}
+
+	now := time.Now()
+	assignments, err := s.assignmentRepo.FindOpen(ctx, groupIDs, now)
+	if err != nil {
+		return nil, err
+	}
+
+	member := []model.GroupMember{{UserID: user.ID, Username: user.Username}}
+	pending := []model.PendingAssignment{}
+	for _, assignment := range assignments {
+		progress, err := s.evaluate(ctx, &assignment, member, now)
+		if err != nil {
+			return nil, err
+		}
+		if progress[0].Status == model.AssignmentCompleted {
+			continue
+		}
+		pending = append(pending, model.PendingAssignment{
+			Assignment: assignment,
+			GroupName:  groupNames[assignment.GroupID],
+			Progress:   progress[0],
+		})
+	}
+	return pending, nil
+}
+
+// evaluate tallies the members' attempts counting towards an assignment
+func (s *AssignmentService) evaluate(ctx context.Context, assignment *model.Assignment, members []model.GroupMember, now time.Time) ([]model.AssignmentProgress, error) {
+	ids := make([]bson.ObjectID, len(members))
+	for i, member := range members {
+		ids[i] = member.UserID
+	}
+	tallies, err := s.attemptRepo.TallyAttempts(ctx, ids, assignment.DrillType, assignment.Perspective, assignment.CreatedAt, assignment.DueAt)
+	if err != nil {
+		return nil, err
+	}
+	byUser := make(map[bson.ObjectID]model.AttemptTally, len(tallies))
+	for _, tally := range tallies {
+		byUser[tally.UserID] = tally
+	}
+
+	progress := make([]model.AssignmentProgress, len(members))
+	for i, member := range members {
+		tally := byUser[member.UserID]
+		p := model.AssignmentProgress{
+			UserID:   member.UserID,
+			Username: member.Username,
+			Attempts: tally.Attempts,
+			Correct:  tally.Correct,
+		}
+		if tally.Attempts > 0 {
+			p.Accuracy = tally.Credit / float64(tally.Attempts) * 100
+		}
+		p.Status = assignmentStatus(assignment, p, now)
+		progress[i] = p
+	}
+	return progress, nil
+}
+
+// assignmentStatus judges a student's progress. Until the due date, a
+// student is behind when their attempts trail an even pace from the
+// assignment's start, or when they have enough attempts but not the
+// accuracy.
+func assignmentStatus(assignment *model.Assignment, p model.AssignmentProgress, now time.Time) model.AssignmentStatus {
+	if p.Attempts >= assignment.TargetAttempts && p.Accuracy >= assignment.TargetAccuracy {
+		return model.AssignmentCompleted
+	}
+	if !now.Before(assignment.DueAt) {
+		return model.AssignmentMissed
+	}
+	if p.Attempts >= assignment.TargetAttempts {
+		return model.AssignmentBehind
+	}
+
+	elapsed := now.Sub(assignment.CreatedAt).Seconds() / assignment.DueAt.Sub(assignment.CreatedAt).Seconds()
+	if float64(p.Attempts) < elapsed*float64(assignment.TargetAttempts) {
+		return model.AssignmentBehind
+	}
+	return model.AssignmentOnTrack
+}
+
+func (s *AssignmentService) coachGroup(ctx context.Context, coach *model.User, groupID bson.ObjectID) (*model.Group, error) {
+	if coach.Role != model.RoleCoach {
+		return nil, ErrNotCoach
+	}
+	group, err := s.groupRepo.FindCoachGroup(ctx, coach.ID, groupID)
+	if err != nil {
+		return nil, groupError(err)
+	}
+	return group, nil
+}
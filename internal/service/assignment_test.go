@@ -0,0 +1,47 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/abdul-hamid-achik/chessdrill/internal/model"
+)
+
+func TestAssignmentStatus(t *testing.T) {
+	created := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	assignment := &model.Assignment{
+		TargetAttempts: 100,
+		TargetAccuracy: 90,
+		CreatedAt:      created,
+		DueAt:          created.AddDate(0, 0, 10),
+	}
+	halfway := created.AddDate(0, 0, 5)
+	overdue := assignment.DueAt.Add(time.Hour)
+
+	tests := []struct {
+		name     string
+		attempts int
+		accuracy float64
+		now      time.Time
+		want     model.AssignmentStatus
+	}{
+		{"ahead of pace", 60, 95, halfway, model.AssignmentOnTrack},
+		{"exactly on pace", 50, 95, halfway, model.AssignmentOnTrack},
+		{"short of pace", 30, 95, halfway, model.AssignmentBehind},
+		{"nothing yet on the first day", 0, 0, created.Add(time.Hour), model.AssignmentBehind},
+		{"enough attempts but not the accuracy", 120, 85, halfway, model.AssignmentBehind},
+		{"targets met early", 100, 92, halfway, model.AssignmentCompleted},
+		{"targets met, seen after the due date", 100, 90, overdue, model.AssignmentCompleted},
+		{"late", 80, 95, overdue, model.AssignmentMissed},
+		{"due date reached exactly", 99, 95, assignment.DueAt, model.AssignmentMissed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := model.AssignmentProgress{Attempts: tt.attempts, Accuracy: tt.accuracy}
+			if got := assignmentStatus(assignment, p, tt.now); got != tt.want {
+				t.Errorf("status = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
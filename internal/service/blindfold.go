@@ -0,0 +1,216 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/abdul-hamid-achik/chessdrill/internal/chess"
+	"github.com/abdul-hamid-achik/chessdrill/internal/model"
+)
+
+// Blindfold question kinds
+const (
+	BlindfoldLocation = "piece_location"
+	BlindfoldAttacked = "attacked"
+)
+
+// bookLines are common opening sequences in SAN. Blindfold sequences follow
+// one of them, or random legal moves, from the starting position.
+var bookLines = [][]string{
+	strings.Fields("e4 e5 Nf3 Nc6 Bb5 a6 Ba4 Nf6 O-O Be7 Re1 b5 Bb3 d6"),
+	strings.Fields("e4 e5 Nf3 Nc6 Bc4 Bc5 c3 Nf6 d3 d6 O-O O-O Re1 a6"),
+	strings.Fields("e4 c5 Nf3 d6 d4 cxd4 Nxd4 Nf6 Nc3 a6 Be3 e5 Nb3 Be6"),
+	strings.Fields("e4 e6 d4 d5 Nc3 Nf6 Bg5 Be7 e5 Nfd7 Bxe7 Qxe7 f4 O-O"),
+	strings.Fields("e4 c6 d4 d5 Nc3 dxe4 Nxe4 Bf5 Ng3 Bg6 h4 h6 Nf3 Nd7"),
+	strings.Fields("e4 d5 exd5 Qxd5 Nc3 Qa5 d4 Nf6 Nf3 Bf5 Bc4 e6 Bd2 c6"),
+	strings.Fields("d4 d5 c4 e6 Nc3 Nf6 Bg5 Be7 e3 O-O Nf3 h6 Bh4 b6"),
+	strings.Fields("d4 d5 c4 c6 Nf3 Nf6 Nc3 dxc4 a4 Bf5 e3 e6 Bxc4 Bb4"),
+	strings.Fields("d4 Nf6 c4 g6 Nc3 Bg7 e4 d6 Nf3 O-O Be2 e5 O-O Nc6"),
+	strings.Fields("c4 e5 Nc3 Nf6 g3 d5 cxd5 Nxd5 Bg2 Nb6 Nf3 Nc6 O-O Be7"),
+}
+
+// blindfoldLength returns the number of half-moves to play for a difficulty
+func blindfoldLength(rng RandomSource, difficulty model.Difficulty) int {
+	switch difficulty {
+	case model.DifficultyHard:
+		return 12 + rng.IntN(5)
+	case model.DifficultyMedium:
+		return 7 + rng.IntN(3)
+	default:
+		return 3 + rng.IntN(3)
+	}
+}
+
+// formatMoveList numbers a SAN sequence played from the starting position,
+// e.g. "1.e4 e5 2.Nf3"
+func formatMoveList(sans []string) string {
+	var b strings.Builder
+	for i, san := range sans {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		if i%2 == 0 {
+			b.WriteString(strconv.Itoa(i/2+1) + ".")
+		}
+		b.WriteString(san)
+	}
+	return b.String()
+}
+
+// generateBlindfoldQuestion plays a move sequence from the starting position
+// without showing the board, then asks where a piece ended up or whether a
+// piece is attacked. The answer is derived by replaying the moves.
+func (s *DrillService) generateBlindfoldQuestion(rng RandomSource, difficulty model.Difficulty) *model.Question {
+	for {
+		pos, sans, origins := playBlindfoldSequence(rng, blindfoldLength(rng, difficulty))
+		moves := formatMoveList(sans)
+
+		var q *model.Question
+		if rng.IntN(2) == 0 {
+			q = blindfoldLocationQuestion(rng, pos, origins, moves)
+		} else {
+			q = blindfoldAttackedQuestion(rng, pos, moves)
+		}
+		if q == nil {
+			continue
+		}
+
+		q.Type = model.DrillTypeBlindfold
+		q.Metadata["moves"] = moves
+		q.Metadata["sequence_length"] = strconv.Itoa(len(sans))
+		q.AnswerData["fen"] = pos.FEN()
+		return q
+	}
+}
+
+// playBlindfoldSequence plays plies half-moves from the starting position,
+// following a book line half of the time. It returns the final position,
+// the moves in SAN, and for every square the starting square of the piece
+// now standing on it.
+func playBlindfoldSequence(rng RandomSource, plies int) (*chess.Position, []string, [64]chess.Square) {
+	pos, _ := chess.ParseFEN(chess.StartFEN)
+	var origins [64]chess.Square
+	for sq := range origins {
+		origins[sq] = chess.Square(sq)
+	}
+
+	var book []string
+	if rng.IntN(2) == 0 {
+		book = bookLines[rng.IntN(len(bookLines))]
+	}
+
+	var sans []string
+	for len(sans) < plies {
+		var m chess.Move
+		if len(sans) < len(book) {
+			m, _ = pos.ParseSAN(book[len(sans)])
+		} else {
+			legal := pos.LegalMoves()
+			if len(legal) == 0 {
+				break
+			}
+			m = legal[rng.IntN(len(legal))]
+		}
+
+		sans = append(sans, pos.SAN(m))
+		origins = trackOrigins(pos, m, origins)
+		pos = pos.Play(m)
+	}
+	return pos, sans, origins
+}
+
+// trackOrigins moves the origin labels along with a move, including the
+// rook when castling and the captured pawn when taking en passant
+func trackOrigins(pos *chess.Position, m chess.Move, origins [64]chess.Square) [64]chess.Square {
+	next := origins
+	next[m.To] = origins[m.From]
+	next[m.From] = chess.NoSquare
+
+	if pos.IsEnPassant(m) {
+		next[chess.NewSquare(m.To.File(), m.From.Rank())] = chess.NoSquare
+	}
+	if pos.IsCastling(m) {
+		rookFrom, rookTo := chess.NewSquare(7, m.From.Rank()), chess.NewSquare(5, m.From.Rank())
+		if m.To.File() == 2 {
+			rookFrom, rookTo = chess.NewSquare(0, m.From.Rank()), chess.NewSquare(3, m.From.Rank())
+		}
+		next[rookTo] = origins[rookFrom]
+		next[rookFrom] = chess.NoSquare
+	}
+	return next
+}
+
+// blindfoldLocationQuestion asks where a piece that has moved now stands,
+// naming it by its starting square
+func blindfoldLocationQuestion(rng RandomSource, pos *chess.Position, origins [64]chess.Square, moves string) *model.Question {
+	var moved []chess.Square
+	for sq := chess.Square(0); sq < 64; sq++ {
+		if !pos.Piece(sq).IsEmpty() && origins[sq] != sq {
+			moved = append(moved, sq)
+		}
+	}
+	if len(moved) == 0 {
+		return nil
+	}
+
+	sq := moved[rng.IntN(len(moved))]
+	piece := pos.Piece(sq)
+	name := fmt.Sprintf("%s %s that started on %s", piece.Color, piece.Type, origins[sq])
+
+	return &model.Question{
+		Prompt: fmt.Sprintf("%s — which square is the %s on now?", moves, name),
+		Answer: sq.String(),
+		Metadata: map[string]string{
+			"piece_type":  piece.Type.String(),
+			"piece_color": piece.Color.String(),
+			"category":    BlindfoldLocation,
+		},
+		AnswerData: map[string]string{
+			"reason": fmt.Sprintf("The %s is on %s.", name, sq),
+		},
+	}
+}
+
+// blindfoldAttackedQuestion asks whether a piece is attacked by the other
+// side, picking attacked and safe pieces equally often
+func blindfoldAttackedQuestion(rng RandomSource, pos *chess.Position, moves string) *model.Question {
+	wantAttacked := rng.IntN(2) == 0
+	var candidates []chess.Square
+	for sq := chess.Square(0); sq < 64; sq++ {
+		piece := pos.Piece(sq)
+		if piece.IsEmpty() || piece.Type == chess.King {
+			continue
+		}
+		if pos.IsAttacked(sq, piece.Color.Other()) == wantAttacked {
+			candidates = append(candidates, sq)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	sq := candidates[rng.IntN(len(candidates))]
+	piece := pos.Piece(sq)
+	answer := "no"
+	reason := fmt.Sprintf("No %s piece attacks %s.", piece.Color.Other(), sq)
+	if attackers := pos.Attackers(sq, piece.Color.Other()); len(attackers) > 0 {
+		answer = "yes"
+		by := pos.Piece(attackers[0])
+		reason = fmt.Sprintf("The %s %s on %s attacks it.", by.Color, by.Type, attackers[0])
+	}
+
+	return &model.Question{
+		Prompt: fmt.Sprintf("%s — is the %s %s on %s attacked?", moves, piece.Color, piece.Type, sq),
+		Answer: answer,
+		Metadata: map[string]string{
+			"piece_type":  piece.Type.String(),
+			"piece_color": piece.Color.String(),
+			"from_square": sq.String(),
+			"category":    BlindfoldAttacked,
+		},
+		AnswerData: map[string]string{
+			"reason": reason,
+		},
+	}
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/abdul-hamid-achik/chessdrill/internal/chess"
+	"github.com/abdul-hamid-achik/chessdrill/internal/model"
+)
+
+// Castling drill categories: either castling is legal, or the rule that
+// forbids it
+const (
+	CastlingLegal        = "legal"
+	CastlingNoRights     = "no_rights"
+	CastlingRookMissing  = "rook_missing"
+	CastlingBlocked      = "blocked"
+	CastlingInCheck      = "in_check"
+	CastlingThroughCheck = "through_check"
+	CastlingIntoCheck    = "into_check"
+)
+
+var castlingScenarios = []string{
+	CastlingLegal, CastlingLegal, CastlingNoRights, CastlingBlocked,
+	CastlingInCheck, CastlingThroughCheck, CastlingIntoCheck,
+}
+
+// castlingAttackers are the pieces used to attack the king's path
+var castlingAttackers = []chess.PieceType{chess.Knight, chess.Bishop, chess.Rook, chess.Queen, chess.Pawn}
+
+// castlingCategory maps a chess.CanCastle error to its drill category
+func castlingCategory(err error) string {
+	switch {
+	case err == nil:
+		return CastlingLegal
+	case errors.Is(err, chess.ErrNoCastlingRights):
+		return CastlingNoRights
+	case errors.Is(err, chess.ErrCastlingRookMissing):
+		return CastlingRookMissing
+	case errors.Is(err, chess.ErrCastlingBlocked):
+		return CastlingBlocked
+	case errors.Is(err, chess.ErrCastlingInCheck):
+		return CastlingInCheck
+	case errors.Is(err, chess.ErrCastlingThroughCheck):
+		return CastlingThroughCheck
+	default:
+		return CastlingIntoCheck
+	}
+}
+
+// generateCastlingQuestion builds a position around one side's castling
+// options and asks whether castling on a given side is legal. Castling
+// rights cannot be seen on the board, so the prompt states the move history.
+func (s *DrillService) generateCastlingQuestion(rng RandomSource) *model.Question {
+	scenario := castlingScenarios[rng.IntN(len(castlingScenarios))]
+	color := chess.Color(rng.IntN(2))
+	kingside := rng.IntN(2) == 0
+	right := chess.CastlingRight(color, kingside)
+
+	home := relativeRank(color, 0)
+	king := chess.NewSquare(4, home)
+	rookFile, crossing, landing, side, notation := 7, 5, 6, "kingside", "O-O"
+	if !kingside {
+		rookFile, crossing, landing, side, notation = 0, 3, 2, "queenside", "O-O-O"
+	}
+	rook := chess.NewSquare(rookFile, home)
+
+	for {
+		pos := castlingBasePosition(rng, color)
+		history := fmt.Sprintf("Neither the %s king nor the %s rook has moved yet.", color, rook)
+
+		ok := true
+		switch scenario {
+		case CastlingLegal:
+			// Attacks on the rook, or on b1/b8, do not prevent castling
+			if rng.IntN(2) == 0 {
+				decoy := rook
+				if !kingside && rng.IntN(2) == 0 {
+					decoy = chess.NewSquare(1, home)
+				}
+				ok = placeCastlingAttacker(rng, pos, decoy, color.Other())
+			}
+		case CastlingNoRights:
+			if rng.IntN(2) == 0 {
+				pos.Castling &^= chess.CastlingRight(color, true) | chess.CastlingRight(color, false)
+				history = fmt.Sprintf("The %s king moved earlier in the game and has returned to %s.", color, king)
+			} else {
+				pos.Castling &^= right
+				history = fmt.Sprintf("The %s rook moved earlier in the game and has returned.", rook)
+			}
+		case CastlingBlocked:
+			between := []int{5, 6}
+			if !kingside {
+				between = []int{1, 2, 3}
+			}
+			sq := chess.NewSquare(between[rng.IntN(len(between))], home)
+			pos.SetPiece(sq, chess.Piece{Type: capturablePieces[rng.IntN(len(capturablePieces))], Color: color})
+		case CastlingInCheck:
+			ok = placeCastlingAttacker(rng, pos, king, color.Other())
+		case CastlingThroughCheck:
+			ok = placeCastlingAttacker(rng, pos, chess.NewSquare(crossing, home), color.Other())
+		case CastlingIntoCheck:
+			ok = placeCastlingAttacker(rng, pos, chess.NewSquare(landing, home), color.Other())
+		}
+		if !ok || pos.Validate() != nil {
+			continue
+		}
+
+		err := pos.CanCastle(right)
+		answer, reason := "yes", "All castling conditions are met."
+		if err != nil {
+			answer = "no"
+			reason = capitalize(err.Error()) + "."
+		}
+
+		return &model.Question{
+			Type:   model.DrillTypeCastling,
+			Target: notation,
+			Prompt: fmt.Sprintf("Can %s castle %s? %s", capitalize(color.String()), side, history),
+			FEN:    pos.FEN(),
+			Answer: answer,
+			Metadata: map[string]string{
+				"piece_type":    "king",
+				"piece_color":   color.String(),
+				"from_square":   king.String(),
+				"castling_side": side,
+			},
+			// The category reveals the answer, so it stays server-side
+			AnswerData: map[string]string{
+				"category": castlingCategory(err),
+				"reason":   reason,
+			},
+		}
+	}
+}
+
+// castlingBasePosition places the king and both rooks of color on their home
+// squares with full castling rights, a few of their pawns, and the enemy king
+// far away on its own half of the board
+func castlingBasePosition(rng RandomSource, color chess.Color) *chess.Position {
+	pos := chess.NewPosition()
+	pos.Turn = color
+	home := relativeRank(color, 0)
+
+	pos.SetPiece(chess.NewSquare(4, home), chess.Piece{Type: chess.King, Color: color})
+	pos.SetPiece(chess.NewSquare(0, home), chess.Piece{Type: chess.Rook, Color: color})
+	pos.SetPiece(chess.NewSquare(7, home), chess.Piece{Type: chess.Rook, Color: color})
+	pos.Castling = chess.CastlingRight(color, true) | chess.CastlingRight(color, false)
+
+	for file := 0; file < 8; file++ {
+		if rng.IntN(100) < 40 {
+			pos.SetPiece(chess.NewSquare(file, relativeRank(color, 1)), chess.Piece{Type: chess.Pawn, Color: color})
+		}
+	}
+
+	enemyKing := chess.NewSquare(rng.IntN(8), relativeRank(color, 5+rng.IntN(3)))
+	pos.SetPiece(enemyKing, chess.Piece{Type: chess.King, Color: color.Other()})
+	return pos
+}
+
+// placeCastlingAttacker puts a piece of color by on an empty square from
+// which it attacks target. It reports false if no such square was found.
+func placeCastlingAttacker(rng RandomSource, pos *chess.Position, target chess.Square, by chess.Color) bool {
+	for tries := 0; tries < 200; tries++ {
+		piece := chess.Piece{Type: castlingAttackers[rng.IntN(len(castlingAttackers))], Color: by}
+		sq := chess.Square(rng.IntN(64))
+		if !pos.Piece(sq).IsEmpty() || (piece.Type == chess.Pawn && (sq.Rank() == 0 || sq.Rank() == 7)) {
+			continue
+		}
+		pos.SetPiece(sq, piece)
+		for _, attacker := range pos.Attackers(target, by) {
+			if attacker == sq {
+				return true
+			}
+		}
+		pos.RemovePiece(sq)
+	}
+	return false
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/abdul-hamid-achik/chessdrill/internal/model"
+	"github.com/abdul-hamid-achik/chessdrill/internal/repository"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+var (
+	ErrChallengeTaken   = errors.New("today's challenge has already been attempted")
+	ErrUnknownDrillType = errors.New("unknown drill type")
+)
+
+// Every daily challenge is a fixed-length session at medium difficulty
+const (
+	ChallengeQuestionCount   = 10
+	challengeDifficulty      = model.DifficultyMedium
+	challengeLeaderboardSize = 50
+)
+
+// ChallengeDrillTypes returns the drill types with a daily challenge. A
+// repertoire is the user's own, so its questions can't be shared.
+func ChallengeDrillTypes() []model.DrillType {
+	return slices.DeleteFunc(DrillTypes(), func(dt model.DrillType) bool {
+		return dt == model.DrillTypeRepertoire
+	})
+}
+
+type ChallengeService struct {
+	challengeRepo *repository.ChallengeRepository
+	userRepo      *repository.UserRepository
+	drillService  *DrillService
+	secret        []byte
+}
+
+func NewChallengeService(challengeRepo *repository.ChallengeRepository, userRepo *repository.UserRepository, drillService *DrillService, secret string) *ChallengeService {
+	return &ChallengeService{
+		challengeRepo: challengeRepo,
+		userRepo:      userRepo,
+		drillService:  drillService,
+		secret:        []byte(secret),
+	}
+}
+
+// ChallengeDay returns the UTC day a challenge played at t belongs to, as
+// YYYY-MM-DD
+func ChallengeDay(t time.Time) string {
+	return t.UTC().Format(time.DateOnly)
+}
+
+// seed derives the question stream of a day's challenge for a drill type.
+// It is keyed with the server secret so the set can't be practiced ahead
+// of time by replaying the seed.
+func (s *ChallengeService) seed(day string, drillType model.DrillType) int64 {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(day + "/" + string(drillType)))
+	sum := mac.Sum(nil)
+	return int64(1 + binary.BigEndian.Uint64(sum[:8])%(maxSeed-1))
+}
+
+// Start begins the user's ranked attempt at today's challenge for a drill
+// type. An attempt that is still in progress is resumed at its current
+// question; a finished one can't be retaken.
+func (s *ChallengeService) Start(ctx context.Context, user *model.User, drillType model.DrillType, perspective string) (*model.DrillSession, *model.Question, error) {
+	if !slices.Contains(ChallengeDrillTypes(), drillType) {
+		return nil, nil, ErrUnknownDrillType
+	}
+	day := ChallengeDay(time.Now())
+
+	entry, err := s.challengeRepo.FindEntry(ctx, user.ID, day, drillType)
+	if err == nil {
+		return s.resume(ctx, user.ID, entry)
+	}
+	if !errors.Is(err, repository.ErrChallengeEntryNotFound) {
+		return nil, nil, err
+	}
+
+	// The entry is created first so concurrent starts can't both go through
+	entry = &model.ChallengeEntry{
+		Day:       day,
+		DrillType: drillType,
+		UserID:    user.ID,
+		Username:  user.Username,
+		StartedAt: time.Now(),
+	}
+	if err := s.challengeRepo.Create(ctx, entry); err != nil {
+		if errors.Is(err, repository.ErrChallengeEntryExists) {
+			return nil, nil, ErrChallengeTaken
+		}
+		return nil, nil, err
+	}
+
+	session, question, err := s.drillService.StartSession(ctx, user.ID, SessionOptions{
+		DrillType:   drillType,
+		InputMethod: model.InputMethodType,
+		Perspective: perspective,
+		Difficulty:  challengeDifficulty,
+		Mode: model.ModeSettings{
+			Mode:          model.SessionModeCount,
+			QuestionCount: ChallengeQuestionCount,
+		},
+		Seed:         s.seed(day, drillType),
+		ChallengeDay: day,
+	})
+	if err != nil {
+		// Give the attempt back if it never started
+		_ = s.challengeRepo.Delete(ctx, entry.ID)
+		return nil, nil, err
+	}
+	if err := s.challengeRepo.SetSession(ctx, entry.ID, session.ID); err != nil {
+		return nil, nil, err
+	}
+	return session, question, nil
+}
+
+// resume returns the current question of an attempt still in progress
+func (s *ChallengeService) resume(ctx context.Context, userID bson.ObjectID, entry *model.ChallengeEntry) (*model.DrillSession, *model.Question, error) {
+	if entry.CompletedAt != nil || entry.SessionID.IsZero() {
+		return nil, nil, ErrChallengeTaken
+	}
+	session, err := s.drillService.findUserSession(ctx, entry.SessionID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	question, err := s.drillService.GetNextQuestion(ctx, session.ID, userID)
+	if err != nil {
+		if errors.Is(err, ErrSessionEnded) {
+			return nil, nil, ErrChallengeTaken
+		}
+		return nil, nil, err
+	}
+	return session, question, nil
+}
+
+// Today returns today's challenge day and the user's entries for it by
+// drill type
+func (s *ChallengeService) Today(ctx context.Context, userID bson.ObjectID) (string, map[model.DrillType]model.ChallengeEntry, error) {
+	day := ChallengeDay(time.Now())
+	entries, err := s.challengeRepo.FindByUserDay(ctx, userID, day)
+	if err != nil {
+		return day, nil, err
+	}
+
+	byType := make(map[model.DrillType]model.ChallengeEntry, len(entries))
+	for _, entry := range entries {
+		byType[entry.DrillType] = entry
+	}
+	return day, byType, nil
+}
+
+// Leaderboard ranks the finished attempts at a day's challenge by score,
+// breaking ties by time. Users hidden from leaderboards are left out.
+func (s *ChallengeService) Leaderboard(ctx context.Context, day string, drillType model.DrillType) ([]model.ChallengeEntry, error) {
+	if !slices.Contains(ChallengeDrillTypes(), drillType) {
+		return nil, ErrUnknownDrillType
+	}
+	entries, err := s.challengeRepo.Leaderboard(ctx, day, drillType, challengeLeaderboardSize)
+	if err != nil || len(entries) == 0 {
+		return entries, err
+	}
+
+	ids := make([]bson.ObjectID, len(entries))
+	for i, entry := range entries {
+		ids[i] = entry.UserID
+	}
+	users, err := s.userRepo.FindByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	hidden := make(map[bson.ObjectID]bool)
+	for _, user := range users {
+		hidden[user.ID] = user.Preferences.HideFromLeaderboards
+	}
+	return slices.DeleteFunc(entries, func(entry model.ChallengeEntry) bool {
+		return hidden[entry.UserID]
+	}), nil
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/abdul-hamid-achik/chessdrill/internal/chess"
+	"github.com/abdul-hamid-achik/chessdrill/internal/model"
+)
+
+// Position classifications for the check recognition drill
+const (
+	StatusNormal    = "normal"
+	StatusCheck     = "check"
+	StatusCheckmate = "checkmate"
+	StatusStalemate = "stalemate"
+)
+
+var positionStatuses = []string{StatusNormal, StatusCheck, StatusCheckmate, StatusStalemate}
+
+// classifyPosition returns the status of the side to move
+func classifyPosition(pos *chess.Position) string {
+	switch {
+	case pos.IsCheckmate():
+		return StatusCheckmate
+	case pos.IsStalemate():
+		return StatusStalemate
+	case pos.InCheck():
+		return StatusCheck
+	default:
+		return StatusNormal
+	}
+}
+
+// generateCheckRecognitionQuestion picks a classification uniformly and
+// samples random positions until one matches, so mates and stalemates come
+// up as often as ordinary positions. The answer for a check is "check:"
+// followed by the checking squares.
+func (s *DrillService) generateCheckRecognitionQuestion(rng RandomSource) *model.Question {
+	status := positionStatuses[rng.IntN(len(positionStatuses))]
+
+	// Mates are more common with more material, stalemates with less
+	extraPieces := 4 + rng.IntN(4)
+	switch status {
+	case StatusCheckmate:
+		extraPieces = 6
+	case StatusStalemate:
+		extraPieces = 3
+	}
+
+	var pos *chess.Position
+	for {
+		pos = randomBoard(rng, extraPieces)
+		if classifyPosition(pos) == status {
+			break
+		}
+	}
+
+	answer := status
+	var checkers []string
+	for _, sq := range pos.Checkers() {
+		checkers = append(checkers, sq.String())
+	}
+	sort.Strings(checkers)
+	if status == StatusCheck {
+		answer = StatusCheck + ":" + strings.Join(checkers, ",")
+	}
+
+	return &model.Question{
+		Type:   model.DrillTypeCheckRecognition,
+		Target: pos.Turn.String(),
+		Prompt: fmt.Sprintf("%s to move. Is this check, checkmate, stalemate, or neither?", capitalize(pos.Turn.String())),
+		FEN:    pos.FEN(),
+		Answer: answer,
+		Metadata: map[string]string{
+			"piece_type":  "king",
+			"piece_color": pos.Turn.String(),
+			"from_square": pos.KingSquare(pos.Turn).String(),
+		},
+		// The category reveals the answer, so it stays server-side
+		AnswerData: map[string]string{
+			"category": status,
+			"checkers": strings.Join(checkers, ","),
+		},
+	}
+}
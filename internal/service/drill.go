@@ -2,11 +2,14 @@ package service
 
 import (
 	"context"
-	"crypto/rand"
+	"errors"
 	"fmt"
-	"math/big"
+	"slices"
+	"sort"
 	"strings"
+	"time"
 
+	"github.com/abdul-hamid-achik/chessdrill/internal/chess"
 	"github.com/abdul-hamid-achik/chessdrill/internal/model"
 	"github.com/abdul-hamid-achik/chessdrill/internal/repository"
 	"go.mongodb.org/mongo-driver/v2/bson"
@@ -15,120 +18,579 @@ import (
 var (
 	files = []string{"a", "b", "c", "d", "e", "f", "g", "h"}
 	ranks = []string{"1", "2", "3", "4", "5", "6", "7", "8"}
+
+	// movementPieces are the pieces piece movement drills ask about
+	movementPieces = []string{"knight", "bishop", "rook", "queen", "king"}
+)
+
+var (
+	ErrNoPieceOnSquare  = errors.New("no piece on square")
+	ErrQuestionNotFound = errors.New("question not found")
+	ErrQuestionAnswered = errors.New("question already answered")
+	ErrSessionNotFound  = errors.New("drill session not found")
+	ErrSessionEnded     = errors.New("drill session has ended")
+	ErrInvalidPiece     = errors.New("invalid piece")
 )
 
 type DrillService struct {
 	drillSessionRepo *repository.DrillSessionRepository
 	attemptRepo      *repository.AttemptRepository
+	questionRepo     *repository.QuestionRepository
+	puzzleRepo       *repository.PuzzleRepository
+	repertoireRepo   *repository.RepertoireRepository
+	challengeRepo    *repository.ChallengeRepository
+	reviewService    *ReviewService
 }
 
-func NewDrillService(drillSessionRepo *repository.DrillSessionRepository, attemptRepo *repository.AttemptRepository) *DrillService {
+func NewDrillService(drillSessionRepo *repository.DrillSessionRepository, attemptRepo *repository.AttemptRepository, questionRepo *repository.QuestionRepository, puzzleRepo *repository.PuzzleRepository, repertoireRepo *repository.RepertoireRepository, challengeRepo *repository.ChallengeRepository, reviewService *ReviewService) *DrillService {
 	return &DrillService{
 		drillSessionRepo: drillSessionRepo,
 		attemptRepo:      attemptRepo,
+		questionRepo:     questionRepo,
+		puzzleRepo:       puzzleRepo,
+		repertoireRepo:   repertoireRepo,
+		challengeRepo:    challengeRepo,
+		reviewService:    reviewService,
 	}
 }
 
-func (s *DrillService) StartSession(ctx context.Context, userID bson.ObjectID, drillType model.DrillType, inputMethod model.InputMethod, perspective string) (*model.DrillSession, *model.Question, error) {
-	session := model.NewDrillSession(userID, drillType, inputMethod, perspective)
+// SessionOptions are the settings a drill session is started with
+type SessionOptions struct {
+	DrillType   model.DrillType
+	InputMethod model.InputMethod
+	Perspective string
+	Difficulty  model.Difficulty
+	Mode        model.ModeSettings
+
+	// Adaptive weights square selection by the user's weakness, keeping
+	// ExplorationFloor of the picks uniform (DefaultExplorationFloor if 0)
+	Adaptive         bool
+	ExplorationFloor float64
+
+	// Region limits the target squares; only its Kind and Values are read
+	Region *model.SquareRegion
+
+	// Piece fixes the piece of piece movement drills; empty varies it
+	Piece string
+
+	// Seed replays an earlier session's question stream; 0 picks a new one
+	Seed int64
+
+	// ChallengeDay marks the session as the ranked attempt at that day's
+	// challenge
+	ChallengeDay string
+}
+
+func (s *DrillService) StartSession(ctx context.Context, userID bson.ObjectID, opts SessionOptions) (*model.DrillSession, *model.Question, error) {
+	mode, err := NormalizeMode(opts.Mode)
+	if err != nil {
+		return nil, nil, err
+	}
+	if mode.Mode == model.SessionModeReview {
+		due, err := s.reviewService.CountDue(ctx, userID, opts.DrillType)
+		if err != nil {
+			return nil, nil, err
+		}
+		if due == 0 {
+			return nil, nil, ErrNothingDue
+		}
+	}
+
+	// A repertoire drill needs the user's repertoire for the side played
+	if opts.DrillType == model.DrillTypeRepertoire {
+		if _, err := s.repertoireRepo.FindByUser(ctx, userID, opts.Perspective); err != nil {
+			if errors.Is(err, repository.ErrRepertoireNotFound) {
+				return nil, nil, ErrRepertoireNotFound
+			}
+			return nil, nil, err
+		}
+	}
+
+	if opts.Piece != "" && !pieceAllowed(opts.DrillType, opts.Piece) {
+		return nil, nil, ErrInvalidPiece
+	}
+
+	session := model.NewDrillSession(userID, opts.DrillType, opts.InputMethod, opts.Perspective, opts.Difficulty, mode)
+	session.Piece = opts.Piece
+	if opts.Region != nil {
+		if session.Region, err = s.resolveRegion(ctx, userID, opts.DrillType, opts.Perspective, mode.Mode, opts.Region); err != nil {
+			return nil, nil, err
+		}
+	}
+	session.Seed = opts.Seed
+	session.ChallengeDay = opts.ChallengeDay
+	session.Unranked = !rankedSession(opts)
+	if session.Seed <= 0 || session.Seed >= maxSeed {
+		session.Seed = newSeed()
+	}
+	if opts.Adaptive {
+		session.Adaptive = true
+		session.ExplorationFloor = opts.ExplorationFloor
+		if session.ExplorationFloor <= 0 || session.ExplorationFloor > 1 {
+			session.ExplorationFloor = DefaultExplorationFloor
+		}
+	}
 	if err := s.drillSessionRepo.Create(ctx, session); err != nil {
 		return nil, nil, err
 	}
 
-	question := s.GenerateQuestion(drillType, "")
+	question, err := s.issueQuestion(ctx, session)
+	if err != nil {
+		return nil, nil, err
+	}
 	return session, question, nil
 }
 
-func (s *DrillService) CheckAnswer(ctx context.Context, sessionID, userID bson.ObjectID, drillType model.DrillType, question, correctAnswer, userAnswer string, responseMs int) (bool, *model.Question, error) {
-	correctAnswer = strings.ToLower(strings.TrimSpace(correctAnswer))
-	userAnswer = strings.ToLower(strings.TrimSpace(userAnswer))
+// issueQuestion generates the next question for a session and stores it so
+// the answer can later be graded without trusting the client. Generated
+// questions draw from the session's seeded stream, so a seed replays the
+// same questions unless adaptive selection reshapes them.
+func (s *DrillService) issueQuestion(ctx context.Context, session *model.DrillSession) (*model.Question, error) {
+	// Review sessions serve due items instead of new questions
+	if session.Mode == model.SessionModeReview {
+		question, err := s.reviewService.NextDue(ctx, session.UserID, session.DrillType)
+		if err != nil {
+			return nil, err
+		}
+		return s.storeQuestion(ctx, session, question)
+	}
 
-	attempt := model.NewAttempt(sessionID, userID, drillType, question, correctAnswer, userAnswer, responseMs)
+	index, err := s.drillSessionRepo.NextQuestionIndex(ctx, session.ID)
+	if err != nil {
+		return nil, err
+	}
+	rng := NewSeededSource(session.Seed, index)
+
+	// Puzzles and repertoire lines come from the database rather than a
+	// generator
+	switch session.DrillType {
+	case model.DrillTypeTactics:
+		question, err := s.generateTacticsQuestion(ctx, rng, session.Difficulty)
+		if err != nil {
+			return nil, err
+		}
+		return s.storeQuestion(ctx, session, question)
+	case model.DrillTypeRepertoire:
+		question, err := s.generateRepertoireQuestion(ctx, rng, session)
+		if err != nil {
+			return nil, err
+		}
+		return s.storeQuestion(ctx, session, question)
+	}
+
+	weights, err := s.squareWeights(ctx, session)
+	if err != nil {
+		return nil, err
+	}
+	// Piece movement sessions fixed to a piece skip the random choice
+	if session.Piece != "" {
+		question := s.generatePieceMovementQuestion(rng, session.Piece, weights.Pick(rng), session.Difficulty)
+		return s.storeQuestion(ctx, session, question)
+	}
+	return s.storeQuestion(ctx, session, s.GenerateQuestion(rng, session.DrillType, session.Difficulty, weights))
+}
+
+// storeQuestion ties a generated question to its session and stores it
+func (s *DrillService) storeQuestion(ctx context.Context, session *model.DrillSession, question *model.Question) (*model.Question, error) {
+	question.SessionID = session.ID
+	question.UserID = session.UserID
+	question.IssuedAt = time.Now()
+
+	if err := s.questionRepo.Create(ctx, question); err != nil {
+		return nil, err
+	}
+	return question, nil
+}
+
+// CheckAnswer grades the answer to a previously issued question and issues
+// the next one. The expected answer is always read from the stored question.
+// When the answer completes the session's mode, or arrives after a timed
+// session ran out, the session is ended and the result carries its summary
+// instead of a next question.
+func (s *DrillService) CheckAnswer(ctx context.Context, userID, questionID bson.ObjectID, userAnswer string, responseMs int) (*model.AnswerResult, *model.Question, error) {
+	question, err := s.questionRepo.FindByID(ctx, questionID)
+	if err != nil {
+		if errors.Is(err, repository.ErrQuestionNotFound) {
+			return nil, nil, ErrQuestionNotFound
+		}
+		return nil, nil, err
+	}
+	if question.UserID != userID {
+		return nil, nil, ErrQuestionNotFound
+	}
+
+	session, err := s.findUserSession(ctx, question.SessionID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if session.EndedAt != nil {
+		return nil, nil, ErrSessionEnded
+	}
+	if timeUp(session, time.Now()) {
+		summary, err := s.endSession(ctx, session)
+		if err != nil {
+			return nil, nil, err
+		}
+		return &model.AnswerResult{Summary: summary, TimeUp: true}, nil, nil
+	}
+
+	if err := s.questionRepo.MarkAnswered(ctx, question.ID); err != nil {
+		if errors.Is(err, repository.ErrQuestionAnswered) {
+			return nil, nil, ErrQuestionAnswered
+		}
+		return nil, nil, err
+	}
+
+	attempt, grade, err := s.recordAnswer(ctx, session, question, userAnswer, responseMs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result := &model.AnswerResult{
+		Correct:       attempt.Correct,
+		Score:         attempt.Score,
+		CorrectAnswer: grade.correctAnswer,
+		Explanation:   grade.explanation,
+	}
+
+	finished, err := s.modeFinished(ctx, session)
+	if err != nil {
+		return nil, nil, err
+	}
+	if finished {
+		if result.Summary, err = s.endSession(ctx, session); err != nil {
+			return nil, nil, err
+		}
+		return result, nil, nil
+	}
+
+	// A solved puzzle step continues the same puzzle, and a correct
+	// repertoire move the same line
+	var next, nextQuestion *model.Question
+	switch {
+	case question.Type == model.DrillTypeTactics && attempt.Correct && !grade.metadata.PuzzleSolved:
+		next = continuePuzzle(question)
+	case question.Type == model.DrillTypeRepertoire && attempt.Correct && !grade.metadata.LineComplete:
+		next = continueLine(question)
+	}
+	if next != nil {
+		nextQuestion, err = s.storeQuestion(ctx, session, next)
+	}
+	if nextQuestion == nil && err == nil {
+		nextQuestion, err = s.issueQuestion(ctx, session)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return result, nextQuestion, nil
+}
+
+// recordAnswer grades an answer to a question of a session, then records
+// the attempt and its effect on the item's review schedule
+func (s *DrillService) recordAnswer(ctx context.Context, session *model.DrillSession, question *model.Question, userAnswer string, responseMs int) (*model.Attempt, *gradeResult, error) {
+	grade := gradeAnswer(question, userAnswer)
+
+	attempt := model.NewAttempt(session.ID, session.UserID, question.Type, question.Target, grade.correctAnswer, grade.userAnswer, responseMs)
+	attempt.Correct = grade.correct()
+	attempt.Score = grade.score
+	attempt.Metadata = grade.metadata
+	attempt.Perspective = session.Perspective
+	attempt.Piece = session.Piece
+	attempt.Unranked = session.Unranked
+	if session.Region != nil {
+		attempt.Region = session.Region.Label
+	}
+	if _, err := chess.ParseSquare(question.Target); err == nil {
+		attempt.Square = question.Target
+	}
 	if err := s.attemptRepo.Create(ctx, attempt); err != nil {
-		return false, nil, err
+		return nil, nil, err
+	}
+	if err := s.reviewService.Record(ctx, question, attempt); err != nil {
+		return nil, nil, err
 	}
+	return attempt, grade, nil
+}
 
-	nextQuestion := s.GenerateQuestion(drillType, "")
-	return attempt.Correct, nextQuestion, nil
+// findUserSession loads a drill session and verifies it belongs to the user
+func (s *DrillService) findUserSession(ctx context.Context, sessionID, userID bson.ObjectID) (*model.DrillSession, error) {
+	session, err := s.drillSessionRepo.FindByID(ctx, sessionID)
+	if err != nil {
+		if errors.Is(err, repository.ErrDrillSessionNotFound) {
+			return nil, ErrSessionNotFound
+		}
+		return nil, err
+	}
+	if session.UserID != userID {
+		return nil, ErrSessionNotFound
+	}
+	return session, nil
 }
 
-func (s *DrillService) EndSession(ctx context.Context, sessionID bson.ObjectID) (*model.DrillSessionSummary, error) {
-	summary, err := s.attemptRepo.GetSessionSummary(ctx, sessionID)
+// EndSession closes one of the user's sessions and returns its summary
+func (s *DrillService) EndSession(ctx context.Context, sessionID, userID bson.ObjectID) (*model.DrillSessionSummary, error) {
+	session, err := s.findUserSession(ctx, sessionID, userID)
 	if err != nil {
 		return nil, err
 	}
+	if session.EndedAt != nil {
+		return nil, ErrSessionEnded
+	}
+	return s.endSession(ctx, session)
+}
 
-	if err := s.drillSessionRepo.EndSession(ctx, sessionID, *summary); err != nil {
+// endSession closes a session and stores its summary, including the score
+// for its mode, updating session to match. A timed session that ran out
+// ends at its deadline. Ending a challenge attempt records its result.
+func (s *DrillService) endSession(ctx context.Context, session *model.DrillSession) (*model.DrillSessionSummary, error) {
+	summary, err := s.attemptRepo.GetSessionSummary(ctx, session.ID)
+	if err != nil {
 		return nil, err
 	}
+	summarize(session, summary)
+	endedAt := endTime(session, time.Now())
+
+	if err := s.drillSessionRepo.EndSession(ctx, session.ID, endedAt, *summary); err != nil {
+		if errors.Is(err, repository.ErrDrillSessionEnded) {
+			return nil, ErrSessionEnded
+		}
+		return nil, err
+	}
+	session.EndedAt = &endedAt
+	session.Summary = *summary
+	if session.ChallengeDay != "" {
+		if err := s.challengeRepo.Complete(ctx, session.ID, summary.ModeScore, summary.Correct, endedAt.Sub(session.StartedAt)); err != nil {
+			return nil, err
+		}
+	}
 
 	return summary, nil
 }
 
-func (s *DrillService) GenerateQuestion(drillType model.DrillType, pieceType string) *model.Question {
+// GenerateQuestion creates a question of a drill type. Square drills pick
+// their target from weights, which may be nil for uniform selection.
+func (s *DrillService) GenerateQuestion(rng RandomSource, drillType model.DrillType, difficulty model.Difficulty, weights *SquareWeights) *model.Question {
 	switch drillType {
 	case model.DrillTypeNameSquare:
-		return s.generateNameSquareQuestion()
+		return s.generateNameSquareQuestion(weights.Pick(rng))
 	case model.DrillTypeFindSquare:
-		return s.generateFindSquareQuestion()
+		return s.generateFindSquareQuestion(weights.Pick(rng))
 	case model.DrillTypePieceMovement:
-		return s.generatePieceMovementQuestion(pieceType)
+		return s.generatePieceMovementQuestion(rng, "", weights.Pick(rng), difficulty)
 	case model.DrillTypeMoveNotation:
-		return s.generateMoveNotationQuestion()
+		return s.generateMoveNotationQuestion(rng)
+	case model.DrillTypePawnRules:
+		return s.generatePawnRulesQuestion(rng)
+	case model.DrillTypeCastling:
+		return s.generateCastlingQuestion(rng)
+	case model.DrillTypeCheckRecognition:
+		return s.generateCheckRecognitionQuestion(rng)
+	case model.DrillTypeSquareColor:
+		return s.generateSquareColorQuestion(weights.Pick(rng))
+	case model.DrillTypeKnightDistance:
+		return s.generateKnightQuestion(rng, difficulty, weights)
+	case model.DrillTypeGeometry:
+		return s.generateGeometryQuestion(rng, weights)
+	case model.DrillTypeBlindfold:
+		return s.generateBlindfoldQuestion(rng, difficulty)
 	default:
-		return s.generateNameSquareQuestion()
+		return s.generateNameSquareQuestion(weights.Pick(rng))
 	}
 }
 
-func (s *DrillService) RandomSquare() string {
-	fileIdx, _ := rand.Int(rand.Reader, big.NewInt(8))
-	rankIdx, _ := rand.Int(rand.Reader, big.NewInt(8))
-	return files[fileIdx.Int64()] + ranks[rankIdx.Int64()]
+// pieceAllowed reports whether sessions of a drill type can be fixed to a
+// piece
+func pieceAllowed(drillType model.DrillType, piece string) bool {
+	return drillType == model.DrillTypePieceMovement && slices.Contains(movementPieces, piece)
 }
 
-func (s *DrillService) generateNameSquareQuestion() *model.Question {
-	target := s.RandomSquare()
+func (s *DrillService) generateNameSquareQuestion(target string) *model.Question {
 	return &model.Question{
 		Type:   model.DrillTypeNameSquare,
 		Target: target,
 		Prompt: "",
-		FEN:    "8/8/8/8/8/8/8/8 w - - 0 1",
+		FEN:    chess.EmptyFEN,
+		Answer: target,
 	}
 }
 
-func (s *DrillService) generateFindSquareQuestion() *model.Question {
-	target := s.RandomSquare()
+func (s *DrillService) generateFindSquareQuestion(target string) *model.Question {
 	return &model.Question{
 		Type:   model.DrillTypeFindSquare,
 		Target: target,
 		Prompt: target,
-		FEN:    "8/8/8/8/8/8/8/8 w - - 0 1",
+		FEN:    chess.EmptyFEN,
+		Answer: target,
 	}
 }
 
-func (s *DrillService) generatePieceMovementQuestion(pieceType string) *model.Question {
+// generateSquareColorQuestion asks for the color of a square without
+// showing the board
+func (s *DrillService) generateSquareColorQuestion(target string) *model.Question {
+	sq, _ := chess.ParseSquare(target)
+	color := "dark"
+	if sq.IsLight() {
+		color = "light"
+	}
+	return &model.Question{
+		Type:   model.DrillTypeSquareColor,
+		Target: target,
+		Prompt: fmt.Sprintf("Is %s a light or a dark square?", target),
+		Answer: color,
+	}
+}
+
+func (s *DrillService) generatePieceMovementQuestion(rng RandomSource, pieceType, square string, difficulty model.Difficulty) *model.Question {
 	if pieceType == "" {
-		pieceTypes := []string{"knight", "bishop", "rook", "queen", "king"}
-		idx, _ := rand.Int(rand.Reader, big.NewInt(int64(len(pieceTypes))))
-		pieceType = pieceTypes[idx.Int64()]
+		pieceType = movementPieces[rng.IntN(len(movementPieces))]
 	}
 
-	square := s.RandomSquare()
 	fen := s.generateSinglePieceFEN(pieceType, square)
+	openSquares, _ := s.LegalMoves(fen, square)
 
-	return &model.Question{
+	question := &model.Question{
 		Type:   model.DrillTypePieceMovement,
 		Target: square,
 		Prompt: fmt.Sprintf("Where can the %s move?", pieceType),
 		FEN:    fen,
+		Answer: strings.Join(openSquares, ","),
+		Metadata: map[string]string{
+			"piece_type":  pieceType,
+			"from_square": square,
+			"category":    "open",
+		},
+	}
+
+	obstacles := obstacleCount(rng, difficulty)
+	if obstacles == 0 {
+		return question
+	}
+
+	pos := addObstacles(rng, fen, square, openSquares, obstacles)
+	destinations, _ := s.LegalMoves(pos.FEN(), square)
+
+	var captures []string
+	for _, d := range destinations {
+		if sq, _ := chess.ParseSquare(d); !pos.Piece(sq).IsEmpty() {
+			captures = append(captures, d)
+		}
+	}
+
+	question.Prompt = fmt.Sprintf("Where can the white %s move? Black pieces can be captured.", pieceType)
+	question.FEN = pos.FEN()
+	question.Answer = strings.Join(destinations, ",")
+	question.Metadata["category"] = "obstacles"
+	question.AnswerData = map[string]string{
+		"captures":     strings.Join(captures, ","),
+		"open_squares": strings.Join(openSquares, ","),
+	}
+	return question
+}
+
+// obstacleCount returns how many friendly and enemy pieces to place in the
+// path of the drilled piece
+func obstacleCount(rng RandomSource, difficulty model.Difficulty) int {
+	switch difficulty {
+	case model.DifficultyMedium:
+		return 2 + rng.IntN(2)
+	case model.DifficultyHard:
+		return 4 + rng.IntN(3)
+	default:
+		return 0
+	}
+}
+
+// addObstacles places white (friendly) and black (enemy) pieces on squares
+// the drilled piece could otherwise reach, so they block rays or can be
+// captured
+func addObstacles(rng RandomSource, fen, square string, openSquares []string, count int) *chess.Position {
+	pos, _ := chess.ParseFEN(fen)
+	obstacleTypes := []chess.PieceType{chess.Pawn, chess.Knight, chess.Bishop, chess.Rook}
+
+	candidates := append([]string(nil), openSquares...)
+	for placed := 0; placed < count && len(candidates) > 0; {
+		idx := rng.IntN(len(candidates))
+		sq, _ := chess.ParseSquare(candidates[idx])
+		candidates = append(candidates[:idx], candidates[idx+1:]...)
+
+		piece := chess.Piece{
+			Type:  obstacleTypes[rng.IntN(len(obstacleTypes))],
+			Color: chess.Color(rng.IntN(2)),
+		}
+		if piece.Type == chess.Pawn && (sq.Rank() == 0 || sq.Rank() == 7) {
+			piece.Type = chess.Knight
+		}
+		pos.SetPiece(sq, piece)
+		placed++
+	}
+	return pos
+}
+
+// generateMoveNotationQuestion picks a legal move in a random position and
+// either shows it on the board and asks for its SAN, or gives the SAN and
+// asks the user to play it
+func (s *DrillService) generateMoveNotationQuestion(rng RandomSource) *model.Question {
+	pos := randomPosition(rng, 3+rng.IntN(8))
+	move := pickNotableMove(rng, pos)
+	san := pos.SAN(move)
+
+	if rng.IntN(2) == 0 {
+		return &model.Question{
+			Type:   model.DrillTypeMoveNotation,
+			Target: move.UCI(),
+			Prompt: fmt.Sprintf("%s to move: write the highlighted move in algebraic notation", capitalize(pos.Turn.String())),
+			FEN:    pos.FEN(),
+			Answer: san,
+			Metadata: map[string]string{
+				"category": "write_san",
+			},
+			AnswerData: map[string]string{
+				"move": move.UCI(),
+			},
+		}
+	}
+
+	return &model.Question{
+		Type:   model.DrillTypeMoveNotation,
+		Target: san,
+		Prompt: fmt.Sprintf("%s to move: play %s", capitalize(pos.Turn.String()), san),
+		FEN:    pos.FEN(),
+		Answer: move.UCI(),
 		Metadata: map[string]string{
-			"piece_type": pieceType,
+			"category": "play_san",
+		},
+		AnswerData: map[string]string{
+			"move": move.UCI(),
 		},
 	}
 }
 
-func (s *DrillService) generateMoveNotationQuestion() *model.Question {
-	return s.generatePieceMovementQuestion("knight")
+// pickNotableMove prefers moves whose SAN exercises captures, checks,
+// promotions, castling or disambiguation, falling back to any legal move
+func pickNotableMove(rng RandomSource, pos *chess.Position) chess.Move {
+	moves := pos.LegalMoves()
+	if rng.IntN(3) > 0 {
+		var notable []chess.Move
+		for _, m := range moves {
+			san := pos.SAN(m)
+			plain := strings.TrimRight(san, "+#")
+			if strings.ContainsAny(san, "x+#=O") || (plain[0] >= 'A' && plain[0] <= 'Z' && len(plain) > 3) {
+				notable = append(notable, m)
+			}
+		}
+		if len(notable) > 0 {
+			return notable[rng.IntN(len(notable))]
+		}
+	}
+	return moves[rng.IntN(len(moves))]
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
 }
 
 func (s *DrillService) generateSinglePieceFEN(pieceType, square string) string {
@@ -173,12 +635,62 @@ func (s *DrillService) GetSessionAttempts(ctx context.Context, sessionID bson.Ob
 	return s.attemptRepo.FindBySessionID(ctx, sessionID)
 }
 
-func (s *DrillService) GetNextQuestion(ctx context.Context, sessionID bson.ObjectID) (*model.Question, error) {
-	session, err := s.drillSessionRepo.FindByID(ctx, sessionID)
+// LegalMoves returns the sorted destination squares of the piece on square.
+// The piece is treated as the side to move so lone drill pieces of either
+// color can be queried.
+func (s *DrillService) LegalMoves(fen, square string) ([]string, error) {
+	pos, err := chess.ParseFEN(fen)
 	if err != nil {
 		return nil, err
 	}
 
-	question := s.GenerateQuestion(session.DrillType, "")
-	return question, nil
+	sq, err := chess.ParseSquare(square)
+	if err != nil {
+		return nil, err
+	}
+
+	piece := pos.Piece(sq)
+	if piece.IsEmpty() {
+		return nil, ErrNoPieceOnSquare
+	}
+	if piece.Color != pos.Turn {
+		pos.Turn = piece.Color
+		pos.EnPassant = chess.NoSquare
+	}
+
+	destinations := chess.Destinations(pos.LegalMovesFrom(sq))
+	moves := make([]string, 0, len(destinations))
+	for _, d := range destinations {
+		moves = append(moves, d.String())
+	}
+	sort.Strings(moves)
+	return moves, nil
+}
+
+// GetNextQuestion returns the session's pending question, issuing a new one
+// if every question so far has been answered
+func (s *DrillService) GetNextQuestion(ctx context.Context, sessionID, userID bson.ObjectID) (*model.Question, error) {
+	session, err := s.findUserSession(ctx, sessionID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if session.EndedAt != nil {
+		return nil, ErrSessionEnded
+	}
+	if timeUp(session, time.Now()) {
+		if _, err := s.endSession(ctx, session); err != nil && !errors.Is(err, ErrSessionEnded) {
+			return nil, err
+		}
+		return nil, ErrSessionEnded
+	}
+
+	question, err := s.questionRepo.FindPending(ctx, session.ID)
+	if err == nil {
+		return question, nil
+	}
+	if !errors.Is(err, repository.ErrQuestionNotFound) {
+		return nil, err
+	}
+
+	return s.issueQuestion(ctx, session)
 }
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/abdul-hamid-achik/chessdrill/internal/chess"
+	"github.com/abdul-hamid-achik/chessdrill/internal/model"
+)
+
+// Board geometry question kinds
+const (
+	GeometryDiagonal     = "same_diagonal"
+	GeometryLine         = "same_line"
+	GeometryKingDistance = "king_distance"
+	GeometryIntersection = "diagonal_intersection"
+)
+
+var geometryKinds = []string{GeometryDiagonal, GeometryLine, GeometryKingDistance, GeometryIntersection}
+
+// squareName builds algebraic notation from 0-based file and rank indexes
+func squareName(file, rank int) string {
+	return files[file] + ranks[rank]
+}
+
+// pickFileRank picks a square from weights as 0-based file and rank indexes
+func pickFileRank(rng RandomSource, weights *SquareWeights) (int, int) {
+	sq, _ := chess.ParseSquare(weights.Pick(rng))
+	return sq.File(), sq.Rank()
+}
+
+// diagonalSteps lists the nonzero steps d for which the square d files and
+// d*dir ranks away from (file, rank) is on the board
+func diagonalSteps(file, rank, dir int) []int {
+	var steps []int
+	for d := -7; d <= 7; d++ {
+		if d != 0 && onBoard(file+d, rank+d*dir) {
+			steps = append(steps, d)
+		}
+	}
+	return steps
+}
+
+func absInt(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// generateGeometryQuestion picks two squares and asks a question about how
+// they relate on the board. The first square, or for diagonal crossings the
+// square named in the answer, is drawn from weights. The board is not shown.
+func (s *DrillService) generateGeometryQuestion(rng RandomSource, weights *SquareWeights) *model.Question {
+	kind := geometryKinds[rng.IntN(len(geometryKinds))]
+
+	var f1, r1, f2, r2 int
+	var prompt, answer, reason string
+
+	switch kind {
+	case GeometryDiagonal:
+		shared := rng.IntN(2) == 0
+		f1, r1 = pickFileRank(rng, weights)
+		for {
+			f2, r2 = rng.IntN(8), rng.IntN(8)
+			df, dr := absInt(f2-f1), absInt(r2-r1)
+			if df+dr > 0 && (df == dr) == shared {
+				break
+			}
+		}
+		prompt = fmt.Sprintf("Are %s and %s on the same diagonal?", squareName(f1, r1), squareName(f2, r2))
+		answer = "no"
+		if shared {
+			answer = "yes"
+		}
+		reason = fmt.Sprintf("They are %s and %s apart; squares share a diagonal only when both distances are equal.",
+			plural(absInt(f2-f1), "file"), plural(absInt(r2-r1), "rank"))
+
+	case GeometryLine:
+		f1, r1 = pickFileRank(rng, weights)
+		switch rng.IntN(3) {
+		case 0:
+			answer, f2, r2 = "rank", (f1+1+rng.IntN(7))%8, r1
+		case 1:
+			answer, f2, r2 = "file", f1, (r1+1+rng.IntN(7))%8
+		default:
+			answer, f2, r2 = "neither", (f1+1+rng.IntN(7))%8, (r1+1+rng.IntN(7))%8
+		}
+		prompt = fmt.Sprintf("Do %s and %s share a rank, a file, or neither?", squareName(f1, r1), squareName(f2, r2))
+		reason = fmt.Sprintf("%s is on the %s-file and rank %s; %s is on the %s-file and rank %s.",
+			squareName(f1, r1), files[f1], ranks[r1], squareName(f2, r2), files[f2], ranks[r2])
+
+	case GeometryKingDistance:
+		f1, r1 = pickFileRank(rng, weights)
+		f2, r2 = f1, r1
+		for f1 == f2 && r1 == r2 {
+			f2, r2 = rng.IntN(8), rng.IntN(8)
+		}
+		df, dr := absInt(f2-f1), absInt(r2-r1)
+		distance := max(df, dr)
+		prompt = fmt.Sprintf("How many king moves from %s to %s?", squareName(f1, r1), squareName(f2, r2))
+		answer = strconv.Itoa(distance)
+		reason = fmt.Sprintf("They are %s and %s apart; a king covers one of each per diagonal step, so it needs %s.",
+			plural(df, "file"), plural(dr, "rank"), plural(distance, "move"))
+
+	case GeometryIntersection:
+		// Pick the meeting square first, then a square on each diagonal
+		// through it. The a8 and h1 corners lie on a single-square
+		// diagonal, so a region of only those falls back to the whole board.
+		fm, rm := pickFileRank(rng, weights)
+		for tries := 0; len(diagonalSteps(fm, rm, 1)) == 0 || len(diagonalSteps(fm, rm, -1)) == 0; tries++ {
+			if tries == 8 {
+				weights = nil
+			}
+			fm, rm = pickFileRank(rng, weights)
+		}
+		steps1, steps2 := diagonalSteps(fm, rm, 1), diagonalSteps(fm, rm, -1)
+		d1, d2 := steps1[rng.IntN(len(steps1))], steps2[rng.IntN(len(steps2))]
+		f1, r1 = fm+d1, rm+d1 // a1-h8 direction
+		f2, r2 = fm+d2, rm-d2 // h1-a8 direction
+		prompt = fmt.Sprintf("On which square does the a1-h8 diagonal through %s cross the h1-a8 diagonal through %s?",
+			squareName(f1, r1), squareName(f2, r2))
+		answer = squareName(fm, rm)
+		reason = fmt.Sprintf("Walk diagonally from %s and from %s until the paths meet on %s.",
+			squareName(f1, r1), squareName(f2, r2), answer)
+	}
+
+	return &model.Question{
+		Type:   model.DrillTypeGeometry,
+		Target: squareName(f1, r1) + "-" + squareName(f2, r2),
+		Prompt: prompt,
+		Answer: answer,
+		Metadata: map[string]string{
+			"from_square": squareName(f1, r1),
+			"to_square":   squareName(f2, r2),
+			"category":    kind,
+		},
+		AnswerData: map[string]string{
+			"reason": reason,
+		},
+	}
+}
+
+// plural formats a count with a singular or plural noun
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+func onBoard(file, rank int) bool {
+	return file >= 0 && file < 8 && rank >= 0 && rank < 8
+}
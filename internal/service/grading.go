@@ -0,0 +1,568 @@
+package service
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/abdul-hamid-achik/chessdrill/internal/chess"
+	"github.com/abdul-hamid-achik/chessdrill/internal/model"
+)
+
+// gradeResult is the outcome of comparing a user's answer with the stored
+// answer of a question. accepted marks an answer that counts as correct
+// without matching the stored one.
+type gradeResult struct {
+	correctAnswer string
+	userAnswer    string
+	accepted      bool
+	score         float64
+	explanation   string
+	metadata      model.AttemptMetadata
+}
+
+func (g *gradeResult) correct() bool {
+	return g.accepted || g.correctAnswer == g.userAnswer
+}
+
+// gradeAnswer normalizes both answers and grades them according to the
+// question's drill type
+func gradeAnswer(question *model.Question, userAnswer string) *gradeResult {
+	g := &gradeResult{
+		correctAnswer: normalizeAnswer(question.Answer),
+		userAnswer:    normalizeAnswer(userAnswer),
+		metadata: model.AttemptMetadata{
+			PieceType:  question.Metadata["piece_type"],
+			PieceColor: question.Metadata["piece_color"],
+			FromSquare: question.Metadata["from_square"],
+			FEN:        question.FEN,
+			Category:   question.Metadata["category"],
+		},
+	}
+	// Categories that would give the answer away are kept in AnswerData
+	if category, ok := question.AnswerData["category"]; ok {
+		g.metadata.Category = category
+	}
+
+	switch question.Type {
+	case model.DrillTypePieceMovement:
+		gradeSquareSet(g)
+		gradeObstacles(g, question)
+	case model.DrillTypePawnRules:
+		gradeSquareSet(g)
+	case model.DrillTypeCastling:
+		gradeCastling(g, question)
+	case model.DrillTypeCheckRecognition:
+		gradeCheckRecognition(g, question)
+	case model.DrillTypeSquareColor:
+		gradeSquareColor(g, question)
+	case model.DrillTypeGeometry:
+		g.userAnswer = normalizeYesNo(g.userAnswer)
+		if !g.correct() {
+			g.explanation = question.AnswerData["reason"]
+		}
+	case model.DrillTypeBlindfold:
+		gradeBlindfold(g, question)
+	case model.DrillTypeKnightDistance:
+		if question.Metadata["category"] == KnightPath {
+			gradeKnightPath(g, question)
+		} else {
+			gradeKnightDistance(g, question)
+		}
+	case model.DrillTypeMoveNotation:
+		// SAN is case-sensitive ("bc4" is a pawn move, "Bc4" a bishop move)
+		g.correctAnswer = strings.TrimSpace(question.Answer)
+		g.userAnswer = strings.TrimSpace(userAnswer)
+		gradeMove(g, question)
+	case model.DrillTypeTactics:
+		g.correctAnswer = strings.TrimSpace(question.Answer)
+		g.userAnswer = strings.TrimSpace(userAnswer)
+		gradeTactics(g, question)
+	case model.DrillTypeRepertoire:
+		g.correctAnswer = strings.TrimSpace(question.Answer)
+		g.userAnswer = strings.TrimSpace(userAnswer)
+		gradeRepertoire(g, question)
+	}
+
+	if g.correct() && g.score == 0 {
+		g.score = 1
+	}
+	return g
+}
+
+func normalizeAnswer(answer string) string {
+	return strings.ToLower(strings.TrimSpace(answer))
+}
+
+// parseSquareSet parses a comma or space separated list of squares into a
+// sorted, de-duplicated slice. Tokens that are not squares are dropped.
+func parseSquareSet(answer string) []string {
+	fields := strings.FieldsFunc(answer, func(r rune) bool {
+		return r == ',' || r == ' ' || r == ';'
+	})
+
+	seen := make(map[string]bool)
+	var squares []string
+	for _, f := range fields {
+		f = strings.ToLower(f)
+		if _, err := chess.ParseSquare(f); err != nil || seen[f] {
+			continue
+		}
+		seen[f] = true
+		squares = append(squares, f)
+	}
+	sort.Strings(squares)
+	return squares
+}
+
+// gradeSquareSet grades a set-of-squares answer. Both answers are rewritten to
+// their canonical form so an exact match means the sets are equal, and the
+// score is the F1 of precision and recall for partial credit.
+func gradeSquareSet(g *gradeResult) {
+	expected := parseSquareSet(g.correctAnswer)
+	given := parseSquareSet(g.userAnswer)
+	g.correctAnswer = strings.Join(expected, ",")
+	g.userAnswer = strings.Join(given, ",")
+
+	expectedSet := make(map[string]bool, len(expected))
+	for _, sq := range expected {
+		expectedSet[sq] = true
+	}
+	givenSet := make(map[string]bool, len(given))
+	for _, sq := range given {
+		givenSet[sq] = true
+	}
+
+	var missed, extra []string
+	hits := 0
+	for _, sq := range expected {
+		if givenSet[sq] {
+			hits++
+		} else {
+			missed = append(missed, sq)
+		}
+	}
+	for _, sq := range given {
+		if !expectedSet[sq] {
+			extra = append(extra, sq)
+		}
+	}
+
+	precision, recall := 1.0, 1.0
+	if len(given) > 0 {
+		precision = float64(hits) / float64(len(given))
+	} else if len(expected) > 0 {
+		precision = 0
+	}
+	if len(expected) > 0 {
+		recall = float64(hits) / float64(len(expected))
+	}
+	if precision+recall > 0 {
+		g.score = 2 * precision * recall / (precision + recall)
+	}
+
+	g.metadata.ExpectedSquares = expected
+	g.metadata.MissedSquares = missed
+	g.metadata.ExtraSquares = extra
+	g.metadata.Precision = precision
+	g.metadata.Recall = recall
+
+	if len(missed) > 0 || len(extra) > 0 {
+		g.explanation = fmt.Sprintf("Found %d of %d squares (%.0f%% credit).", hits, len(expected), g.score*100)
+		if len(missed) > 0 {
+			g.explanation += " Missed: " + strings.Join(missed, ", ") + "."
+		}
+		if len(extra) > 0 {
+			g.explanation += " Not reachable: " + strings.Join(extra, ", ") + "."
+		}
+	}
+}
+
+// gradeMove grades a move answer by resolving it to a legal move in the
+// question's position, so a board click or any equivalent spelling of the
+// right move is accepted. A SAN answer may omit the check or mate suffix
+// but must not claim a check that the move does not give. Written SAN
+// questions test the notation itself and are graded by gradeWrittenSAN.
+func gradeMove(g *gradeResult, question *model.Question) {
+	pos, err := chess.ParseFEN(question.FEN)
+	if err != nil {
+		return
+	}
+	expected, err := chess.ParseUCI(question.AnswerData["move"])
+	if err != nil {
+		return
+	}
+	g.metadata.Move = expected.UCI()
+	g.metadata.SAN = pos.SAN(expected)
+
+	if g.metadata.Category == "write_san" {
+		gradeWrittenSAN(g, pos, expected)
+		return
+	}
+
+	played, ok := parseMoveAnswer(pos, g.userAnswer)
+	if !ok {
+		g.explanation = fmt.Sprintf("%q is not a legal move here.", g.userAnswer)
+		return
+	}
+	if played != expected {
+		g.explanation = fmt.Sprintf("You gave %s, a different move.", pos.SAN(played))
+		return
+	}
+
+	san := pos.SAN(expected)
+	claimsCheck := strings.ContainsAny(g.userAnswer, "+#")
+	if claimsCheck && !strings.ContainsAny(san, "+#") {
+		g.explanation = "Right move, but it does not give check."
+		return
+	}
+	if strings.HasSuffix(g.userAnswer, "#") && !strings.HasSuffix(san, "#") {
+		g.explanation = "Right move, but it is check, not mate."
+		return
+	}
+
+	g.userAnswer = g.correctAnswer
+}
+
+// gradeWrittenSAN grades a move written in SAN against the move's own
+// spelling. Only the check suffix and a promotion's "=" are optional: a
+// capture without its "x" or a move missing its disambiguation is wrong,
+// even though the lenient SAN parser would still find the move
+func gradeWrittenSAN(g *gradeResult, pos *chess.Position, expected chess.Move) {
+	san := pos.SAN(expected)
+	answer := g.userAnswer
+	bare := func(s string) string {
+		return strings.ReplaceAll(strings.TrimRight(s, "+#"), "=", "")
+	}
+
+	if bare(answer) != bare(san) {
+		played, err := pos.ParseSAN(answer)
+		switch {
+		case err != nil:
+			g.explanation = fmt.Sprintf("%q is not a legal move in SAN here.", answer)
+		case played == expected:
+			g.explanation = fmt.Sprintf("Right move, but it is written %s.", san)
+		default:
+			g.explanation = fmt.Sprintf("You wrote %s, a different move.", pos.SAN(played))
+		}
+		return
+	}
+
+	if strings.ContainsAny(answer, "+#") && !strings.ContainsAny(san, "+#") {
+		g.explanation = "Right move, but it does not give check."
+		return
+	}
+	if strings.HasSuffix(answer, "#") && !strings.HasSuffix(san, "#") {
+		g.explanation = "Right move, but it is check, not mate."
+		return
+	}
+
+	g.userAnswer = g.correctAnswer
+}
+
+// parseMoveAnswer accepts a move as UCI (from a board click, where a missing
+// promotion piece means a queen) or as SAN
+func parseMoveAnswer(pos *chess.Position, answer string) (chess.Move, bool) {
+	if m, err := chess.ParseUCI(strings.ToLower(answer)); err == nil {
+		if m.Promotion == chess.NoPieceType && pos.Piece(m.From).Type == chess.Pawn && (m.To.Rank() == 0 || m.To.Rank() == 7) {
+			m.Promotion = chess.Queen
+		}
+		if pos.IsLegal(m) {
+			return m, true
+		}
+	}
+	if m, err := pos.ParseSAN(answer); err == nil {
+		return m, true
+	}
+	return chess.Move{}, false
+}
+
+// gradeObstacles records which obstacle concepts a piece movement answer got
+// wrong: captures the user did not see, and squares the user picked that
+// the piece could reach on an empty board but not through the blockers
+func gradeObstacles(g *gradeResult, question *model.Question) {
+	if question.AnswerData == nil {
+		return
+	}
+
+	captures := parseSquareSet(question.AnswerData["captures"])
+	open := make(map[string]bool)
+	for _, sq := range parseSquareSet(question.AnswerData["open_squares"]) {
+		open[sq] = true
+	}
+
+	missed := make(map[string]bool)
+	for _, sq := range g.metadata.MissedSquares {
+		missed[sq] = true
+	}
+
+	g.metadata.CaptureSquares = captures
+	for _, sq := range captures {
+		if missed[sq] {
+			g.metadata.MissedCaptures = append(g.metadata.MissedCaptures, sq)
+		}
+	}
+	for _, sq := range g.metadata.ExtraSquares {
+		if open[sq] {
+			g.metadata.BlockedSquares = append(g.metadata.BlockedSquares, sq)
+		}
+	}
+
+	if len(g.metadata.MissedCaptures) > 0 {
+		g.explanation += " Missed captures: " + strings.Join(g.metadata.MissedCaptures, ", ") + "."
+	}
+	if len(g.metadata.BlockedSquares) > 0 {
+		g.explanation += " Blocked: " + strings.Join(g.metadata.BlockedSquares, ", ") + "."
+	}
+}
+
+// normalizeYesNo maps common spellings of yes and no onto "yes" and "no" and
+// leaves other answers alone
+func normalizeYesNo(answer string) string {
+	switch answer {
+	case "y", "true", "legal":
+		return "yes"
+	case "n", "false", "illegal":
+		return "no"
+	}
+	return answer
+}
+
+// gradeCastling accepts common spellings of yes and no and always explains
+// why castling is or is not legal
+func gradeCastling(g *gradeResult, question *model.Question) {
+	g.userAnswer = normalizeYesNo(g.userAnswer)
+	g.explanation = question.AnswerData["reason"]
+	g.metadata.CastlingSide = question.Metadata["castling_side"]
+}
+
+// gradeCheckRecognition grades a classification, optionally followed by the
+// checking squares ("check:e4,f6"). A right classification of a check earns
+// half credit, and the checking pieces the other half.
+func gradeCheckRecognition(g *gradeResult, question *model.Question) {
+	status := question.AnswerData["category"]
+	checkers := parseSquareSet(question.AnswerData["checkers"])
+	g.metadata.Checkers = checkers
+
+	given, squares, _ := strings.Cut(g.userAnswer, ":")
+	switch given = strings.TrimSpace(given); given {
+	case "mate", "#":
+		given = StatusCheckmate
+	case "stale", "draw":
+		given = StatusStalemate
+	case "+":
+		given = StatusCheck
+	case "none", "neither":
+		given = StatusNormal
+	}
+	g.metadata.Classification = given
+
+	if given != status {
+		g.userAnswer = given
+		switch status {
+		case StatusNormal:
+			g.explanation = "The king is not in check and there are legal moves."
+		case StatusStalemate:
+			g.explanation = "It is stalemate: the king is not in check but there are no legal moves."
+		default:
+			pieces := "piece"
+			if len(checkers) > 1 {
+				pieces = "pieces"
+			}
+			g.explanation = fmt.Sprintf("It is %s, given by the %s on %s.", status, pieces, strings.Join(checkers, " and "))
+		}
+		return
+	}
+	if status != StatusCheck {
+		g.userAnswer = g.correctAnswer
+		return
+	}
+
+	sub := &gradeResult{correctAnswer: strings.Join(checkers, ","), userAnswer: squares}
+	gradeSquareSet(sub)
+	g.correctAnswer = StatusCheck + ":" + sub.correctAnswer
+	g.userAnswer = StatusCheck + ":" + sub.userAnswer
+	g.score = 0.5 + sub.score/2
+	g.metadata.ExpectedSquares = sub.metadata.ExpectedSquares
+	g.metadata.MissedSquares = sub.metadata.MissedSquares
+	g.metadata.ExtraSquares = sub.metadata.ExtraSquares
+	g.metadata.Precision = sub.metadata.Precision
+	g.metadata.Recall = sub.metadata.Recall
+	if !g.correct() {
+		g.explanation = fmt.Sprintf("It is check, but the checking pieces are on %s.", strings.Join(checkers, ", "))
+	}
+}
+
+// gradeSquareColor accepts "white"/"black" and single letters for the square
+// colors, and explains the file-plus-rank parity rule on wrong answers
+func gradeSquareColor(g *gradeResult, question *model.Question) {
+	switch g.userAnswer {
+	case "l", "w", "white":
+		g.userAnswer = "light"
+	case "d", "b", "black":
+		g.userAnswer = "dark"
+	}
+	if g.correct() {
+		return
+	}
+
+	sq, err := chess.ParseSquare(question.Target)
+	if err != nil {
+		return
+	}
+	sum := sq.File() + 1 + sq.Rank() + 1
+	parity := "even"
+	if sum%2 == 1 {
+		parity = "odd"
+	}
+	g.explanation = fmt.Sprintf("%s is file %d, rank %d: %d is %s, and squares with an %s sum are %s.",
+		question.Target, sq.File()+1, sq.Rank()+1, sum, parity, parity, g.correctAnswer)
+}
+
+// gradeKnightDistance grades an estimated number of knight moves
+func gradeKnightDistance(g *gradeResult, question *model.Question) {
+	g.metadata.KnightDistance, _ = strconv.Atoi(question.AnswerData["distance"])
+	g.metadata.GivenDistance, _ = strconv.Atoi(g.userAnswer)
+	if !g.correct() {
+		route := strings.ReplaceAll(question.AnswerData["route"], ",", "-")
+		g.explanation = fmt.Sprintf("One shortest route is %s-%s.", question.Metadata["from_square"], route)
+	}
+}
+
+// gradeKnightPath validates a clicked knight route hop by hop. Any shortest
+// route is correct; a valid but longer route earns partial credit.
+func gradeKnightPath(g *gradeResult, question *model.Question) {
+	distance, _ := strconv.Atoi(question.AnswerData["distance"])
+	g.metadata.KnightDistance = distance
+
+	from, err := chess.ParseSquare(question.Metadata["from_square"])
+	if err != nil {
+		return
+	}
+	to, err := chess.ParseSquare(question.Metadata["to_square"])
+	if err != nil {
+		return
+	}
+
+	// The route may be given with or without the starting square
+	route := strings.FieldsFunc(g.userAnswer, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '-'
+	})
+	if len(route) > 0 && route[0] == from.String() {
+		route = route[1:]
+	}
+	g.userAnswer = strings.Join(route, ",")
+	g.metadata.KnightPath = route
+	g.metadata.GivenDistance = len(route)
+
+	shortest := strings.ReplaceAll(question.AnswerData["route"], ",", "-")
+	if len(route) == 0 {
+		g.explanation = fmt.Sprintf("No route given. One shortest route is %s-%s.", from, shortest)
+		return
+	}
+	prev := from
+	for _, name := range route {
+		sq, err := chess.ParseSquare(name)
+		if err != nil {
+			g.explanation = fmt.Sprintf("%q is not a square. One shortest route is %s-%s.", name, from, shortest)
+			return
+		}
+		if !chess.IsKnightMove(prev, sq) {
+			g.explanation = fmt.Sprintf("%s to %s is not a knight move. One shortest route is %s-%s.", prev, sq, from, shortest)
+			return
+		}
+		prev = sq
+	}
+	if prev != to {
+		g.explanation = fmt.Sprintf("The route ends on %s, not %s. One shortest route is %s-%s.", prev, to, from, shortest)
+		return
+	}
+
+	if len(route) > distance {
+		g.score = float64(distance) / float64(len(route))
+		g.explanation = fmt.Sprintf("Valid route, but it takes %d moves; %d is possible, e.g. %s-%s.", len(route), distance, from, shortest)
+		return
+	}
+	g.userAnswer = g.correctAnswer
+}
+
+// gradeBlindfold records the sequence that was played and the position it
+// led to, which the client never sees
+func gradeBlindfold(g *gradeResult, question *model.Question) {
+	g.metadata.FEN = question.AnswerData["fen"]
+	g.metadata.Moves = question.Metadata["moves"]
+	g.metadata.SequenceLength, _ = strconv.Atoi(question.Metadata["sequence_length"])
+
+	g.userAnswer = normalizeYesNo(g.userAnswer)
+	if !g.correct() {
+		g.explanation = question.AnswerData["reason"]
+	}
+}
+
+// gradeTactics grades one move of a puzzle solution. As on Lichess, any move
+// that delivers checkmate solves the puzzle even if it is not the stored one.
+func gradeTactics(g *gradeResult, question *model.Question) {
+	g.metadata.PuzzleID = question.Metadata["puzzle_id"]
+	g.metadata.PuzzleRating, _ = strconv.Atoi(question.Metadata["rating"])
+	g.metadata.Themes = strings.Fields(question.AnswerData["themes"])
+	g.metadata.PuzzlePly, _ = strconv.Atoi(question.AnswerData["ply"])
+	moves := strings.Fields(question.AnswerData["moves"])
+
+	gradeMove(g, question)
+
+	if !g.correct() {
+		pos, err := chess.ParseFEN(question.FEN)
+		if err != nil {
+			return
+		}
+		played, ok := parseMoveAnswer(pos, g.userAnswer)
+		if !ok || !pos.Play(played).IsCheckmate() {
+			g.explanation = strings.TrimSpace(g.explanation + " Puzzle failed.")
+			return
+		}
+		g.userAnswer = g.correctAnswer
+		g.metadata.PuzzleSolved = true
+	}
+
+	if g.metadata.PuzzlePly+1 >= len(moves) {
+		g.metadata.PuzzleSolved = true
+	}
+	if g.metadata.PuzzleSolved {
+		g.explanation = "Puzzle solved!"
+	}
+}
+
+// gradeRepertoire grades one of the user's moves in a repertoire line. Any
+// move the repertoire prepares at the position counts, though the line
+// goes on with its own move.
+func gradeRepertoire(g *gradeResult, question *model.Question) {
+	g.metadata.Line = question.AnswerData["line"]
+	g.metadata.LinePly, _ = strconv.Atoi(question.AnswerData["ply"])
+	moves := strings.Fields(question.AnswerData["moves"])
+	prepared := strings.Fields(question.AnswerData["prepared"])
+
+	gradeMove(g, question)
+
+	if !g.correct() {
+		pos, err := chess.ParseFEN(question.FEN)
+		if err != nil || g.metadata.LinePly >= len(prepared) {
+			return
+		}
+		played, ok := parseMoveAnswer(pos, g.userAnswer)
+		if !ok || played.UCI() == g.metadata.Move || !slices.Contains(strings.Split(prepared[g.metadata.LinePly], ","), played.UCI()) {
+			return
+		}
+		g.explanation = fmt.Sprintf("%s is also in your repertoire; this line continues %s.", pos.SAN(played), g.correctAnswer)
+		g.metadata.AlternativeMove = played.UCI()
+		g.accepted = true
+	}
+
+	if g.metadata.LinePly+2 >= len(moves) {
+		g.metadata.LineComplete = true
+		if g.explanation == "" {
+			g.explanation = "Line complete!"
+		}
+	}
+}
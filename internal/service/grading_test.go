@@ -0,0 +1,118 @@
+package service
+
+import (
+	"math"
+	"testing"
+
+	"github.com/abdul-hamid-achik/chessdrill/internal/model"
+)
+
+// moveQuestion builds a move notation question for one move of a position
+func moveQuestion(category, fen, uci, answer string) *model.Question {
+	return &model.Question{
+		Type:       model.DrillTypeMoveNotation,
+		FEN:        fen,
+		Answer:     answer,
+		Metadata:   map[string]string{"category": category},
+		AnswerData: map[string]string{"move": uci},
+	}
+}
+
+func TestGradeAnswer(t *testing.T) {
+	pieceMovement := &model.Question{
+		Type:   model.DrillTypePieceMovement,
+		Target: "a1",
+		Answer: "b2,c3,d4,a2",
+	}
+	check := &model.Question{
+		Type:       model.DrillTypeCheckRecognition,
+		Answer:     "check:e4",
+		AnswerData: map[string]string{"category": StatusCheck, "checkers": "e4"},
+	}
+	normal := &model.Question{
+		Type:       model.DrillTypeCheckRecognition,
+		Answer:     StatusNormal,
+		AnswerData: map[string]string{"category": StatusNormal},
+	}
+	squareColor := &model.Question{Type: model.DrillTypeSquareColor, Target: "a1", Answer: "dark"}
+	knightPath := &model.Question{
+		Type:       model.DrillTypeKnightDistance,
+		Answer:     "c2",
+		Metadata:   map[string]string{"category": KnightPath, "from_square": "a1", "to_square": "c2"},
+		AnswerData: map[string]string{"distance": "1", "route": "c2"},
+	}
+
+	// Ra8 gives check; Nxf3 captures; two rooks can reach d1; a8=Q checks
+	playRook := moveQuestion("play_san", "4k3/8/8/8/8/8/8/R3K3 w - - 0 1", "a1a8", "a1a8")
+	writeCapture := moveQuestion("write_san", "4k3/8/8/8/8/5p2/8/4K1N1 w - - 0 1", "g1f3", "Nxf3")
+	writeRook := moveQuestion("write_san", "4k3/8/8/8/8/8/4K3/R6R w - - 0 1", "a1d1", "Rad1")
+	writePromotion := moveQuestion("write_san", "4k3/P7/8/8/8/8/8/4K3 w - - 0 1", "a7a8q", "a8=Q+")
+
+	tests := []struct {
+		name     string
+		question *model.Question
+		answer   string
+		correct  bool
+		score    float64
+	}{
+		{"piece movement, every square", pieceMovement, "a2 B2 d4 c3", true, 1},
+		{"piece movement, half the squares", pieceMovement, "b2,c3", false, 2.0 / 3},
+		{"piece movement, half right with wrong extras", pieceMovement, "b2,c3,b1,h8", false, 0.5},
+		{"piece movement, nothing", pieceMovement, "", false, 0},
+
+		{"check with its checker", check, "check:e4", true, 1},
+		{"check by its symbol", check, "+:e4", true, 1},
+		{"check without the checker", check, "check", false, 0.5},
+		{"check called mate", check, "mate", false, 0},
+		{"normal", normal, "normal", true, 1},
+		{"normal as none", normal, "none", true, 1},
+		{"blank classification", normal, "", false, 0},
+
+		{"square color by letter", squareColor, "b", true, 1},
+		{"square color wrong", squareColor, "light", false, 0},
+
+		{"shortest knight route", knightPath, "a1-c2", true, 1},
+		{"longer knight route", knightPath, "b3,d4,c2", false, 1.0 / 3},
+		{"broken knight route", knightPath, "b3,c2", false, 0},
+
+		{"played by board click", playRook, "a1a8", true, 1},
+		{"played as SAN without check", playRook, "Ra8", true, 1},
+		{"played as SAN with check", playRook, "Ra8+", true, 1},
+		{"played as SAN claiming mate", playRook, "Ra8#", false, 0},
+		{"played a different move", playRook, "Ra7", false, 0},
+
+		{"written capture", writeCapture, "Nxf3", true, 1},
+		{"written capture without x", writeCapture, "Nf3", false, 0},
+		{"written capture claiming check", writeCapture, "Nxf3+", false, 0},
+		{"written capture as UCI", writeCapture, "g1f3", false, 0},
+		{"written disambiguation", writeRook, "Rad1", true, 1},
+		{"written without disambiguation", writeRook, "Rd1", false, 0},
+		{"written with a rank instead of a file", writeRook, "R1d1", false, 0},
+		{"written promotion", writePromotion, "a8=Q+", true, 1},
+		{"written promotion without = or check", writePromotion, "a8Q", true, 1},
+		{"written promotion to the wrong piece", writePromotion, "a8=R+", false, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gradeAnswer(tt.question, tt.answer)
+			if g.correct() != tt.correct {
+				t.Errorf("correct = %v, want %v (%s)", g.correct(), tt.correct, g.explanation)
+			}
+			if math.Abs(g.score-tt.score) > 1e-9 {
+				t.Errorf("score = %v, want %v", g.score, tt.score)
+			}
+		})
+	}
+}
+
+func TestBlankClassificationIsNotNormal(t *testing.T) {
+	question := &model.Question{
+		Type:       model.DrillTypeCheckRecognition,
+		Answer:     StatusNormal,
+		AnswerData: map[string]string{"category": StatusNormal},
+	}
+	if g := gradeAnswer(question, "  "); g.metadata.Classification != "" {
+		t.Errorf("classification = %q, want none recorded", g.metadata.Classification)
+	}
+}
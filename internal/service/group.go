@@ -0,0 +1,217 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/abdul-hamid-achik/chessdrill/internal/model"
+	"github.com/abdul-hamid-achik/chessdrill/internal/repository"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+var (
+	ErrGroupNotFound    = errors.New("group not found")
+	ErrNotGroupMember   = errors.New("not a member of this group")
+	ErrAlreadyInGroup   = errors.New("already a member of this group")
+	ErrNotCoach         = errors.New("only coaches can manage groups")
+	ErrOwnGroup         = errors.New("coaches can't join their own group")
+	ErrInvalidGroupName = errors.New("group name must be 1 to 60 characters")
+)
+
+const (
+	groupJoinCodeLength = 8
+	maxGroupNameLength  = 60
+	// groupDashboardSessions is the number of recent sessions shown per
+	// student on a group dashboard
+	groupDashboardSessions = 5
+	// studentReportSessions is the number shown on a student's own page
+	studentReportSessions = 20
+	// joinCodeAttempts bounds retries when a new code is already taken
+	joinCodeAttempts = 5
+)
+
+// GroupService manages coaches' groups. A coach can read a student's stats
+// and sessions only through a group the student is currently a member of,
+// so leaving a group revokes the coach's access at once.
+type GroupService struct {
+	groupRepo      *repository.GroupRepository
+	assignmentRepo *repository.AssignmentRepository
+	statsService   *StatsService
+}
+
+func NewGroupService(groupRepo *repository.GroupRepository, assignmentRepo *repository.AssignmentRepository, statsService *StatsService) *GroupService {
+	return &GroupService{
+		groupRepo:      groupRepo,
+		assignmentRepo: assignmentRepo,
+		statsService:   statsService,
+	}
+}
+
+// CreateGroup creates a group with a fresh join code
+func (s *GroupService) CreateGroup(ctx context.Context, coach *model.User, name string) (*model.Group, error) {
+	if coach.Role != model.RoleCoach {
+		return nil, ErrNotCoach
+	}
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > maxGroupNameLength {
+		return nil, ErrInvalidGroupName
+	}
+
+	for range joinCodeAttempts {
+		group := model.NewGroup(coach.ID, name, newCode(groupJoinCodeLength))
+		err := s.groupRepo.Create(ctx, group)
+		if errors.Is(err, repository.ErrJoinCodeExists) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return group, nil
+	}
+	return nil, repository.ErrJoinCodeExists
+}
+
+// CoachGroups returns the groups a coach runs
+func (s *GroupService) CoachGroups(ctx context.Context, coachID bson.ObjectID) ([]model.Group, error) {
+	return s.groupRepo.FindByCoach(ctx, coachID)
+}
+
+// MemberGroups returns the groups a user has joined
+func (s *GroupService) MemberGroups(ctx context.Context, userID bson.ObjectID) ([]model.Group, error) {
+	return s.groupRepo.FindByMember(ctx, userID)
+}
+
+// Join adds the user to the group with a join code
+func (s *GroupService) Join(ctx context.Context, user *model.User, code string) (*model.Group, error) {
+	group, err := s.groupRepo.FindByJoinCode(ctx, strings.ToUpper(strings.TrimSpace(code)))
+	if err != nil {
+		return nil, groupError(err)
+	}
+	if group.CoachID == user.ID {
+		return nil, ErrOwnGroup
+	}
+
+	member := model.GroupMember{UserID: user.ID, Username: user.Username, JoinedAt: time.Now()}
+	if err := s.groupRepo.AddMember(ctx, group.ID, member); err != nil {
+		return nil, groupError(err)
+	}
+	group.Members = append(group.Members, member)
+	return group, nil
+}
+
+// Leave takes the user out of a group, revoking its coach's access
+func (s *GroupService) Leave(ctx context.Context, userID, groupID bson.ObjectID) error {
+	return groupError(s.groupRepo.RemoveMember(ctx, groupID, userID))
+}
+
+// LeaveAll takes the user out of every group, revoking all coach access
+func (s *GroupService) LeaveAll(ctx context.Context, userID bson.ObjectID) error {
+	return s.groupRepo.RemoveMemberFromAll(ctx, userID)
+}
+
+// DeleteGroup removes one of the coach's groups and its assignments
+func (s *GroupService) DeleteGroup(ctx context.Context, coach *model.User, groupID bson.ObjectID) error {
+	if coach.Role != model.RoleCoach {
+		return ErrNotCoach
+	}
+	if err := s.groupRepo.Delete(ctx, coach.ID, groupID); err != nil {
+		return groupError(err)
+	}
+	return s.assignmentRepo.DeleteByGroup(ctx, groupID)
+}
+
+// coachGroup loads a group the coach runs; any other group is reported as
+// not found
+func (s *GroupService) coachGroup(ctx context.Context, coach *model.User, groupID bson.ObjectID) (*model.Group, error) {
+	if coach.Role != model.RoleCoach {
+		return nil, ErrNotCoach
+	}
+	group, err := s.groupRepo.FindCoachGroup(ctx, coach.ID, groupID)
+	if err != nil {
+		return nil, groupError(err)
+	}
+	return group, nil
+}
+
+// groupError translates the repository's group errors
+func groupError(err error) error {
+	switch {
+	case errors.Is(err, repository.ErrGroupNotFound):
+		return ErrGroupNotFound
+	case errors.Is(err, repository.ErrNotGroupMember):
+		return ErrNotGroupMember
+	case errors.Is(err, repository.ErrAlreadyInGroup):
+		return ErrAlreadyInGroup
+	default:
+		return err
+	}
+}
+
+// Dashboard returns a coach's group with each member's overall stats and
+// latest sessions
+func (s *GroupService) Dashboard(ctx context.Context, coach *model.User, groupID bson.ObjectID) (*model.Group, []model.StudentOverview, error) {
+	group, err := s.coachGroup(ctx, coach, groupID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	students := make([]model.StudentOverview, 0, len(group.Members))
+	for _, member := range group.Members {
+		stats, err := s.statsService.GetOverallStats(ctx, member.UserID)
+		if err != nil {
+			return nil, nil, err
+		}
+		sessions, err := s.statsService.RecentSessions(ctx, member.UserID, groupDashboardSessions)
+		if err != nil {
+			return nil, nil, err
+		}
+		students = append(students, model.StudentOverview{
+			Member:         member,
+			Stats:          stats,
+			RecentSessions: sessions,
+		})
+	}
+	return group, students, nil
+}
+
+// Student returns a member's stats, heatmap and sessions to the coach of
+// their group. It fails with ErrNotGroupMember unless the student is in
+// the group right now.
+func (s *GroupService) Student(ctx context.Context, coach *model.User, groupID, studentID bson.ObjectID) (*model.StudentReport, error) {
+	group, err := s.coachGroup(ctx, coach, groupID)
+	if err != nil {
+		return nil, err
+	}
+	var member *model.GroupMember
+	for i := range group.Members {
+		if group.Members[i].UserID == studentID {
+			member = &group.Members[i]
+		}
+	}
+	if member == nil {
+		return nil, ErrNotGroupMember
+	}
+
+	stats, err := s.statsService.GetOverallStats(ctx, studentID)
+	if err != nil {
+		return nil, err
+	}
+	heatmap, err := s.statsService.GetHeatmapData(ctx, studentID)
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := s.statsService.RecentSessions(ctx, studentID, studentReportSessions)
+	if err != nil {
+		return nil, err
+	}
+	return &model.StudentReport{
+		Group:          group,
+		Member:         *member,
+		Stats:          stats,
+		Heatmap:        heatmap,
+		RecentSessions: sessions,
+	}, nil
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/abdul-hamid-achik/chessdrill/internal/chess"
+	"github.com/abdul-hamid-achik/chessdrill/internal/model"
+)
+
+// Knight drill question kinds
+const (
+	KnightDistance = "distance"
+	KnightPath     = "path"
+)
+
+// generateKnightQuestion picks two squares, the first from weights, and
+// asks for the number of knight moves between them or, on hard difficulty,
+// for a shortest route. Easy questions stay within three moves.
+func (s *DrillService) generateKnightQuestion(rng RandomSource, difficulty model.Difficulty, weights *SquareWeights) *model.Question {
+	from, _ := chess.ParseSquare(weights.Pick(rng))
+	var to chess.Square
+	var distance int
+	for {
+		to = chess.Square(rng.IntN(64))
+		distance = chess.KnightDistances(from)[to]
+		if distance > 0 && (difficulty != model.DifficultyEasy || distance <= 3) {
+			break
+		}
+	}
+
+	var route []string
+	for _, sq := range chess.KnightPath(from, to) {
+		route = append(route, sq.String())
+	}
+
+	q := &model.Question{
+		Type:   model.DrillTypeKnightDistance,
+		Target: from.String() + "-" + to.String(),
+		FEN:    s.generateSinglePieceFEN("knight", from.String()),
+		Metadata: map[string]string{
+			"piece_type":  "knight",
+			"piece_color": "white",
+			"from_square": from.String(),
+			"to_square":   to.String(),
+		},
+		AnswerData: map[string]string{
+			"distance": strconv.Itoa(distance),
+			"route":    strings.Join(route, ","),
+		},
+	}
+
+	if difficulty == model.DifficultyHard {
+		q.Metadata["category"] = KnightPath
+		q.Prompt = fmt.Sprintf("Click a shortest knight route from %s to %s.", from, to)
+		q.Answer = strings.Join(route, ",")
+	} else {
+		q.Metadata["category"] = KnightDistance
+		q.Prompt = fmt.Sprintf("How many moves does a knight need from %s to %s?", from, to)
+		q.Answer = strconv.Itoa(distance)
+	}
+	return q
+}
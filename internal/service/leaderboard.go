@@ -0,0 +1,283 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/abdul-hamid-achik/chessdrill/internal/model"
+	"github.com/abdul-hamid-achik/chessdrill/internal/repository"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+var ErrInvalidLeaderboard = errors.New("invalid leaderboard")
+
+const (
+	// leaderboardSize is the number of users kept per leaderboard
+	leaderboardSize = 100
+	// MinLeaderboardAnswers is the number of correct answers a user needs
+	// to be ranked by average response time
+	MinLeaderboardAnswers = 50
+	// leaderboardTimeLimitSec is the only time limit ranked for timed
+	// scores, so they are comparable
+	leaderboardTimeLimitSec = defaultTimeLimitSec
+	// leaderboardDifficulty is the only difficulty ranked, since harder
+	// questions are answered slower and less often right
+	leaderboardDifficulty = model.DifficultyEasy
+)
+
+var (
+	leaderboardMetrics      = []model.LeaderboardMetric{model.LeaderboardTimedScore, model.LeaderboardBestStreak, model.LeaderboardFastestAvg}
+	leaderboardPeriods      = []model.LeaderboardPeriod{model.LeaderboardWeek, model.LeaderboardMonth, model.LeaderboardAll}
+	leaderboardPerspectives = []string{"white", "black"}
+)
+
+type LeaderboardService struct {
+	leaderboardRepo  *repository.LeaderboardRepository
+	attemptRepo      *repository.AttemptRepository
+	drillSessionRepo *repository.DrillSessionRepository
+	userRepo         *repository.UserRepository
+}
+
+func NewLeaderboardService(leaderboardRepo *repository.LeaderboardRepository, attemptRepo *repository.AttemptRepository, drillSessionRepo *repository.DrillSessionRepository, userRepo *repository.UserRepository) *LeaderboardService {
+	return &LeaderboardService{
+		leaderboardRepo:  leaderboardRepo,
+		attemptRepo:      attemptRepo,
+		drillSessionRepo: drillSessionRepo,
+		userRepo:         userRepo,
+	}
+}
+
+// periodStart returns when a period began, or the zero time for all time
+func periodStart(period model.LeaderboardPeriod, now time.Time) time.Time {
+	switch period {
+	case model.LeaderboardWeek:
+		return now.AddDate(0, 0, -7)
+	case model.LeaderboardMonth:
+		return now.AddDate(0, -1, 0)
+	default:
+		return time.Time{}
+	}
+}
+
+// Run refreshes the leaderboards now and then every interval until ctx is
+// done
+func (s *LeaderboardService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := s.Refresh(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("Failed to refresh leaderboards: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Refresh recomputes every leaderboard: each metric and period, for every
+// drill type and perspective as well as across all of them
+func (s *LeaderboardService) Refresh(ctx context.Context) error {
+	now := time.Now()
+	for _, period := range leaderboardPeriods {
+		since := periodStart(period, now)
+		for _, metric := range leaderboardMetrics {
+			rows, err := s.leaderboardRows(ctx, metric, since)
+			if err != nil {
+				return err
+			}
+			boards, err := s.buildLeaderboards(ctx, metric, rows)
+			if err != nil {
+				return err
+			}
+			for _, board := range boards {
+				board.Period = period
+				board.ComputedAt = now
+				if err := s.leaderboardRepo.Save(ctx, board); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (s *LeaderboardService) leaderboardRows(ctx context.Context, metric model.LeaderboardMetric, since time.Time) ([]model.LeaderboardRow, error) {
+	switch metric {
+	case model.LeaderboardTimedScore:
+		return s.drillSessionRepo.GetBestTimedScoreRows(ctx, since, leaderboardTimeLimitSec)
+	case model.LeaderboardBestStreak:
+		return s.drillSessionRepo.GetBestStreakRows(ctx, since)
+	default:
+		return s.attemptRepo.GetResponseTimeRows(ctx, since)
+	}
+}
+
+// rankedSession reports whether a session started with opts can be ranked.
+// A chosen seed can be replayed until its answers are memorised, and every
+// challenge attempt of a day shares its seed.
+func rankedSession(opts SessionOptions) bool {
+	return opts.Seed == 0 && opts.ChallengeDay == "" &&
+		(opts.Difficulty == "" || opts.Difficulty == leaderboardDifficulty)
+}
+
+// leaderboardKey identifies a leaderboard within a metric and period
+type leaderboardKey struct {
+	drillType   model.DrillType
+	perspective string
+}
+
+// buildLeaderboards rolls rows up into the leaderboard of every drill type
+// and perspective combination, including the "all" ones, and ranks them.
+// Users who opted out are left out.
+func (s *LeaderboardService) buildLeaderboards(ctx context.Context, metric model.LeaderboardMetric, rows []model.LeaderboardRow) ([]*model.Leaderboard, error) {
+	totals := make(map[leaderboardKey]map[bson.ObjectID]*model.LeaderboardEntry)
+	for _, dt := range append([]model.DrillType{""}, drillTypes...) {
+		for _, p := range append([]string{""}, leaderboardPerspectives...) {
+			totals[leaderboardKey{dt, p}] = make(map[bson.ObjectID]*model.LeaderboardEntry)
+		}
+	}
+
+	for _, row := range rows {
+		keys := []leaderboardKey{
+			{row.DrillType, row.Perspective},
+			{row.DrillType, ""},
+			{"", row.Perspective},
+			{"", ""},
+		}
+		for _, key := range keys {
+			users, ok := totals[key]
+			if !ok {
+				continue
+			}
+			entry, ok := users[row.UserID]
+			if !ok {
+				users[row.UserID] = &model.LeaderboardEntry{UserID: row.UserID, Value: row.Value, Count: row.Count}
+				continue
+			}
+			// Averages are summed and divided below; the rest keep the best
+			if metric == model.LeaderboardFastestAvg {
+				entry.Value += row.Value
+			} else {
+				entry.Value = max(entry.Value, row.Value)
+			}
+			entry.Count += row.Count
+		}
+	}
+
+	users, err := s.leaderboardUsers(ctx, rows)
+	if err != nil {
+		return nil, err
+	}
+
+	boards := make([]*model.Leaderboard, 0, len(totals))
+	for key, byUser := range totals {
+		entries := make([]model.LeaderboardEntry, 0, len(byUser))
+		for id, entry := range byUser {
+			user, ok := users[id]
+			if !ok || user.Preferences.HideFromLeaderboards {
+				continue
+			}
+			if metric == model.LeaderboardFastestAvg {
+				if entry.Count < MinLeaderboardAnswers {
+					continue
+				}
+				entry.Value /= float64(entry.Count)
+			}
+			entry.Username = user.Username
+			entries = append(entries, *entry)
+		}
+		rankEntries(metric, entries)
+		if len(entries) > leaderboardSize {
+			entries = entries[:leaderboardSize]
+		}
+
+		boards = append(boards, &model.Leaderboard{
+			Metric:      metric,
+			DrillType:   key.drillType,
+			Perspective: key.perspective,
+			Entries:     entries,
+		})
+	}
+	return boards, nil
+}
+
+// rankEntries sorts entries best first: lowest for averages, highest
+// otherwise, then by username so ties are stable
+func rankEntries(metric model.LeaderboardMetric, entries []model.LeaderboardEntry) {
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Value != entries[j].Value {
+			if metric == model.LeaderboardFastestAvg {
+				return entries[i].Value < entries[j].Value
+			}
+			return entries[i].Value > entries[j].Value
+		}
+		return entries[i].Username < entries[j].Username
+	})
+}
+
+// leaderboardUsers loads the users the rows belong to
+func (s *LeaderboardService) leaderboardUsers(ctx context.Context, rows []model.LeaderboardRow) (map[bson.ObjectID]model.User, error) {
+	var ids []bson.ObjectID
+	seen := make(map[bson.ObjectID]bool)
+	for _, row := range rows {
+		if !seen[row.UserID] {
+			seen[row.UserID] = true
+			ids = append(ids, row.UserID)
+		}
+	}
+	return s.usersByID(ctx, ids)
+}
+
+func (s *LeaderboardService) usersByID(ctx context.Context, ids []bson.ObjectID) (map[bson.ObjectID]model.User, error) {
+	users := make(map[bson.ObjectID]model.User, len(ids))
+	if len(ids) == 0 {
+		return users, nil
+	}
+	found, err := s.userRepo.FindByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, user := range found {
+		users[user.ID] = user
+	}
+	return users, nil
+}
+
+// Get returns a precomputed leaderboard. An empty drill type or perspective
+// selects the one across all of them. Users who opted out since it was
+// computed are left out.
+func (s *LeaderboardService) Get(ctx context.Context, metric model.LeaderboardMetric, drillType model.DrillType, perspective string, period model.LeaderboardPeriod) (*model.Leaderboard, error) {
+	if !slices.Contains(leaderboardMetrics, metric) || !slices.Contains(leaderboardPeriods, period) ||
+		(drillType != "" && !slices.Contains(drillTypes, drillType)) ||
+		(perspective != "" && !slices.Contains(leaderboardPerspectives, perspective)) {
+		return nil, ErrInvalidLeaderboard
+	}
+
+	board, err := s.leaderboardRepo.Find(ctx, metric, drillType, perspective, period)
+	if errors.Is(err, repository.ErrLeaderboardNotFound) {
+		// Not computed yet
+		return &model.Leaderboard{Metric: metric, DrillType: drillType, Perspective: perspective, Period: period}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]bson.ObjectID, len(board.Entries))
+	for i, entry := range board.Entries {
+		ids[i] = entry.UserID
+	}
+	users, err := s.usersByID(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	board.Entries = slices.DeleteFunc(board.Entries, func(entry model.LeaderboardEntry) bool {
+		return users[entry.UserID].Preferences.HideFromLeaderboards
+	})
+	return board, nil
+}
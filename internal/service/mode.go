@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/abdul-hamid-achik/chessdrill/internal/model"
+)
+
+var ErrInvalidMode = errors.New("invalid session mode")
+
+// Default and maximum limits for each session mode
+const (
+	defaultTimeLimitSec  = 60
+	maxTimeLimitSec      = 600
+	defaultQuestionCount = 20
+	maxQuestionCount     = 200
+	defaultMaxMisses     = 3
+	maxMaxMisses         = 10
+)
+
+// timedGrace allows for the round trip of an answer submitted just before
+// a timed session runs out
+const timedGrace = 2 * time.Second
+
+// NormalizeMode fills in the default limit for a mode, caps it, and clears
+// the limits that do not apply. An empty mode is an open session.
+func NormalizeMode(settings model.ModeSettings) (model.ModeSettings, error) {
+	normalized := model.ModeSettings{Mode: settings.Mode}
+	switch settings.Mode {
+	case "", model.SessionModeOpen:
+		normalized.Mode = model.SessionModeOpen
+	case model.SessionModeTimed:
+		normalized.TimeLimitSec = clampLimit(settings.TimeLimitSec, defaultTimeLimitSec, maxTimeLimitSec)
+	case model.SessionModeCount:
+		normalized.QuestionCount = clampLimit(settings.QuestionCount, defaultQuestionCount, maxQuestionCount)
+	case model.SessionModeSurvival:
+		normalized.MaxMisses = clampLimit(settings.MaxMisses, defaultMaxMisses, maxMaxMisses)
+	case model.SessionModeReview:
+	default:
+		return model.ModeSettings{}, ErrInvalidMode
+	}
+	return normalized, nil
+}
+
+func clampLimit(n, fallback, limit int) int {
+	if n <= 0 {
+		return fallback
+	}
+	return min(n, limit)
+}
+
+// timeUp reports whether a timed session ran out, allowing for timedGrace
+func timeUp(session *model.DrillSession, now time.Time) bool {
+	deadline := session.Deadline()
+	return !deadline.IsZero() && now.After(deadline.Add(timedGrace))
+}
+
+// expired reports whether a timed session ran out without being ended, as
+// when the user leaves before the clock does
+func expired(session *model.DrillSession, now time.Time) bool {
+	return session.EndedAt == nil && timeUp(session, now)
+}
+
+// endTime returns when a session ending now ends: a timed session that ran
+// out ends at its deadline, however long after it is closed
+func endTime(session *model.DrillSession, now time.Time) time.Time {
+	if timeUp(session, now) {
+		return session.Deadline()
+	}
+	return now
+}
+
+// CloseExpiredSessions ends every timed session that ran out without being
+// ended, so its score reaches summaries and leaderboards
+func (s *DrillService) CloseExpiredSessions(ctx context.Context) error {
+	sessions, err := s.drillSessionRepo.FindOpenTimed(ctx, time.Now().Add(-timedGrace))
+	if err != nil {
+		return err
+	}
+	return s.closeExpired(ctx, sessions)
+}
+
+// closeExpired ends the sessions among sessions that ran out, updating them
+// in place
+func (s *DrillService) closeExpired(ctx context.Context, sessions []model.DrillSession) error {
+	now := time.Now()
+	for i := range sessions {
+		if !expired(&sessions[i], now) {
+			continue
+		}
+		_, err := s.endSession(ctx, &sessions[i])
+		if errors.Is(err, ErrSessionEnded) {
+			// Ended meanwhile by an answer or the user
+			var ended *model.DrillSession
+			if ended, err = s.drillSessionRepo.FindByID(ctx, sessions[i].ID); err == nil {
+				sessions[i] = *ended
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RunExpiry closes expired timed sessions now and then every interval until
+// ctx is done
+func (s *DrillService) RunExpiry(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := s.CloseExpiredSessions(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("Failed to close expired sessions: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// modeFinished reports whether a session has reached its mode's limit
+func (s *DrillService) modeFinished(ctx context.Context, session *model.DrillSession) (bool, error) {
+	switch session.Mode {
+	case model.SessionModeTimed:
+		return !time.Now().Before(session.Deadline()), nil
+	case model.SessionModeCount, model.SessionModeSurvival:
+		summary, err := s.attemptRepo.GetSessionSummary(ctx, session.ID)
+		if err != nil {
+			return false, err
+		}
+		return limitReached(session, summary), nil
+	case model.SessionModeReview:
+		due, err := s.reviewService.CountDue(ctx, session.UserID, session.DrillType)
+		return due == 0, err
+	default:
+		return false, nil
+	}
+}
+
+// limitReached reports whether a count session has had all its questions
+// or a survival session all its misses
+func limitReached(session *model.DrillSession, summary *model.DrillSessionSummary) bool {
+	if session.Mode == model.SessionModeCount {
+		return summary.TotalAttempts >= session.QuestionCount
+	}
+	return summary.TotalAttempts-summary.Correct >= session.MaxMisses
+}
+
+// summarize completes a session's summary with its mode score and region
+func summarize(session *model.DrillSession, summary *model.DrillSessionSummary) {
+	applyModeScore(session, summary)
+	if session.Region != nil {
+		summary.Region = session.Region.Label
+	}
+}
+
+// applyModeScore records the mode-specific score of a session on its summary
+func applyModeScore(session *model.DrillSession, summary *model.DrillSessionSummary) {
+	switch session.Mode {
+	case model.SessionModeTimed:
+		summary.ModeScore = summary.Credit
+		summary.ModeLimit = session.TimeLimitSec
+	case model.SessionModeCount:
+		summary.ModeScore = summary.Credit
+		summary.ModeLimit = session.QuestionCount
+	case model.SessionModeSurvival:
+		summary.ModeScore = float64(summary.Correct)
+		summary.ModeLimit = session.MaxMisses
+	case model.SessionModeReview:
+		summary.ModeScore = float64(summary.Correct)
+		summary.ModeLimit = summary.TotalAttempts
+	case model.SessionModeRace:
+		summary.ModeScore = float64(summary.Correct)
+		summary.ModeLimit = session.QuestionCount
+	default:
+		return
+	}
+	summary.Mode = session.Mode
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/abdul-hamid-achik/chessdrill/internal/model"
+)
+
+func TestNormalizeMode(t *testing.T) {
+	tests := []struct {
+		name string
+		in   model.ModeSettings
+		want model.ModeSettings
+		err  error
+	}{
+		{"empty is open", model.ModeSettings{}, model.ModeSettings{Mode: model.SessionModeOpen}, nil},
+		{"open drops limits", model.ModeSettings{Mode: model.SessionModeOpen, TimeLimitSec: 30}, model.ModeSettings{Mode: model.SessionModeOpen}, nil},
+		{"timed default", model.ModeSettings{Mode: model.SessionModeTimed}, model.ModeSettings{Mode: model.SessionModeTimed, TimeLimitSec: defaultTimeLimitSec}, nil},
+		{"timed capped", model.ModeSettings{Mode: model.SessionModeTimed, TimeLimitSec: 5000}, model.ModeSettings{Mode: model.SessionModeTimed, TimeLimitSec: maxTimeLimitSec}, nil},
+		{"count keeps its limit only", model.ModeSettings{Mode: model.SessionModeCount, QuestionCount: 10, MaxMisses: 2}, model.ModeSettings{Mode: model.SessionModeCount, QuestionCount: 10}, nil},
+		{"survival default", model.ModeSettings{Mode: model.SessionModeSurvival, MaxMisses: -1}, model.ModeSettings{Mode: model.SessionModeSurvival, MaxMisses: defaultMaxMisses}, nil},
+		{"review", model.ModeSettings{Mode: model.SessionModeReview}, model.ModeSettings{Mode: model.SessionModeReview}, nil},
+		{"unknown", model.ModeSettings{Mode: "marathon"}, model.ModeSettings{}, ErrInvalidMode},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeMode(tt.in)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("error = %v, want %v", err, tt.err)
+			}
+			if got != tt.want {
+				t.Errorf("NormalizeMode = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLimitReached(t *testing.T) {
+	survival := &model.DrillSession{ModeSettings: model.ModeSettings{Mode: model.SessionModeSurvival, MaxMisses: 3}}
+	count := &model.DrillSession{ModeSettings: model.ModeSettings{Mode: model.SessionModeCount, QuestionCount: 20}}
+
+	tests := []struct {
+		name    string
+		session *model.DrillSession
+		total   int
+		correct int
+		want    bool
+	}{
+		{"survival with lives left", survival, 12, 10, false},
+		{"survival ends on the last miss", survival, 13, 10, true},
+		{"survival ends however many were right", survival, 3, 0, true},
+		{"count short of its questions", count, 19, 5, false},
+		{"count ends on its last question", count, 20, 20, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			summary := &model.DrillSessionSummary{TotalAttempts: tt.total, Correct: tt.correct}
+			if got := limitReached(tt.session, summary); got != tt.want {
+				t.Errorf("limitReached = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyModeScore(t *testing.T) {
+	summary := model.DrillSessionSummary{TotalAttempts: 10, Correct: 6, Credit: 7.5}
+	tests := []struct {
+		settings  model.ModeSettings
+		wantScore float64
+		wantLimit int
+	}{
+		{model.ModeSettings{Mode: model.SessionModeTimed, TimeLimitSec: 60}, 7.5, 60},
+		{model.ModeSettings{Mode: model.SessionModeCount, QuestionCount: 10}, 7.5, 10},
+		{model.ModeSettings{Mode: model.SessionModeSurvival, MaxMisses: 3}, 6, 3},
+		{model.ModeSettings{Mode: model.SessionModeReview}, 6, 10},
+		{model.ModeSettings{Mode: model.SessionModeOpen}, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.settings.Mode), func(t *testing.T) {
+			got := summary
+			applyModeScore(&model.DrillSession{ModeSettings: tt.settings}, &got)
+			if got.ModeScore != tt.wantScore || got.ModeLimit != tt.wantLimit {
+				t.Errorf("score %v of %d, want %v of %d", got.ModeScore, got.ModeLimit, tt.wantScore, tt.wantLimit)
+			}
+		})
+	}
+}
+
+func TestExpiredTimedSession(t *testing.T) {
+	now := time.Now()
+	timed := func(startedAgo time.Duration) model.DrillSession {
+		return model.DrillSession{
+			StartedAt:    now.Add(-startedAgo),
+			ModeSettings: model.ModeSettings{Mode: model.SessionModeTimed, TimeLimitSec: 60},
+		}
+	}
+	ended := timed(5 * time.Minute)
+	ended.EndedAt = &now
+
+	tests := []struct {
+		name    string
+		session model.DrillSession
+		expired bool
+	}{
+		{"past its limit and grace", timed(60*time.Second + timedGrace + time.Second), true},
+		{"left hours ago", timed(3 * time.Hour), true},
+		{"within the grace", timed(61 * time.Second), false},
+		{"still running", timed(30 * time.Second), false},
+		{"already ended", ended, false},
+		{"open session", model.DrillSession{StartedAt: now.Add(-3 * time.Hour), ModeSettings: model.ModeSettings{Mode: model.SessionModeOpen}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := tt.session
+			if got := expired(&session, now); got != tt.expired {
+				t.Fatalf("expired = %v, want %v", got, tt.expired)
+			}
+			if !tt.expired {
+				return
+			}
+
+			if got := endTime(&session, now); !got.Equal(session.Deadline()) {
+				t.Errorf("ends at %v, want the deadline %v", got, session.Deadline())
+			}
+			summary := &model.DrillSessionSummary{TotalAttempts: 9, Correct: 7, Credit: 7.5}
+			summarize(&session, summary)
+			if summary.Mode != model.SessionModeTimed || summary.ModeScore != 7.5 || summary.ModeLimit != 60 {
+				t.Errorf("summary = %+v, want timed mode score 7.5 of 60s", summary)
+			}
+		})
+	}
+}
+
+func TestEndTimeBeforeDeadline(t *testing.T) {
+	now := time.Now()
+	session := &model.DrillSession{
+		StartedAt:    now.Add(-20 * time.Second),
+		ModeSettings: model.ModeSettings{Mode: model.SessionModeTimed, TimeLimitSec: 60},
+	}
+	if got := endTime(session, now); !got.Equal(now) {
+		t.Errorf("a session ended early ends at %v, want now %v", got, now)
+	}
+}
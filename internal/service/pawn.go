@@ -0,0 +1,145 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/abdul-hamid-achik/chessdrill/internal/chess"
+	"github.com/abdul-hamid-achik/chessdrill/internal/model"
+)
+
+// Pawn sub-rules exercised by the pawn rules drill
+const (
+	PawnRulePush       = "push"
+	PawnRuleDoublePush = "double_push"
+	PawnRuleCapture    = "capture"
+	PawnRuleEnPassant  = "en_passant"
+	PawnRulePromotion  = "promotion"
+)
+
+var pawnRules = []string{PawnRulePush, PawnRuleDoublePush, PawnRuleCapture, PawnRuleEnPassant, PawnRulePromotion}
+
+// capturablePieces are enemy pieces placed where a pawn can take them
+var capturablePieces = []chess.PieceType{chess.Knight, chess.Bishop, chess.Rook, chess.Queen}
+
+// relativeRank converts a rank counted from the given side (0 = its back
+// rank) into an absolute board rank
+func relativeRank(c chess.Color, rank int) int {
+	if c == chess.White {
+		return rank
+	}
+	return 7 - rank
+}
+
+func pawnDirection(c chess.Color) int {
+	if c == chess.White {
+		return 1
+	}
+	return -1
+}
+
+// generatePawnRulesQuestion builds a position around a single pawn of either
+// color that exercises one pawn rule, and asks for its destination squares
+func (s *DrillService) generatePawnRulesQuestion(rng RandomSource) *model.Question {
+	rule := pawnRules[rng.IntN(len(pawnRules))]
+	color := chess.Color(rng.IntN(2))
+	enemy := color.Other()
+	dir := pawnDirection(color)
+
+	pos := chess.NewPosition()
+	pos.Turn = color
+	file := rng.IntN(8)
+	note := ""
+
+	randomEnemy := func() chess.Piece {
+		return chess.Piece{Type: capturablePieces[rng.IntN(len(capturablePieces))], Color: enemy}
+	}
+	maybeBlock := func(from chess.Square, chance int) {
+		if rng.IntN(100) < chance {
+			if sq, ok := from.Offset(0, dir); ok {
+				pos.SetPiece(sq, chess.Piece{Type: chess.Knight, Color: chess.Color(rng.IntN(2))})
+			}
+		}
+	}
+
+	var from chess.Square
+	switch rule {
+	case PawnRulePush:
+		from = chess.NewSquare(file, relativeRank(color, 2+rng.IntN(4)))
+		maybeBlock(from, 35)
+
+	case PawnRuleDoublePush:
+		from = chess.NewSquare(file, relativeRank(color, 1))
+		if rng.IntN(100) < 40 {
+			sq, _ := from.Offset(0, dir*(1+rng.IntN(2)))
+			pos.SetPiece(sq, chess.Piece{Type: chess.Bishop, Color: chess.Color(rng.IntN(2))})
+		}
+
+	case PawnRuleCapture:
+		from = chess.NewSquare(file, relativeRank(color, 2+rng.IntN(4)))
+		for _, df := range []int{-1, 1} {
+			sq, ok := from.Offset(df, dir)
+			if !ok {
+				continue
+			}
+			switch rng.IntN(3) {
+			case 0:
+				pos.SetPiece(sq, randomEnemy())
+			case 1:
+				pos.SetPiece(sq, chess.Piece{Type: chess.Knight, Color: color})
+			}
+		}
+		maybeBlock(from, 30)
+
+	case PawnRuleEnPassant:
+		from = chess.NewSquare(file, relativeRank(color, 4))
+		df := 1
+		if file == 7 || (file > 0 && rng.IntN(2) == 0) {
+			df = -1
+		}
+		victim, _ := from.Offset(df, 0)
+		pos.SetPiece(victim, chess.Piece{Type: chess.Pawn, Color: enemy})
+		origin := chess.NewSquare(victim.File(), relativeRank(enemy, 1))
+		if rng.IntN(100) < 70 {
+			pos.EnPassant, _ = victim.Offset(0, dir)
+			note = fmt.Sprintf(" %s just played %s-%s.", capitalize(enemy.String()), origin, victim)
+		} else {
+			note = fmt.Sprintf(" The %s pawn on %s did not move last turn.", enemy, victim)
+		}
+
+	case PawnRulePromotion:
+		from = chess.NewSquare(file, relativeRank(color, 6))
+		if rng.IntN(2) == 0 {
+			df := 1
+			if file == 7 || (file > 0 && rng.IntN(2) == 0) {
+				df = -1
+			}
+			sq, _ := from.Offset(df, dir)
+			pos.SetPiece(sq, randomEnemy())
+		}
+		maybeBlock(from, 30)
+	}
+
+	pos.SetPiece(from, chess.Piece{Type: chess.Pawn, Color: color})
+
+	var destinations []string
+	for _, sq := range chess.Destinations(pos.LegalMovesFrom(from)) {
+		destinations = append(destinations, sq.String())
+	}
+	sort.Strings(destinations)
+
+	return &model.Question{
+		Type:   model.DrillTypePawnRules,
+		Target: from.String(),
+		Prompt: fmt.Sprintf("Where can the %s pawn on %s move?%s", color, from, note),
+		FEN:    pos.FEN(),
+		Answer: strings.Join(destinations, ","),
+		Metadata: map[string]string{
+			"piece_type":  "pawn",
+			"piece_color": color.String(),
+			"from_square": from.String(),
+			"category":    rule,
+		},
+	}
+}
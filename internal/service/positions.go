@@ -0,0 +1,102 @@
+package service
+
+import (
+	"github.com/abdul-hamid-achik/chessdrill/internal/chess"
+)
+
+// randomPieceTypes is weighted towards pawns, as in real games
+var randomPieceTypes = []chess.PieceType{
+	chess.Pawn, chess.Pawn, chess.Pawn,
+	chess.Knight, chess.Bishop, chess.Rook, chess.Queen,
+}
+
+// randomPosition builds a random legal position with both kings and the
+// given number of extra pieces, in which the side to move has a legal move.
+// Castling rights are granted when a king and rook stand on their original
+// squares.
+func randomPosition(rng RandomSource, extraPieces int) *chess.Position {
+	for {
+		pos := randomBoard(rng, extraPieces)
+		if len(pos.LegalMoves()) > 0 {
+			return pos
+		}
+	}
+}
+
+// randomBoard builds a random valid position like randomPosition, but the
+// side to move may be checkmated or stalemated
+func randomBoard(rng RandomSource, extraPieces int) *chess.Position {
+	for {
+		pos := chess.NewPosition()
+
+		whiteKing := chess.Square(rng.IntN(64))
+		blackKing := chess.Square(rng.IntN(64))
+		if kingDistance(whiteKing, blackKing) < 2 {
+			continue
+		}
+		pos.SetPiece(whiteKing, chess.Piece{Type: chess.King, Color: chess.White})
+		pos.SetPiece(blackKing, chess.Piece{Type: chess.King, Color: chess.Black})
+
+		for placed := 0; placed < extraPieces; {
+			piece := chess.Piece{
+				Type:  randomPieceTypes[rng.IntN(len(randomPieceTypes))],
+				Color: chess.Color(rng.IntN(2)),
+			}
+			sq := chess.Square(rng.IntN(64))
+			if !pos.Piece(sq).IsEmpty() || (piece.Type == chess.Pawn && (sq.Rank() == 0 || sq.Rank() == 7)) {
+				continue
+			}
+			pos.SetPiece(sq, piece)
+			placed++
+		}
+
+		pos.Turn = chess.Color(rng.IntN(2))
+		pos.Castling = homeCastlingRights(pos)
+
+		if pos.Validate() != nil {
+			continue
+		}
+		return pos
+	}
+}
+
+// homeCastlingRights returns the castling rights consistent with the kings
+// and rooks that stand on their original squares
+func homeCastlingRights(pos *chess.Position) chess.CastlingRights {
+	rights := chess.NoCastling
+	for _, c := range []chess.Color{chess.White, chess.Black} {
+		rank := 0
+		kingside, queenside := chess.WhiteKingside, chess.WhiteQueenside
+		if c == chess.Black {
+			rank = 7
+			kingside, queenside = chess.BlackKingside, chess.BlackQueenside
+		}
+		if pos.Piece(chess.NewSquare(4, rank)) != (chess.Piece{Type: chess.King, Color: c}) {
+			continue
+		}
+		rook := chess.Piece{Type: chess.Rook, Color: c}
+		if pos.Piece(chess.NewSquare(7, rank)) == rook {
+			rights |= kingside
+		}
+		if pos.Piece(chess.NewSquare(0, rank)) == rook {
+			rights |= queenside
+		}
+	}
+	return rights
+}
+
+// kingDistance is the number of king moves between two squares
+func kingDistance(a, b chess.Square) int {
+	df := a.File() - b.File()
+	if df < 0 {
+		df = -df
+	}
+	dr := a.Rank() - b.Rank()
+	if dr < 0 {
+		dr = -dr
+	}
+	if df > dr {
+		return df
+	}
+	return dr
+}
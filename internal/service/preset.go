@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/abdul-hamid-achik/chessdrill/internal/model"
+	"github.com/abdul-hamid-achik/chessdrill/internal/repository"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+var (
+	ErrPresetNotFound = errors.New("preset not found")
+	ErrInvalidPreset  = errors.New("invalid preset")
+	ErrTooManyPresets = errors.New("too many presets")
+)
+
+const (
+	MaxPresets          = 20
+	maxPresetNameLength = 60
+)
+
+var (
+	perspectives = []string{"white", "black"}
+	inputMethods = []model.InputMethod{model.InputMethodType, model.InputMethodClick, model.InputMethodGrid, model.InputMethodBoardClick}
+	difficulties = []model.Difficulty{model.DifficultyEasy, model.DifficultyMedium, model.DifficultyHard}
+)
+
+// CreatePreset validates a drill setup and saves it as one of the user's
+// presets. Empty perspective, input method and difficulty take the same
+// defaults as starting a drill.
+func (s *UserService) CreatePreset(ctx context.Context, userID bson.ObjectID, preset model.DrillPreset) (*model.DrillPreset, error) {
+	preset.Name = strings.TrimSpace(preset.Name)
+	if preset.Perspective == "" {
+		preset.Perspective = "white"
+	}
+	if preset.InputMethod == "" {
+		preset.InputMethod = model.InputMethodType
+	}
+	if preset.Difficulty == "" {
+		preset.Difficulty = model.DifficultyEasy
+	}
+	if preset.Name == "" || utf8.RuneCountInString(preset.Name) > maxPresetNameLength ||
+		!slices.Contains(drillTypes, preset.DrillType) ||
+		!slices.Contains(perspectives, preset.Perspective) ||
+		!slices.Contains(inputMethods, preset.InputMethod) ||
+		!slices.Contains(difficulties, preset.Difficulty) ||
+		(preset.Piece != "" && !pieceAllowed(preset.DrillType, preset.Piece)) {
+		return nil, ErrInvalidPreset
+	}
+
+	mode, err := NormalizeMode(preset.ModeSettings)
+	if err != nil {
+		return nil, ErrInvalidPreset
+	}
+	preset.ModeSettings = mode
+
+	if preset.Region != nil {
+		if !regionAllowed(preset.DrillType, preset.Mode) {
+			return nil, ErrInvalidPreset
+		}
+		region, err := parseRegion(preset.Region)
+		if err != nil || (region.Kind == model.RegionWeakest && !recordsSquares(preset.DrillType)) {
+			return nil, ErrInvalidPreset
+		}
+		preset.Region = &model.SquareRegion{Kind: region.Kind, Values: region.Values, Label: region.Label}
+	}
+
+	preset.ID = bson.NewObjectID()
+	preset.CreatedAt = time.Now()
+	if err := s.userRepo.AddPreset(ctx, userID, preset, MaxPresets); err != nil {
+		if errors.Is(err, repository.ErrPresetLimit) {
+			return nil, ErrTooManyPresets
+		}
+		return nil, err
+	}
+	return &preset, nil
+}
+
+func (s *UserService) DeletePreset(ctx context.Context, userID, presetID bson.ObjectID) error {
+	err := s.userRepo.DeletePreset(ctx, userID, presetID)
+	if errors.Is(err, repository.ErrPresetNotFound) {
+		return ErrPresetNotFound
+	}
+	return err
+}
+
+// PresetSession returns the options a preset starts a session with. The
+// user's adaptive preferences still apply.
+func PresetSession(preset *model.DrillPreset) SessionOptions {
+	opts := SessionOptions{
+		DrillType:   preset.DrillType,
+		InputMethod: preset.InputMethod,
+		Perspective: preset.Perspective,
+		Difficulty:  preset.Difficulty,
+		Mode:        preset.ModeSettings,
+		Piece:       preset.Piece,
+	}
+	if preset.Region != nil {
+		opts.Region = &model.SquareRegion{Kind: preset.Region.Kind, Values: preset.Region.Values}
+	}
+	return opts
+}
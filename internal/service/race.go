@@ -0,0 +1,547 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/abdul-hamid-achik/chessdrill/internal/model"
+	"github.com/abdul-hamid-achik/chessdrill/internal/repository"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+var (
+	ErrRaceNotFound      = errors.New("race room not found")
+	ErrRaceFull          = errors.New("race room is full")
+	ErrRaceStarted       = errors.New("race has already started")
+	ErrNotInRace         = errors.New("not a player in this race")
+	ErrNotRaceHost       = errors.New("only the host can start the race")
+	ErrRaceTooFewPlayers = errors.New("a race needs at least two players")
+	ErrRoundClosed       = errors.New("round is closed")
+)
+
+const (
+	MinRacePlayers    = 2
+	MaxRacePlayers    = 8
+	defaultRaceRounds = 20
+	maxRaceRounds     = 50
+)
+
+// Race timing, all kept by the server
+const (
+	raceCountdown  = 3 * time.Second
+	raceRoundTime  = 10 * time.Second
+	raceRoundPause = 2 * time.Second
+	// raceRoomTTL is how long a room waiting in the lobby, or a finished
+	// one, is kept after it last changed
+	raceRoomTTL = 30 * time.Minute
+	// raceStoreTimeout bounds the database work done from round timers
+	raceStoreTimeout = 10 * time.Second
+)
+
+const raceCodeLength = 6
+
+// raceDrillTypes are the coordinate drills rooms can race
+var raceDrillTypes = []model.DrillType{model.DrillTypeNameSquare, model.DrillTypeFindSquare}
+
+// RaceDrillTypes returns the drill types rooms can be created for
+func RaceDrillTypes() []model.DrillType {
+	return slices.Clone(raceDrillTypes)
+}
+
+// RaceService runs multiplayer race rooms. Rooms live in memory; each
+// player's answers are recorded in their own drill session and the final
+// standings are stored once a race finishes.
+type RaceService struct {
+	raceRepo     *repository.RaceRepository
+	drillService *DrillService
+
+	mu    sync.Mutex
+	rooms map[string]*raceRoom
+}
+
+func NewRaceService(raceRepo *repository.RaceRepository, drillService *DrillService) *RaceService {
+	return &RaceService{
+		raceRepo:     raceRepo,
+		drillService: drillService,
+		rooms:        make(map[string]*raceRoom),
+	}
+}
+
+// raceRoom is the state of one room. Its mutex serializes answers, so the
+// first correct answer to reach the server wins the round.
+type raceRoom struct {
+	mu sync.Mutex
+
+	code      string
+	drillType model.DrillType
+	rounds    int
+	seed      int64
+	hostID    bson.ObjectID
+	raceID    bson.ObjectID
+	status    model.RaceStatus
+	players   []*racePlayer
+
+	// round is the index of the current round, -1 before the first
+	round      int
+	question   *model.Question
+	roundOpen  bool
+	roundStart time.Time
+	startsAt   time.Time
+	lastRound  *model.RaceRoundResult
+	startedAt  time.Time
+	updatedAt  time.Time
+
+	subscribers map[chan model.RaceState]bson.ObjectID
+}
+
+type racePlayer struct {
+	userID      bson.ObjectID
+	username    string
+	perspective string
+	session     *model.DrillSession
+	points      int
+	answered    bool
+	connections int
+}
+
+// Create opens a room hosted by the user and returns its code
+func (s *RaceService) Create(user *model.User, drillType model.DrillType, rounds int) (string, error) {
+	if !slices.Contains(raceDrillTypes, drillType) {
+		return "", ErrUnknownDrillType
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep()
+
+	code := newCode(raceCodeLength)
+	for s.rooms[code] != nil {
+		code = newCode(raceCodeLength)
+	}
+	room := &raceRoom{
+		code:        code,
+		drillType:   drillType,
+		rounds:      clampLimit(rounds, defaultRaceRounds, maxRaceRounds),
+		seed:        newSeed(),
+		hostID:      user.ID,
+		raceID:      bson.NewObjectID(),
+		status:      model.RaceStatusLobby,
+		round:       -1,
+		updatedAt:   time.Now(),
+		subscribers: make(map[chan model.RaceState]bson.ObjectID),
+	}
+	room.players = append(room.players, newRacePlayer(user))
+	s.rooms[code] = room
+	return code, nil
+}
+
+func newRacePlayer(user *model.User) *racePlayer {
+	perspective := "white"
+	if user.Preferences.Perspective == "black" {
+		perspective = "black"
+	}
+	return &racePlayer{userID: user.ID, username: user.Username, perspective: perspective}
+}
+
+// sweep drops rooms idle in the lobby or finished for longer than
+// raceRoomTTL. s.mu must be held.
+func (s *RaceService) sweep() {
+	for code, room := range s.rooms {
+		room.mu.Lock()
+		expired := room.status != model.RaceStatusRunning && time.Since(room.updatedAt) > raceRoomTTL
+		room.mu.Unlock()
+		if expired {
+			delete(s.rooms, code)
+		}
+	}
+}
+
+func (s *RaceService) room(code string) (*raceRoom, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	room := s.rooms[strings.ToUpper(code)]
+	if room == nil {
+		return nil, ErrRaceNotFound
+	}
+	return room, nil
+}
+
+// Join adds the user to a room that has not started yet. Joining a room
+// the user is already in does nothing.
+func (s *RaceService) Join(user *model.User, code string) error {
+	room, err := s.room(code)
+	if err != nil {
+		return err
+	}
+	room.mu.Lock()
+	defer room.mu.Unlock()
+
+	if room.player(user.ID) != nil {
+		return nil
+	}
+	if room.status != model.RaceStatusLobby {
+		return ErrRaceStarted
+	}
+	if len(room.players) >= MaxRacePlayers {
+		return ErrRaceFull
+	}
+	room.players = append(room.players, newRacePlayer(user))
+	room.changed()
+	return nil
+}
+
+// Leave removes the user from a room still in the lobby, handing the host
+// role on if needed. Players can't leave a running race; they simply stop
+// answering.
+func (s *RaceService) Leave(userID bson.ObjectID, code string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	room := s.rooms[strings.ToUpper(code)]
+	if room == nil {
+		return ErrRaceNotFound
+	}
+	room.mu.Lock()
+	defer room.mu.Unlock()
+
+	if room.player(userID) == nil {
+		return ErrNotInRace
+	}
+	if room.status != model.RaceStatusLobby {
+		return ErrRaceStarted
+	}
+	room.players = slices.DeleteFunc(room.players, func(p *racePlayer) bool {
+		return p.userID == userID
+	})
+	if len(room.players) == 0 {
+		delete(s.rooms, room.code)
+		return nil
+	}
+	if room.hostID == userID {
+		room.hostID = room.players[0].userID
+	}
+	room.changed()
+	return nil
+}
+
+// State returns a room's current state as seen by one of its players
+func (s *RaceService) State(userID bson.ObjectID, code string) (*model.RaceState, error) {
+	room, err := s.room(code)
+	if err != nil {
+		return nil, err
+	}
+	room.mu.Lock()
+	defer room.mu.Unlock()
+
+	if room.player(userID) == nil {
+		return nil, ErrNotInRace
+	}
+	state := room.snapshot()
+	return &state, nil
+}
+
+// Subscribe streams a room's state to one of its players, starting with
+// the current state. Only the latest state is kept for a slow reader.
+// cancel must be called when the player disconnects.
+func (s *RaceService) Subscribe(userID bson.ObjectID, code string) (<-chan model.RaceState, func(), error) {
+	room, err := s.room(code)
+	if err != nil {
+		return nil, nil, err
+	}
+	room.mu.Lock()
+	defer room.mu.Unlock()
+
+	player := room.player(userID)
+	if player == nil {
+		return nil, nil, ErrNotInRace
+	}
+	ch := make(chan model.RaceState, 1)
+	room.subscribers[ch] = userID
+	player.connections++
+	room.broadcast()
+
+	cancel := func() {
+		room.mu.Lock()
+		defer room.mu.Unlock()
+		if _, ok := room.subscribers[ch]; !ok {
+			return
+		}
+		delete(room.subscribers, ch)
+		if player := room.player(userID); player != nil {
+			player.connections--
+		}
+		room.broadcast()
+	}
+	return ch, cancel, nil
+}
+
+// Start begins the race after a short countdown. Every player gets a drill
+// session seeded with the room's seed, so all of them see the same
+// question stream.
+func (s *RaceService) Start(ctx context.Context, userID bson.ObjectID, code string) error {
+	room, err := s.room(code)
+	if err != nil {
+		return err
+	}
+	room.mu.Lock()
+	defer room.mu.Unlock()
+
+	if room.hostID != userID {
+		return ErrNotRaceHost
+	}
+	if room.status != model.RaceStatusLobby {
+		return ErrRaceStarted
+	}
+	if len(room.players) < MinRacePlayers {
+		return ErrRaceTooFewPlayers
+	}
+
+	mode := model.ModeSettings{Mode: model.SessionModeRace, QuestionCount: room.rounds}
+	for _, player := range room.players {
+		session := model.NewDrillSession(player.userID, room.drillType, model.InputMethodType, player.perspective, "", mode)
+		session.Seed = room.seed
+		session.Unranked = true
+		session.RaceID = room.raceID
+		if err := s.drillService.drillSessionRepo.Create(ctx, session); err != nil {
+			return err
+		}
+		player.session = session
+	}
+
+	room.status = model.RaceStatusRunning
+	room.startedAt = time.Now()
+	room.startsAt = room.startedAt.Add(raceCountdown)
+	room.changed()
+	time.AfterFunc(raceCountdown, func() { s.startRound(room, 0) })
+	return nil
+}
+
+// startRound issues the question of a round to every player
+func (s *RaceService) startRound(room *raceRoom, round int) {
+	room.mu.Lock()
+	defer room.mu.Unlock()
+	if room.status != model.RaceStatusRunning {
+		return
+	}
+
+	rng := NewSeededSource(room.seed, round)
+	room.question = s.drillService.GenerateQuestion(rng, room.drillType, "", nil)
+	room.round = round
+	room.roundOpen = true
+	room.roundStart = time.Now()
+	for _, player := range room.players {
+		player.answered = false
+	}
+	room.changed()
+	time.AfterFunc(raceRoundTime, func() { s.timeoutRound(room, round) })
+}
+
+// timeoutRound closes a round nobody won in time
+func (s *RaceService) timeoutRound(room *raceRoom, round int) {
+	room.mu.Lock()
+	defer room.mu.Unlock()
+	if room.round != round || !room.roundOpen {
+		return
+	}
+	s.closeRound(room, nil)
+}
+
+// closeRound ends the current round, won by winner if not nil, and moves
+// on to the next round or the results. room.mu must be held.
+func (s *RaceService) closeRound(room *raceRoom, winner *racePlayer) {
+	room.roundOpen = false
+	room.lastRound = &model.RaceRoundResult{
+		Round:  room.round + 1,
+		Answer: room.question.Answer,
+	}
+	if winner != nil {
+		room.lastRound.WinnerID = winner.userID
+		room.lastRound.WinnerName = winner.username
+	}
+	room.changed()
+
+	next := room.round + 1
+	if next >= room.rounds {
+		time.AfterFunc(raceRoundPause, func() { s.finish(room) })
+		return
+	}
+	time.AfterFunc(raceRoundPause, func() { s.startRound(room, next) })
+}
+
+// Answer grades a player's answer to the current round. Each player gets
+// one answer per round; the first correct one scores and closes the round.
+// round is the 1-based round the answer is for, so late answers to an
+// earlier round are rejected.
+func (s *RaceService) Answer(ctx context.Context, userID bson.ObjectID, code string, round int, answer string) (bool, error) {
+	room, err := s.room(code)
+	if err != nil {
+		return false, err
+	}
+	room.mu.Lock()
+	defer room.mu.Unlock()
+
+	player := room.player(userID)
+	if player == nil {
+		return false, ErrNotInRace
+	}
+	if room.status != model.RaceStatusRunning || !room.roundOpen || round != room.round+1 {
+		return false, ErrRoundClosed
+	}
+	if player.answered {
+		return false, ErrQuestionAnswered
+	}
+
+	responseMs := int(time.Since(room.roundStart).Milliseconds())
+	attempt, _, err := s.drillService.recordAnswer(ctx, player.session, room.question, answer, responseMs)
+	if err != nil {
+		return false, err
+	}
+	player.answered = true
+
+	if attempt.Correct {
+		player.points++
+		s.closeRound(room, player)
+		return true, nil
+	}
+	if !slices.ContainsFunc(room.players, func(p *racePlayer) bool { return !p.answered }) {
+		s.closeRound(room, nil)
+		return false, nil
+	}
+	room.changed()
+	return false, nil
+}
+
+// finish ends every player's session and stores the final standings
+func (s *RaceService) finish(room *raceRoom) {
+	room.mu.Lock()
+	defer room.mu.Unlock()
+	if room.status != model.RaceStatusRunning {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), raceStoreTimeout)
+	defer cancel()
+
+	for _, player := range room.players {
+		if _, err := s.drillService.endSession(ctx, player.session); err != nil && !errors.Is(err, ErrSessionEnded) {
+			log.Printf("Failed to end race session %s: %v", player.session.ID.Hex(), err)
+		}
+	}
+
+	result := &model.RaceResult{
+		ID:         room.raceID,
+		Code:       room.code,
+		DrillType:  room.drillType,
+		Rounds:     room.rounds,
+		Players:    raceStandings(room.players),
+		StartedAt:  room.startedAt,
+		FinishedAt: time.Now(),
+	}
+	if err := s.raceRepo.Create(ctx, result); err != nil {
+		log.Printf("Failed to store race %s: %v", room.code, err)
+	}
+
+	room.status = model.RaceStatusFinished
+	room.question = nil
+	room.changed()
+}
+
+// raceStandings ranks players by points; tied players share a rank
+func raceStandings(players []*racePlayer) []model.RacePlayerResult {
+	results := make([]model.RacePlayerResult, len(players))
+	for i, player := range players {
+		results[i] = model.RacePlayerResult{
+			UserID:    player.userID,
+			Username:  player.username,
+			SessionID: player.session.ID,
+			Points:    player.points,
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Points > results[j].Points
+	})
+	for i := range results {
+		results[i].Rank = i + 1
+		if i > 0 && results[i].Points == results[i-1].Points {
+			results[i].Rank = results[i-1].Rank
+		}
+	}
+	return results
+}
+
+func (r *raceRoom) player(userID bson.ObjectID) *racePlayer {
+	for _, player := range r.players {
+		if player.userID == userID {
+			return player
+		}
+	}
+	return nil
+}
+
+// changed records a state change and sends it to every subscriber.
+// r.mu must be held.
+func (r *raceRoom) changed() {
+	r.updatedAt = time.Now()
+	r.broadcast()
+}
+
+// broadcast sends the current state to every subscriber, replacing any
+// state a subscriber has not read yet. r.mu must be held; as the only
+// sender, it never blocks.
+func (r *raceRoom) broadcast() {
+	state := r.snapshot()
+	for ch := range r.subscribers {
+		select {
+		case ch <- state:
+		default:
+			select {
+			case <-ch:
+			default:
+			}
+			ch <- state
+		}
+	}
+}
+
+// snapshot returns the room's public state. r.mu must be held.
+func (r *raceRoom) snapshot() model.RaceState {
+	state := model.RaceState{
+		Code:       r.code,
+		DrillType:  r.drillType,
+		Status:     r.status,
+		HostID:     r.hostID,
+		Round:      r.round + 1,
+		Rounds:     r.rounds,
+		LastRound:  r.lastRound,
+		ServerTime: time.Now(),
+	}
+	for _, player := range r.players {
+		state.Players = append(state.Players, model.RaceStanding{
+			UserID:    player.userID,
+			Username:  player.username,
+			Points:    player.points,
+			Answered:  player.answered,
+			Connected: player.connections > 0,
+		})
+	}
+	if r.status == model.RaceStatusRunning && r.round < 0 {
+		startsAt := r.startsAt
+		state.StartsAt = &startsAt
+	}
+	if r.roundOpen {
+		state.Question = &model.RaceQuestion{
+			Type:   r.question.Type,
+			Target: r.question.Target,
+			Prompt: r.question.Prompt,
+			FEN:    r.question.FEN,
+		}
+		endsAt := r.roundStart.Add(raceRoundTime)
+		state.RoundEndsAt = &endsAt
+	}
+	return state
+}
@@ -0,0 +1,49 @@
+package service
+
+import (
+	crand "crypto/rand"
+	"math/big"
+	"math/rand/v2"
+)
+
+// RandomSource supplies the random choices made while generating questions.
+// Sessions use a seeded source so their question stream can be replayed.
+type RandomSource interface {
+	IntN(n int) int
+}
+
+// maxSeed keeps seeds within the integers JavaScript represents exactly, so
+// they survive a round trip through the browser
+const maxSeed = 1 << 53
+
+// NewSeededSource returns the deterministic source for one question of a
+// seeded stream. Each question gets its own source, derived from the seed
+// and the question's index, so a stream can be resumed at any point.
+func NewSeededSource(seed int64, index int) RandomSource {
+	return rand.New(rand.NewPCG(uint64(seed), uint64(index)))
+}
+
+// newSeed returns a random seed for a new session
+func newSeed() int64 {
+	return int64(1 + randomInt(maxSeed-1))
+}
+
+// randomInt returns a uniformly random int in [0, n) for seeds and codes,
+// which must not be guessable. Questions draw from a RandomSource instead.
+func randomInt(n int) int {
+	idx, _ := crand.Int(crand.Reader, big.NewInt(int64(n)))
+	return int(idx.Int64())
+}
+
+// codeAlphabet leaves out letters and digits that are easily confused, for
+// codes people read out to each other
+const codeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// newCode returns a random code of n characters from codeAlphabet
+func newCode(n int) string {
+	code := make([]byte, n)
+	for i := range code {
+		code[i] = codeAlphabet[randomInt(len(codeAlphabet))]
+	}
+	return string(code)
+}
@@ -0,0 +1,247 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/abdul-hamid-achik/chessdrill/internal/chess"
+	"github.com/abdul-hamid-achik/chessdrill/internal/model"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+var (
+	ErrInvalidRegion = errors.New("invalid square region")
+	ErrNoWeakSquares = errors.New("no answers yet to find your weakest squares from")
+)
+
+// weakestRegionSize is how many squares a "weakest squares" region holds
+const weakestRegionSize = 10
+
+// quadrantCorners are the corner squares quadrants are named by
+var quadrantCorners = []string{"a1", "h1", "a8", "h8"}
+
+// resolveRegion validates a requested practice region and works out its
+// squares. The weakest squares are fixed when the session starts so a
+// replayed seed draws from the same region.
+func (s *DrillService) resolveRegion(ctx context.Context, userID bson.ObjectID, drillType model.DrillType, perspective string, mode model.SessionMode, requested *model.SquareRegion) (*model.SquareRegion, error) {
+	if !regionAllowed(drillType, mode) {
+		return nil, ErrInvalidRegion
+	}
+	region, err := parseRegion(requested)
+	if err != nil || region.Kind != model.RegionWeakest {
+		return region, err
+	}
+	if !recordsSquares(drillType) {
+		return nil, ErrInvalidRegion
+	}
+
+	stats, err := s.attemptRepo.GetDrillSquareAccuracy(ctx, userID, drillType, perspective)
+	if err != nil {
+		return nil, err
+	}
+	weakest := weakestSquares(stats, weakestRegionSize)
+	if weakest == nil {
+		return nil, ErrNoWeakSquares
+	}
+	slices.Sort(weakest)
+	for _, sq := range weakest {
+		region.Squares = append(region.Squares, sq.String())
+	}
+	return region, nil
+}
+
+// regionAllowed reports whether sessions of a drill type and mode can be
+// limited to a region. Only drills that pick a target square can, and
+// review sessions serve their due items wherever they are.
+func regionAllowed(drillType model.DrillType, mode model.SessionMode) bool {
+	return usesSquareWeights(drillType) && mode != model.SessionModeReview
+}
+
+// parseRegion validates a region's kind and values and works out its
+// squares and label. The squares of a "weakest" region depend on the user
+// and are left for resolveRegion.
+func parseRegion(requested *model.SquareRegion) (*model.SquareRegion, error) {
+	var in [64]bool
+	region := &model.SquareRegion{Kind: requested.Kind}
+	switch requested.Kind {
+	case model.RegionFiles, model.RegionRanks:
+		lines, err := parseLines(requested.Kind, requested.Values)
+		if err != nil {
+			return nil, err
+		}
+		for sq := range in {
+			line := chess.Square(sq).Rank()
+			if requested.Kind == model.RegionFiles {
+				line = chess.Square(sq).File()
+			}
+			in[sq] = slices.Contains(lines, line)
+		}
+		region.Values = make([]string, len(lines))
+		for i, line := range lines {
+			if requested.Kind == model.RegionFiles {
+				region.Values[i] = files[line]
+			} else {
+				region.Values[i] = ranks[line]
+			}
+		}
+		region.Label = lineLabel(requested.Kind, region.Values)
+
+	case model.RegionQuadrant:
+		if len(requested.Values) != 1 || !slices.Contains(quadrantCorners, strings.ToLower(requested.Values[0])) {
+			return nil, ErrInvalidRegion
+		}
+		corner, _ := chess.ParseSquare(strings.ToLower(requested.Values[0]))
+		lowFile, lowRank := corner.File()&4, corner.Rank()&4
+		markRect(&in, lowFile, lowRank, lowFile+3, lowRank+3)
+		region.Values = []string{corner.String()}
+		region.Label = fmt.Sprintf("Quadrant %s-%s", chess.NewSquare(lowFile, lowRank), chess.NewSquare(lowFile+3, lowRank+3))
+
+	case model.RegionCenter:
+		markRect(&in, 2, 2, 5, 5)
+		region.Label = "Center"
+
+	case model.RegionQueenside:
+		markRect(&in, 0, 0, 3, 7)
+		region.Label = "Queenside"
+
+	case model.RegionKingside:
+		markRect(&in, 4, 0, 7, 7)
+		region.Label = "Kingside"
+
+	case model.RegionSquares:
+		for _, v := range requested.Values {
+			sq, err := chess.ParseSquare(strings.ToLower(strings.TrimSpace(v)))
+			if err != nil {
+				return nil, ErrInvalidRegion
+			}
+			in[sq] = true
+		}
+
+	case model.RegionWeakest:
+		region.Label = fmt.Sprintf("%d weakest squares", weakestRegionSize)
+		return region, nil
+
+	default:
+		return nil, ErrInvalidRegion
+	}
+
+	for sq, ok := range in {
+		if ok {
+			region.Squares = append(region.Squares, chess.Square(sq).String())
+		}
+	}
+	if len(region.Squares) == 0 {
+		return nil, ErrInvalidRegion
+	}
+	// Hand-picked squares are labelled once they are deduplicated
+	if requested.Kind == model.RegionSquares {
+		region.Values = region.Squares
+		region.Label = handPickedLabel(region.Squares)
+	}
+	return region, nil
+}
+
+// parseLines parses the files or ranks of a region into sorted, distinct
+// indexes
+func parseLines(kind model.RegionKind, values []string) ([]int, error) {
+	names := ranks
+	if kind == model.RegionFiles {
+		names = files
+	}
+	var lines []int
+	for _, v := range values {
+		line := slices.Index(names, strings.ToLower(strings.TrimSpace(v)))
+		if line < 0 {
+			return nil, ErrInvalidRegion
+		}
+		if !slices.Contains(lines, line) {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		return nil, ErrInvalidRegion
+	}
+	slices.Sort(lines)
+	return lines, nil
+}
+
+// lineLabel describes a files or ranks region, e.g. "Files d, e"
+func lineLabel(kind model.RegionKind, values []string) string {
+	noun := "Rank"
+	if kind == model.RegionFiles {
+		noun = "File"
+	}
+	if len(values) > 1 {
+		noun += "s"
+	}
+	return noun + " " + strings.Join(values, ", ")
+}
+
+// handPickedLabel lists a few hand-picked squares, or counts more of them
+func handPickedLabel(squares []string) string {
+	if len(squares) <= 4 {
+		return "Squares " + strings.Join(squares, ", ")
+	}
+	return fmt.Sprintf("%d hand-picked squares", len(squares))
+}
+
+// markRect marks the squares between two corners, inclusive
+func markRect(in *[64]bool, fromFile, fromRank, toFile, toRank int) {
+	for f := fromFile; f <= toFile; f++ {
+		for r := fromRank; r <= toRank; r++ {
+			in[chess.NewSquare(f, r)] = true
+		}
+	}
+}
+
+// weakestSquares returns the n squares with the highest weakness, ties
+// broken in board order. It returns nil when no square has been answered,
+// as every square would tie.
+func weakestSquares(stats []model.SquareAccuracy, n int) []chess.Square {
+	if !slices.ContainsFunc(stats, func(st model.SquareAccuracy) bool { return st.Total > 0 }) {
+		return nil
+	}
+	weakness := squareWeakness(stats)
+	order := make([]chess.Square, 64)
+	for sq := range order {
+		order[sq] = chess.Square(sq)
+	}
+	slices.SortStableFunc(order, func(a, b chess.Square) int {
+		return cmp.Compare(weakness[b], weakness[a])
+	})
+	return order[:n]
+}
+
+// Within restricts the distribution to a region's squares, keeping their
+// relative weights. A nil *SquareWeights becomes uniform over the region.
+func (w *SquareWeights) Within(squares []string) *SquareWeights {
+	var mass [64]float64
+	for _, name := range squares {
+		if sq, err := chess.ParseSquare(name); err == nil {
+			mass[sq] = w.mass(sq)
+		}
+	}
+
+	within := &SquareWeights{}
+	running := 0.0
+	for sq, m := range mass {
+		running += m
+		within.cumulative[sq] = running
+	}
+	return within
+}
+
+// mass returns the probability of picking a square
+func (w *SquareWeights) mass(sq chess.Square) float64 {
+	if w == nil {
+		return 1
+	}
+	if sq == 0 {
+		return w.cumulative[0]
+	}
+	return w.cumulative[sq] - w.cumulative[sq-1]
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/abdul-hamid-achik/chessdrill/internal/chess"
+	"github.com/abdul-hamid-achik/chessdrill/internal/model"
+)
+
+func TestParseRegion(t *testing.T) {
+	tests := []struct {
+		name    string
+		region  model.SquareRegion
+		label   string
+		squares int
+		has     []string
+		lacks   []string
+	}{
+		{"files", model.SquareRegion{Kind: model.RegionFiles, Values: []string{"E", "d", "e"}}, "Files d, e", 16, []string{"d1", "e8"}, []string{"c4", "f4"}},
+		{"one rank", model.SquareRegion{Kind: model.RegionRanks, Values: []string{"7"}}, "Rank 7", 8, []string{"a7", "h7"}, []string{"a8"}},
+		{"quadrant", model.SquareRegion{Kind: model.RegionQuadrant, Values: []string{"H8"}}, "Quadrant e5-h8", 16, []string{"e5", "h8"}, []string{"d5", "e4"}},
+		{"center", model.SquareRegion{Kind: model.RegionCenter}, "Center", 16, []string{"c3", "f6"}, []string{"b3", "c7"}},
+		{"queenside", model.SquareRegion{Kind: model.RegionQueenside}, "Queenside", 32, []string{"a1", "d8"}, []string{"e1"}},
+		{"few squares", model.SquareRegion{Kind: model.RegionSquares, Values: []string{"e4", " D5 ", "e4"}}, "Squares e4, d5", 2, []string{"d5", "e4"}, []string{"d4"}},
+		{"many squares", model.SquareRegion{Kind: model.RegionSquares, Values: []string{"a1", "b2", "c3", "d4", "e5"}}, "5 hand-picked squares", 5, []string{"c3"}, nil},
+		{"weakest is resolved later", model.SquareRegion{Kind: model.RegionWeakest}, "10 weakest squares", 0, nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			region, err := parseRegion(&tt.region)
+			if err != nil {
+				t.Fatalf("parseRegion: %v", err)
+			}
+			if region.Label != tt.label || len(region.Squares) != tt.squares {
+				t.Errorf("parseRegion = %q with %d squares, want %q with %d", region.Label, len(region.Squares), tt.label, tt.squares)
+			}
+			for _, sq := range tt.has {
+				if !slices.Contains(region.Squares, sq) {
+					t.Errorf("region lacks %s", sq)
+				}
+			}
+			for _, sq := range tt.lacks {
+				if slices.Contains(region.Squares, sq) {
+					t.Errorf("region has %s", sq)
+				}
+			}
+		})
+	}
+}
+
+func TestParseRegionErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		region model.SquareRegion
+	}{
+		{"no files", model.SquareRegion{Kind: model.RegionFiles}},
+		{"bad file", model.SquareRegion{Kind: model.RegionFiles, Values: []string{"i"}}},
+		{"bad rank", model.SquareRegion{Kind: model.RegionRanks, Values: []string{"0"}}},
+		{"not a corner", model.SquareRegion{Kind: model.RegionQuadrant, Values: []string{"d4"}}},
+		{"bad square", model.SquareRegion{Kind: model.RegionSquares, Values: []string{"e9"}}},
+		{"no squares", model.SquareRegion{Kind: model.RegionSquares}},
+		{"unknown kind", model.SquareRegion{Kind: "diagonal"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseRegion(&tt.region); !errors.Is(err, ErrInvalidRegion) {
+				t.Errorf("error = %v, want ErrInvalidRegion", err)
+			}
+		})
+	}
+}
+
+func TestRegionAllowed(t *testing.T) {
+	tests := []struct {
+		drillType model.DrillType
+		mode      model.SessionMode
+		want      bool
+	}{
+		{model.DrillTypeFindSquare, model.SessionModeTimed, true},
+		{model.DrillTypeKnightDistance, model.SessionModeOpen, true},
+		{model.DrillTypeGeometry, model.SessionModeCount, true},
+		{model.DrillTypeFindSquare, model.SessionModeReview, false},
+		{model.DrillTypeTactics, model.SessionModeOpen, false},
+	}
+
+	for _, tt := range tests {
+		if got := regionAllowed(tt.drillType, tt.mode); got != tt.want {
+			t.Errorf("regionAllowed(%s, %s) = %v, want %v", tt.drillType, tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestWeakestSquares(t *testing.T) {
+	tests := []struct {
+		name  string
+		stats []model.SquareAccuracy
+		n     int
+		want  []string
+	}{
+		{
+			name: "no history",
+			n:    10,
+		},
+		{
+			name:  "only unanswered rows",
+			stats: []model.SquareAccuracy{{Square: "e4"}},
+			n:     10,
+		},
+		{
+			name: "missed squares first, then unseen ones in board order",
+			stats: []model.SquareAccuracy{
+				{Square: "a1", Total: 10, Credit: 10},
+				{Square: "e4", Total: 4, Credit: 0},
+				{Square: "d5", Total: 4, Credit: 1},
+			},
+			n:    4,
+			want: []string{"e4", "d5", "b1", "c1"},
+		},
+		{
+			name: "a slow square outranks unseen ones, a fast one falls behind",
+			stats: []model.SquareAccuracy{
+				{Square: "c3", Total: 4, Credit: 2, AvgResponseMs: 1000},
+				{Square: "f6", Total: 4, Credit: 2, AvgResponseMs: 3000},
+			},
+			n:    2,
+			want: []string{"f6", "a1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := weakestSquares(tt.stats, tt.n)
+			if tt.want == nil {
+				if got != nil {
+					t.Fatalf("weakestSquares = %v, want nil", got)
+				}
+				return
+			}
+			if names := squareNames(got); !slices.Equal(names, tt.want) {
+				t.Errorf("weakestSquares = %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
+
+// squareNames returns the names of squares
+func squareNames(squares []chess.Square) []string {
+	names := make([]string, len(squares))
+	for i, sq := range squares {
+		names[i] = sq.String()
+	}
+	return names
+}
@@ -0,0 +1,346 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/abdul-hamid-achik/chessdrill/internal/chess"
+	"github.com/abdul-hamid-achik/chessdrill/internal/model"
+	"github.com/abdul-hamid-achik/chessdrill/internal/repository"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+var (
+	ErrRepertoireNotFound = errors.New("no repertoire uploaded for this color")
+	ErrInvalidRepertoire  = errors.New("invalid repertoire")
+)
+
+// maxRepertoireMoves bounds the size of a repertoire's tree
+const maxRepertoireMoves = 5000
+
+// RepertoireService stores the opening repertoires users upload as PGN
+type RepertoireService struct {
+	repertoireRepo *repository.RepertoireRepository
+	attemptRepo    *repository.AttemptRepository
+}
+
+func NewRepertoireService(repertoireRepo *repository.RepertoireRepository, attemptRepo *repository.AttemptRepository) *RepertoireService {
+	return &RepertoireService{
+		repertoireRepo: repertoireRepo,
+		attemptRepo:    attemptRepo,
+	}
+}
+
+// repertoireEdge identifies a move by the move it follows, so the same
+// moves from different games and variations merge
+type repertoireEdge struct {
+	parent int
+	uci    string
+}
+
+// Import parses a PGN into the user's repertoire for a color, replacing the
+// one they had. Every game and variation is merged into one tree; games
+// must start from the initial position.
+func (s *RepertoireService) Import(ctx context.Context, userID bson.ObjectID, color, pgn string) (*model.Repertoire, error) {
+	if !slices.Contains(perspectives, color) {
+		return nil, fmt.Errorf("%w: color must be white or black", ErrInvalidRepertoire)
+	}
+	games, err := chess.ParsePGN(pgn)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRepertoire, err)
+	}
+
+	repertoire := &model.Repertoire{
+		UserID:     userID,
+		Color:      color,
+		Moves:      []model.RepertoireMove{},
+		UploadedAt: time.Now(),
+	}
+	index := make(map[repertoireEdge]int)
+	var merge func(parent int, moves []*chess.PGNMove) error
+	merge = func(parent int, moves []*chess.PGNMove) error {
+		for _, m := range moves {
+			edge := repertoireEdge{parent: parent, uci: m.Move.UCI()}
+			i, ok := index[edge]
+			if !ok {
+				if len(repertoire.Moves) == maxRepertoireMoves {
+					return fmt.Errorf("%w: more than %d moves", ErrInvalidRepertoire, maxRepertoireMoves)
+				}
+				i = len(repertoire.Moves)
+				index[edge] = i
+				repertoire.Moves = append(repertoire.Moves, model.RepertoireMove{Parent: parent, UCI: edge.uci, SAN: m.SAN})
+			}
+			if repertoire.Moves[i].Comment == "" {
+				repertoire.Moves[i].Comment = m.Comment
+			}
+			if err := merge(i, m.Next); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, game := range games {
+		if fen, ok := game.Tags["FEN"]; ok {
+			if pos, err := chess.ParseFEN(fen); err != nil || pos.FEN() != chess.StartFEN {
+				return nil, fmt.Errorf("%w: games must start from the initial position", ErrInvalidRepertoire)
+			}
+		}
+		if err := merge(-1, game.Moves); err != nil {
+			return nil, err
+		}
+	}
+	if len(repertoireLines(repertoire)) == 0 {
+		return nil, fmt.Errorf("%w: no moves for %s", ErrInvalidRepertoire, color)
+	}
+
+	if err := s.repertoireRepo.Save(ctx, repertoire); err != nil {
+		return nil, err
+	}
+	return repertoire, nil
+}
+
+// Get returns the user's repertoire for a color
+func (s *RepertoireService) Get(ctx context.Context, userID bson.ObjectID, color string) (*model.Repertoire, error) {
+	repertoire, err := s.repertoireRepo.FindByUser(ctx, userID, color)
+	if errors.Is(err, repository.ErrRepertoireNotFound) {
+		return nil, ErrRepertoireNotFound
+	}
+	return repertoire, err
+}
+
+// Overview lists the user's repertoires with the stats of every line,
+// including lines not drilled yet
+func (s *RepertoireService) Overview(ctx context.Context, userID bson.ObjectID) ([]model.RepertoireOverview, error) {
+	repertoires, err := s.repertoireRepo.FindAllByUser(ctx, userID)
+	if err != nil || len(repertoires) == 0 {
+		return nil, err
+	}
+	stats, err := s.attemptRepo.GetLineStats(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	overviews := make([]model.RepertoireOverview, 0, len(repertoires))
+	for i := range repertoires {
+		repertoire := &repertoires[i]
+		byLine := lineStatsFor(stats, repertoire.Color)
+		overview := model.RepertoireOverview{
+			Color:      repertoire.Color,
+			Moves:      len(repertoire.Moves),
+			UploadedAt: repertoire.UploadedAt,
+		}
+		for _, line := range repertoireLines(repertoire) {
+			st := model.LineStats{Perspective: repertoire.Color, Line: line.name}
+			if known, ok := byLine[line.name]; ok {
+				st = *known
+			}
+			overview.Lines = append(overview.Lines, st)
+		}
+		overviews = append(overviews, overview)
+	}
+	return overviews, nil
+}
+
+func (s *RepertoireService) Delete(ctx context.Context, userID bson.ObjectID, color string) error {
+	err := s.repertoireRepo.Delete(ctx, userID, color)
+	if errors.Is(err, repository.ErrRepertoireNotFound) {
+		return ErrRepertoireNotFound
+	}
+	return err
+}
+
+// repertoireLine is a path through a repertoire from a first move to one of
+// the user's moves, named by its numbered moves
+type repertoireLine struct {
+	moves []int
+	name  string
+}
+
+// repertoireLines lists a repertoire's lines in tree order. Each ends with
+// a move of the user's: opponent moves the user has no answer to are
+// dropped, and lines left without a move of the user's are skipped.
+func repertoireLines(repertoire *model.Repertoire) []repertoireLine {
+	// children[i+1] are the moves that follow move i; children[0] the
+	// first moves
+	children := make([][]int, len(repertoire.Moves)+1)
+	for i, m := range repertoire.Moves {
+		children[m.Parent+1] = append(children[m.Parent+1], i)
+	}
+	userPly := 0
+	if repertoire.Color == "black" {
+		userPly = 1
+	}
+
+	var lines []repertoireLine
+	seen := make(map[string]bool)
+	var walk func(path []int)
+	walk = func(path []int) {
+		last := -1
+		if len(path) > 0 {
+			last = path[len(path)-1]
+		}
+		if next := children[last+1]; len(next) > 0 {
+			for _, i := range next {
+				walk(append(path, i))
+			}
+			return
+		}
+
+		n := len(path)
+		for n > 0 && (n-1)%2 != userPly {
+			n--
+		}
+		sans := make([]string, n)
+		for i, m := range path[:n] {
+			sans[i] = repertoire.Moves[m].SAN
+		}
+		name := formatMoveList(sans)
+		if n == 0 || seen[name] {
+			return
+		}
+		seen[name] = true
+		lines = append(lines, repertoireLine{moves: slices.Clone(path[:n]), name: name})
+	}
+	walk(nil)
+	return lines
+}
+
+// lineStatsFor indexes the line stats of one color by line
+func lineStatsFor(stats []model.LineStats, color string) map[string]*model.LineStats {
+	byLine := make(map[string]*model.LineStats)
+	for i := range stats {
+		if stats[i].Perspective == color {
+			byLine[stats[i].Line] = &stats[i]
+		}
+	}
+	return byLine
+}
+
+// lineWeakness scores how weak the user is on each line, the same way
+// squareWeakness scores squares
+func lineWeakness(lines []repertoireLine, byLine map[string]*model.LineStats) []float64 {
+	records := make([]answerRecord, len(lines))
+	for i, line := range lines {
+		if st, ok := byLine[line.name]; ok {
+			records[i] = answerRecord{attempts: st.TotalAttempts, credit: st.Credit, avgResponseMs: st.AvgResponseMs}
+		}
+	}
+	return weaknessScores(records)
+}
+
+// generateRepertoireQuestion starts a line of the user's repertoire for the
+// session's color at the user's first move. Weak, slow and new lines are
+// picked more often; adaptive sessions keep their own exploration floor.
+func (s *DrillService) generateRepertoireQuestion(ctx context.Context, rng RandomSource, session *model.DrillSession) (*model.Question, error) {
+	repertoire, err := s.repertoireRepo.FindByUser(ctx, session.UserID, session.Perspective)
+	if err != nil {
+		if errors.Is(err, repository.ErrRepertoireNotFound) {
+			return nil, ErrRepertoireNotFound
+		}
+		return nil, err
+	}
+	lines := repertoireLines(repertoire)
+	if len(lines) == 0 {
+		return nil, ErrRepertoireNotFound
+	}
+
+	stats, err := s.attemptRepo.GetLineStats(ctx, session.UserID)
+	if err != nil {
+		return nil, err
+	}
+	floor := DefaultExplorationFloor
+	if session.Adaptive {
+		floor = session.ExplorationFloor
+	}
+	line := lines[pickWeighted(rng, lineWeakness(lines, lineStatsFor(stats, session.Perspective)), floor)]
+
+	// Each move is stored with the repertoire's moves at its position, so
+	// the user may play any prepared move
+	moves := make([]string, len(line.moves))
+	prepared := make([]string, len(line.moves))
+	for ply, i := range line.moves {
+		moves[ply] = repertoire.Moves[i].UCI
+		var siblings []string
+		for _, m := range repertoire.Moves {
+			if m.Parent == repertoire.Moves[i].Parent {
+				siblings = append(siblings, m.UCI)
+			}
+		}
+		prepared[ply] = strings.Join(siblings, ",")
+	}
+
+	userPly := 0
+	if session.Perspective == "black" {
+		userPly = 1
+	}
+	return repertoireStep(line.name, moves, prepared, userPly)
+}
+
+// repertoireStep builds the question for the user's move at index ply of a
+// line, played from the initial position. The opponent's preceding move is
+// shown as the last move.
+func repertoireStep(line string, moves, prepared []string, ply int) (*model.Question, error) {
+	pos := chess.StartingPosition()
+	lastSAN := ""
+	for _, uci := range moves[:ply] {
+		m, err := chess.ParseUCI(uci)
+		if err != nil || !pos.IsLegal(m) {
+			return nil, fmt.Errorf("repertoire line %s: illegal move %s", line, uci)
+		}
+		lastSAN = pos.SAN(m)
+		pos = pos.Play(m)
+	}
+
+	expected, err := chess.ParseUCI(moves[ply])
+	if err != nil || !pos.IsLegal(expected) {
+		return nil, fmt.Errorf("repertoire line %s: illegal move %s", line, moves[ply])
+	}
+
+	target := ""
+	prompt := fmt.Sprintf("Play your first move as %s.", capitalize(pos.Turn.String()))
+	if ply > 0 {
+		target = moves[ply-1]
+		number := strconv.Itoa((ply-1)/2+1) + "."
+		if pos.Turn == chess.White {
+			number += ".."
+		}
+		prompt = fmt.Sprintf("%s played %s%s. Play your prepared reply.", capitalize(pos.Turn.Other().String()), number, lastSAN)
+	}
+
+	return &model.Question{
+		Type:   model.DrillTypeRepertoire,
+		Target: target,
+		Prompt: prompt,
+		FEN:    pos.FEN(),
+		Answer: pos.SAN(expected),
+		AnswerData: map[string]string{
+			"move":     expected.UCI(),
+			"moves":    strings.Join(moves, " "),
+			"prepared": strings.Join(prepared, " "),
+			"ply":      strconv.Itoa(ply),
+			"line":     line,
+		},
+	}, nil
+}
+
+// continueLine returns the question for the user's next move in a line
+// after a correct answer, or nil when the line is finished
+func continueLine(question *model.Question) *model.Question {
+	moves := strings.Fields(question.AnswerData["moves"])
+	ply, _ := strconv.Atoi(question.AnswerData["ply"])
+	if ply+2 >= len(moves) {
+		return nil
+	}
+
+	next, err := repertoireStep(question.AnswerData["line"], moves, strings.Fields(question.AnswerData["prepared"]), ply+2)
+	if err != nil {
+		return nil
+	}
+	return next
+}
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/abdul-hamid-achik/chessdrill/internal/model"
+	"github.com/abdul-hamid-achik/chessdrill/internal/repository"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+var ErrNothingDue = errors.New("nothing is due for review")
+
+// SM-2 parameters
+const (
+	initialEasiness = 2.5
+	minEasiness     = 1.3
+)
+
+// Response times that separate a quick recall from a hesitant one
+const (
+	fastRecall = 3 * time.Second
+	slowRecall = 10 * time.Second
+)
+
+type ReviewService struct {
+	reviewRepo *repository.ReviewRepository
+}
+
+func NewReviewService(reviewRepo *repository.ReviewRepository) *ReviewService {
+	return &ReviewService{
+		reviewRepo: reviewRepo,
+	}
+}
+
+// reviewKey identifies the item a question drills, or returns "" for drill
+// types that are not scheduled. Square drills are keyed by the square and
+// piece drills by the square and the position.
+func reviewKey(question *model.Question) string {
+	switch question.Type {
+	case model.DrillTypeNameSquare, model.DrillTypeFindSquare, model.DrillTypeSquareColor:
+		return question.Target
+	case model.DrillTypePieceMovement, model.DrillTypePawnRules:
+		return question.Target + " " + question.FEN
+	default:
+		return ""
+	}
+}
+
+// reviewQuality maps a graded attempt to an SM-2 recall quality from 0 to 5:
+// full credit scores 3 to 5 by response time, partial credit of 60% or more
+// scores 3, and anything less is a lapse
+func reviewQuality(score float64, responseMs int) int {
+	response := time.Duration(responseMs) * time.Millisecond
+	switch {
+	case score >= 1 && response < fastRecall:
+		return 5
+	case score >= 1 && response < slowRecall:
+		return 4
+	case score >= 0.6:
+		return 3
+	case score > 0:
+		return 2
+	default:
+		return 1
+	}
+}
+
+// scheduleReview applies an SM-2 review of the given quality to an item
+func scheduleReview(item *model.ReviewItem, quality int, now time.Time) {
+	if quality >= 3 {
+		switch item.Repetitions {
+		case 0:
+			item.IntervalDays = 1
+		case 1:
+			item.IntervalDays = 6
+		default:
+			item.IntervalDays = int(math.Round(float64(item.IntervalDays) * item.Easiness))
+		}
+		item.Repetitions++
+	} else {
+		item.Repetitions = 0
+		item.IntervalDays = 1
+		item.Lapses++
+	}
+
+	q := float64(5 - quality)
+	item.Easiness = math.Max(minEasiness, item.Easiness+0.1-q*(0.08+q*0.02))
+	item.LastReviewedAt = now
+	item.DueAt = now.AddDate(0, 0, item.IntervalDays)
+}
+
+// reviewCutoff returns the end of the current UTC day; items due before it
+// count as due today
+func reviewCutoff(now time.Time) time.Time {
+	return now.UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+}
+
+// Record updates the review state of the item a graded question drilled.
+// Every square is scheduled once seen; positions are only added when they
+// were missed, as the generators rarely repeat one.
+func (s *ReviewService) Record(ctx context.Context, question *model.Question, attempt *model.Attempt) error {
+	key := reviewKey(question)
+	if key == "" {
+		return nil
+	}
+
+	item, err := s.reviewRepo.FindByKey(ctx, attempt.UserID, question.Type, key)
+	if errors.Is(err, repository.ErrReviewItemNotFound) {
+		// Position keys extend the target with the FEN
+		if key != question.Target && attempt.Correct {
+			return nil
+		}
+		item = &model.ReviewItem{
+			UserID:     attempt.UserID,
+			DrillType:  question.Type,
+			Key:        key,
+			Target:     question.Target,
+			Prompt:     question.Prompt,
+			FEN:        question.FEN,
+			Metadata:   question.Metadata,
+			Answer:     question.Answer,
+			AnswerData: question.AnswerData,
+			Easiness:   initialEasiness,
+			CreatedAt:  attempt.AnsweredAt,
+		}
+	} else if err != nil {
+		return err
+	}
+
+	scheduleReview(item, reviewQuality(attempt.Score, attempt.ResponseMs), attempt.AnsweredAt)
+	return s.reviewRepo.Save(ctx, item)
+}
+
+// NextDue returns a question for the most overdue item of a drill type
+func (s *ReviewService) NextDue(ctx context.Context, userID bson.ObjectID, drillType model.DrillType) (*model.Question, error) {
+	item, err := s.reviewRepo.FindNextDue(ctx, userID, drillType, reviewCutoff(time.Now()))
+	if err != nil {
+		if errors.Is(err, repository.ErrReviewItemNotFound) {
+			return nil, ErrNothingDue
+		}
+		return nil, err
+	}
+
+	return &model.Question{
+		Type:       item.DrillType,
+		Target:     item.Target,
+		Prompt:     item.Prompt,
+		FEN:        item.FEN,
+		Metadata:   item.Metadata,
+		Answer:     item.Answer,
+		AnswerData: item.AnswerData,
+	}, nil
+}
+
+// CountDue counts the items of a drill type due today
+func (s *ReviewService) CountDue(ctx context.Context, userID bson.ObjectID, drillType model.DrillType) (int64, error) {
+	return s.reviewRepo.CountDue(ctx, userID, drillType, reviewCutoff(time.Now()))
+}
+
+// DueToday counts the items due today for each drill type
+func (s *ReviewService) DueToday(ctx context.Context, userID bson.ObjectID) ([]model.ReviewDue, error) {
+	return s.reviewRepo.DueByDrillType(ctx, userID, reviewCutoff(time.Now()))
+}
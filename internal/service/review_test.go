@@ -0,0 +1,105 @@
+package service
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/abdul-hamid-achik/chessdrill/internal/model"
+)
+
+func TestReviewQuality(t *testing.T) {
+	tests := []struct {
+		name       string
+		score      float64
+		responseMs int
+		want       int
+	}{
+		{"quick full credit", 1, 1500, 5},
+		{"hesitant full credit", 1, 5000, 4},
+		{"slow full credit", 1, 15000, 3},
+		{"most of the squares", 0.6, 1000, 3},
+		{"some of the squares", 0.4, 1000, 2},
+		{"wrong", 0, 1000, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := reviewQuality(tt.score, tt.responseMs); got != tt.want {
+				t.Errorf("reviewQuality(%v, %d) = %d, want %d", tt.score, tt.responseMs, got, tt.want)
+			}
+		})
+	}
+}
+
+// Successive reviews of one item: intervals grow 1, 6, then by the
+// easiness, and a lapse starts them over
+func TestScheduleReview(t *testing.T) {
+	steps := []struct {
+		quality      int
+		interval     int
+		repetitions  int
+		lapses       int
+		wantEasiness float64
+	}{
+		{5, 1, 1, 0, 2.6},
+		{5, 6, 2, 0, 2.7},
+		{5, 16, 3, 0, 2.8},
+		{4, 45, 4, 0, 2.8},
+		{1, 1, 0, 1, 2.26},
+		{3, 1, 1, 1, 2.12},
+		{4, 6, 2, 1, 2.12},
+	}
+
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	item := &model.ReviewItem{Easiness: initialEasiness}
+	for i, step := range steps {
+		scheduleReview(item, step.quality, now)
+		if item.IntervalDays != step.interval || item.Repetitions != step.repetitions || item.Lapses != step.lapses {
+			t.Fatalf("review %d (quality %d): interval %d, repetitions %d, lapses %d; want %d, %d, %d",
+				i+1, step.quality, item.IntervalDays, item.Repetitions, item.Lapses, step.interval, step.repetitions, step.lapses)
+		}
+		if math.Abs(item.Easiness-step.wantEasiness) > 1e-9 {
+			t.Fatalf("review %d: easiness %v, want %v", i+1, item.Easiness, step.wantEasiness)
+		}
+		if want := now.AddDate(0, 0, step.interval); !item.DueAt.Equal(want) || !item.LastReviewedAt.Equal(now) {
+			t.Fatalf("review %d: due %v, want %v", i+1, item.DueAt, want)
+		}
+		now = item.DueAt
+	}
+}
+
+func TestScheduleReviewEasinessFloor(t *testing.T) {
+	item := &model.ReviewItem{Easiness: 1.5}
+	for range 3 {
+		scheduleReview(item, 1, time.Now())
+	}
+	if item.Easiness != minEasiness {
+		t.Errorf("easiness = %v, want the floor %v", item.Easiness, minEasiness)
+	}
+}
+
+func TestReviewKey(t *testing.T) {
+	tests := []struct {
+		question model.Question
+		want     string
+	}{
+		{model.Question{Type: model.DrillTypeNameSquare, Target: "e4"}, "e4"},
+		{model.Question{Type: model.DrillTypeSquareColor, Target: "h8"}, "h8"},
+		{model.Question{Type: model.DrillTypePieceMovement, Target: "d4", FEN: "8/8/8/8/3N4/8/8/8 w - - 0 1"}, "d4 8/8/8/8/3N4/8/8/8 w - - 0 1"},
+		{model.Question{Type: model.DrillTypeTactics, Target: "e2e4"}, ""},
+	}
+
+	for _, tt := range tests {
+		if got := reviewKey(&tt.question); got != tt.want {
+			t.Errorf("reviewKey(%s %s) = %q, want %q", tt.question.Type, tt.question.Target, got, tt.want)
+		}
+	}
+}
+
+func TestReviewCutoff(t *testing.T) {
+	now := time.Date(2026, 3, 1, 23, 30, 0, 0, time.FixedZone("UTC-5", -5*3600))
+	if got, want := reviewCutoff(now), time.Date(2026, 3, 3, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("reviewCutoff = %v, want the end of the UTC day %v", got, want)
+	}
+}
@@ -2,22 +2,56 @@ package service
 
 import (
 	"context"
+	"slices"
 
 	"github.com/abdul-hamid-achik/chessdrill/internal/model"
 	"github.com/abdul-hamid-achik/chessdrill/internal/repository"
 	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
+// drillTypes lists every drill type in the order they are presented
+var drillTypes = []model.DrillType{
+	model.DrillTypeNameSquare,
+	model.DrillTypeFindSquare,
+	model.DrillTypePieceMovement,
+	model.DrillTypeMoveNotation,
+	model.DrillTypePawnRules,
+	model.DrillTypeCastling,
+	model.DrillTypeCheckRecognition,
+	model.DrillTypeSquareColor,
+	model.DrillTypeKnightDistance,
+	model.DrillTypeGeometry,
+	model.DrillTypeBlindfold,
+	model.DrillTypeTactics,
+	model.DrillTypeRepertoire,
+}
+
+// DrillTypes returns every drill type in the order they are presented
+func DrillTypes() []model.DrillType {
+	return slices.Clone(drillTypes)
+}
+
 type StatsService struct {
 	attemptRepo      *repository.AttemptRepository
 	drillSessionRepo *repository.DrillSessionRepository
+	drillService     *DrillService
 }
 
-func NewStatsService(attemptRepo *repository.AttemptRepository, drillSessionRepo *repository.DrillSessionRepository) *StatsService {
+func NewStatsService(attemptRepo *repository.AttemptRepository, drillSessionRepo *repository.DrillSessionRepository, drillService *DrillService) *StatsService {
 	return &StatsService{
 		attemptRepo:      attemptRepo,
 		drillSessionRepo: drillSessionRepo,
+		drillService:     drillService,
+	}
+}
+
+// categoryField is the attempt metadata field a drill type's stats are
+// broken down by. Puzzles carry several theme tags each.
+func categoryField(drillType model.DrillType) string {
+	if drillType == model.DrillTypeTactics {
+		return "themes"
 	}
+	return "category"
 }
 
 // GetOverallStats returns overall stats for a user
@@ -35,12 +69,6 @@ func (s *StatsService) GetOverallStats(ctx context.Context, userID bson.ObjectID
 	stats.TotalSessions = int(sessionCount)
 
 	// Get per-drill-type stats
-	drillTypes := []model.DrillType{
-		model.DrillTypeNameSquare,
-		model.DrillTypeFindSquare,
-		model.DrillTypePieceMovement,
-		model.DrillTypeMoveNotation,
-	}
 
 	for _, dt := range drillTypes {
 		drillStats, err := s.attemptRepo.GetDrillStats(ctx, userID, dt)
@@ -48,13 +76,31 @@ func (s *StatsService) GetOverallStats(ctx context.Context, userID bson.ObjectID
 			continue
 		}
 		if drillStats.TotalAttempts > 0 {
+			drillStats.Categories, _ = s.attemptRepo.GetCategoryStats(ctx, userID, dt, categoryField(dt))
 			stats.DrillStats = append(stats.DrillStats, *drillStats)
 		}
 	}
 
+	// Region and repertoire line stats are left out if they cannot be loaded
+	stats.RegionStats, _ = s.attemptRepo.GetRegionStats(ctx, userID)
+	stats.LineStats, _ = s.attemptRepo.GetLineStats(ctx, userID)
+
 	return stats, nil
 }
 
+// RecentSessions returns a user's latest sessions, newest first. Timed
+// sessions that ran out are closed first, so they show their scores.
+func (s *StatsService) RecentSessions(ctx context.Context, userID bson.ObjectID, limit int) ([]model.DrillSession, error) {
+	sessions, err := s.drillSessionRepo.FindByUserID(ctx, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.drillService.closeExpired(ctx, sessions); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
 // GetHeatmapData returns accuracy data for the heat map
 func (s *StatsService) GetHeatmapData(ctx context.Context, userID bson.ObjectID) (*model.HeatmapData, error) {
 	accuracies, err := s.attemptRepo.GetSquareAccuracy(ctx, userID)
@@ -87,7 +133,16 @@ func (s *StatsService) GetHeatmapData(ctx context.Context, userID bson.ObjectID)
 	}, nil
 }
 
-// GetDrillStats returns stats for a specific drill type
+// GetDrillStats returns stats for a specific drill type, broken down by category
 func (s *StatsService) GetDrillStats(ctx context.Context, userID bson.ObjectID, drillType model.DrillType) (*model.DrillStats, error) {
-	return s.attemptRepo.GetDrillStats(ctx, userID, drillType)
+	drillStats, err := s.attemptRepo.GetDrillStats(ctx, userID, drillType)
+	if err != nil {
+		return nil, err
+	}
+
+	drillStats.Categories, err = s.attemptRepo.GetCategoryStats(ctx, userID, drillType, categoryField(drillType))
+	if err != nil {
+		return nil, err
+	}
+	return drillStats, nil
 }
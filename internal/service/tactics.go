@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/abdul-hamid-achik/chessdrill/internal/chess"
+	"github.com/abdul-hamid-achik/chessdrill/internal/model"
+	"github.com/abdul-hamid-achik/chessdrill/internal/repository"
+)
+
+var ErrNoPuzzles = errors.New("no puzzles available; run import-puzzles first")
+
+// puzzleRatingRange returns the puzzle ratings served at a difficulty
+func puzzleRatingRange(difficulty model.Difficulty) (int, int) {
+	switch difficulty {
+	case model.DifficultyHard:
+		return 1900, 3500
+	case model.DifficultyMedium:
+		return 1400, 1900
+	default:
+		return 0, 1400
+	}
+}
+
+// puzzleIDAlphabet holds the characters of Lichess puzzle IDs in sort order
+const puzzleIDAlphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// generateTacticsQuestion serves the first move of a random puzzle in the
+// session's rating range. The puzzle is the first one at or after a random
+// rating and ID, so the same random choices find the same puzzle.
+func (s *DrillService) generateTacticsQuestion(ctx context.Context, rng RandomSource, difficulty model.Difficulty) (*model.Question, error) {
+	minRating, maxRating := puzzleRatingRange(difficulty)
+	rating := minRating + rng.IntN(maxRating-minRating+1)
+	id := make([]byte, 5)
+	for i := range id {
+		id[i] = puzzleIDAlphabet[rng.IntN(len(puzzleIDAlphabet))]
+	}
+
+	puzzle, err := s.puzzleRepo.FindFrom(ctx, minRating, maxRating, rating, string(id))
+	if errors.Is(err, repository.ErrPuzzleNotFound) {
+		// Wrap around to the start of the range
+		puzzle, err = s.puzzleRepo.FindFrom(ctx, minRating, maxRating, minRating, "")
+	}
+	if errors.Is(err, repository.ErrPuzzleNotFound) {
+		// Fall back to any rating rather than leaving the drill empty
+		puzzle, err = s.puzzleRepo.FindFrom(ctx, 0, 1<<30, rating, string(id))
+	}
+	if errors.Is(err, repository.ErrPuzzleNotFound) {
+		puzzle, err = s.puzzleRepo.FindFrom(ctx, 0, 1<<30, 0, "")
+	}
+	if err != nil {
+		if errors.Is(err, repository.ErrPuzzleNotFound) {
+			return nil, ErrNoPuzzles
+		}
+		return nil, err
+	}
+
+	pos, err := chess.ParseFEN(puzzle.FEN)
+	if err != nil {
+		return nil, err
+	}
+	return tacticsStep(pos, puzzle.PuzzleID, puzzle.Rating, puzzle.Themes, puzzle.Moves, 1)
+}
+
+// tacticsStep builds the question for the solver's move at index ply of a
+// puzzle's move list. pos is the position before the opponent's preceding
+// move, which is played and shown as the last move.
+func tacticsStep(pos *chess.Position, puzzleID string, rating int, themes, moves []string, ply int) (*model.Question, error) {
+	reply, err := chess.ParseUCI(moves[ply-1])
+	if err != nil || !pos.IsLegal(reply) {
+		return nil, fmt.Errorf("puzzle %s: illegal move %s", puzzleID, moves[ply-1])
+	}
+	replySAN := pos.SAN(reply)
+	pos = pos.Play(reply)
+
+	expected, err := chess.ParseUCI(moves[ply])
+	if err != nil || !pos.IsLegal(expected) {
+		return nil, fmt.Errorf("puzzle %s: illegal move %s", puzzleID, moves[ply])
+	}
+
+	prompt := fmt.Sprintf("%s played %s. Find the best move for %s.", capitalize(pos.Turn.Other().String()), replySAN, pos.Turn)
+	if ply > 1 {
+		prompt = fmt.Sprintf("%s replied %s. Find the next move.", capitalize(pos.Turn.Other().String()), replySAN)
+	}
+
+	return &model.Question{
+		Type:   model.DrillTypeTactics,
+		Target: reply.UCI(),
+		Prompt: prompt,
+		FEN:    pos.FEN(),
+		Answer: pos.SAN(expected),
+		Metadata: map[string]string{
+			"puzzle_id": puzzleID,
+			"rating":    strconv.Itoa(rating),
+		},
+		// Themes such as "mateIn2" hint at the solution
+		AnswerData: map[string]string{
+			"move":   expected.UCI(),
+			"moves":  strings.Join(moves, " "),
+			"ply":    strconv.Itoa(ply),
+			"themes": strings.Join(themes, " "),
+		},
+	}, nil
+}
+
+// continuePuzzle returns the question for the solver's next move after a
+// correct answer, or nil when the puzzle is solved
+func continuePuzzle(question *model.Question) *model.Question {
+	moves := strings.Fields(question.AnswerData["moves"])
+	ply, _ := strconv.Atoi(question.AnswerData["ply"])
+	if ply+2 >= len(moves) {
+		return nil
+	}
+
+	pos, err := chess.ParseFEN(question.FEN)
+	if err != nil {
+		return nil
+	}
+	played, err := chess.ParseUCI(moves[ply])
+	if err != nil {
+		return nil
+	}
+	rating, _ := strconv.Atoi(question.Metadata["rating"])
+	themes := strings.Fields(question.AnswerData["themes"])
+
+	next, err := tacticsStep(pos.Play(played), question.Metadata["puzzle_id"], rating, themes, moves, ply+2)
+	if err != nil {
+		return nil
+	}
+	return next
+}
@@ -25,3 +25,11 @@ func (s *UserService) GetByID(ctx context.Context, userID bson.ObjectID) (*model
 func (s *UserService) UpdatePreferences(ctx context.Context, userID bson.ObjectID, prefs model.Preferences) error {
 	return s.userRepo.UpdatePreferences(ctx, userID, prefs)
 }
+
+// BecomeCoach lets the user create groups and follow their members.
+// Becoming a coach is self-service because the role grants nothing over
+// other users by itself: a coach sees a student only once the student joins
+// one of their groups with its code, and no longer once they leave.
+func (s *UserService) BecomeCoach(ctx context.Context, userID bson.ObjectID) error {
+	return s.userRepo.SetRole(ctx, userID, model.RoleCoach)
+}
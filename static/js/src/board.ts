@@ -83,6 +83,21 @@ export class ChessBoard {
     });
   }
 
+  // Draw an arrow for a move (for move notation drill)
+  drawArrow(from: string, to: string, brush: string = 'green'): void {
+    this.ground.set({
+      drawable: {
+        autoShapes: [
+          {
+            orig: from as Key,
+            dest: to as Key,
+            brush: brush,
+          }
+        ],
+      },
+    });
+  }
+
   // Toggle board orientation
   toggleOrientation(): void {
     this.orientation = this.orientation === 'white' ? 'black' : 'white';
@@ -1,12 +1,25 @@
 import { ChessBoard } from './board';
-import { ChessLogic, calculatePieceMoves, PieceType } from './chess';
+import { ChessLogic } from './chess';
 
 interface Question {
   sessionId: string;
+  questionId: string;
   target: string;
   prompt: string;
   fen: string;
   type: string;
+  category: string;
+  correct?: boolean; // set on the question that follows a graded answer
+}
+
+// Limited session modes, read from the start form
+interface ModeState {
+  mode: string;
+  limit: number;
+  answered: number;
+  misses: number;
+  deadline: number;
+  timerId: number | null;
 }
 
 interface DrillStats {
@@ -23,6 +36,10 @@ export class DrillController {
   private drillType: string;
   private currentQuestion: Question | null = null;
   private sessionId: string | null = null;
+  private selectedSquares: Set<string> = new Set();
+  private moveFrom: string | null = null;
+  private knightRoute: string[] = [];
+  private modeState: ModeState | null = null;
   private stats: DrillStats = {
     total: 0,
     correct: 0,
@@ -45,20 +62,120 @@ export class DrillController {
       const square = e.detail.square;
       this.handleSquareClick(square);
     }) as EventListener);
+
+    // Classification buttons (check recognition drill)
+    document.addEventListener('click', (e) => {
+      const button = (e.target as HTMLElement).closest('[data-choice]') as HTMLElement | null;
+      if (button?.dataset.choice) {
+        this.handleChoice(button.dataset.choice);
+      }
+    });
   }
 
   // Handle question ready event (from HTMX)
   handleQuestionReady(detail: Question): void {
-    this.sessionId = detail.sessionId;
+    if (this.sessionId !== detail.sessionId) {
+      this.sessionId = detail.sessionId;
+      this.startMode();
+    }
     this.setQuestion(detail);
   }
 
   // Handle next question event
   handleNextQuestion(detail: Question): void {
+    if (this.modeState) {
+      this.modeState.answered++;
+      if (detail.correct === false) {
+        this.modeState.misses++;
+      }
+      this.updateModeDisplay();
+    }
     this.setQuestion(detail);
     this.updateStatsDisplay();
   }
 
+  // Handle a session the server ended because its mode limit was reached
+  handleSessionEnded(): void {
+    this.stopMode();
+    const endBtn = document.getElementById('end-drill');
+    if (endBtn) {
+      endBtn.style.display = 'none';
+    }
+  }
+
+  // Read the chosen mode from the start form. The server enforces the
+  // limits; the client only shows progress and ends timed sessions when
+  // the clock runs out.
+  private startMode(): void {
+    this.stopMode();
+
+    const form = document.getElementById('drill-options') as HTMLFormElement | null;
+    if (!form) return;
+    const data = new FormData(form);
+    const mode = String(data.get('mode') || 'open');
+    const limitField: Record<string, string> = {
+      timed: 'time_limit_sec',
+      count: 'question_count',
+      survival: 'max_misses',
+    };
+    if (!limitField[mode]) return;
+
+    const limit = Number(data.get(limitField[mode])) || 0;
+    this.modeState = {
+      mode,
+      limit,
+      answered: 0,
+      misses: 0,
+      deadline: Date.now() + limit * 1000,
+      timerId: null,
+    };
+    if (mode === 'timed') {
+      this.modeState.timerId = window.setInterval(() => this.tickCountdown(), 250);
+    }
+
+    document.getElementById('mode-status')?.classList.remove('hidden');
+    this.updateModeDisplay();
+  }
+
+  private stopMode(): void {
+    if (this.modeState?.timerId) {
+      window.clearInterval(this.modeState.timerId);
+    }
+    this.modeState = null;
+  }
+
+  private tickCountdown(): void {
+    this.updateModeDisplay();
+    if (this.modeState && Date.now() >= this.modeState.deadline) {
+      this.endSession();
+    }
+  }
+
+  // Show the time, questions or lives left in a limited session
+  private updateModeDisplay(): void {
+    const label = document.getElementById('mode-label');
+    const display = document.getElementById('mode-display');
+    if (!this.modeState || !label || !display) return;
+
+    const state = this.modeState;
+    switch (state.mode) {
+      case 'timed': {
+        const left = Math.max(0, Math.ceil((state.deadline - Date.now()) / 1000));
+        label.textContent = 'Time Left';
+        display.textContent = `${Math.floor(left / 60)}:${String(left % 60).padStart(2, '0')}`;
+        break;
+      }
+      case 'count':
+        label.textContent = 'Question';
+        display.textContent = `${Math.min(state.answered + 1, state.limit)} / ${state.limit}`;
+        break;
+      case 'survival':
+        label.textContent = 'Lives';
+        display.textContent = '♥'.repeat(Math.max(0, state.limit - state.misses)) || '—';
+        break;
+    }
+  }
+
   // Set the current question
   setQuestion(question: Question): void {
     this.currentQuestion = question;
@@ -88,12 +205,43 @@ export class DrillController {
         break;
 
       case 'piece_movement':
-        // Show piece and let user identify legal moves
+      case 'pawn_rules':
+        // Show piece and let user pick every legal destination
+        this.selectedSquares.clear();
         this.board.highlightSquare(question.target);
         break;
 
+      case 'check_recognition':
+        // Checking pieces are picked on the board before classifying
+        this.selectedSquares.clear();
+        break;
+
+      case 'knight_distance': {
+        // Target is "from-to"; in path mode the route is clicked hop by hop
+        const [from, to] = question.target.split('-');
+        this.knightRoute = [];
+        this.board.highlightSquares([from, to]);
+        break;
+      }
+
+      case 'tactics':
+      case 'repertoire': {
+        // Show the opponent's last move and let the user play the reply
+        this.moveFrom = null;
+        if (question.target.length >= 4) {
+          this.board.drawArrow(question.target.slice(0, 2), question.target.slice(2, 4), 'blue');
+        }
+        const turn = question.fen.split(' ')[1] === 'b' ? 'black' : 'white';
+        this.board.setOrientation(turn);
+        break;
+      }
+
       case 'move_notation':
-        // Parse the move and require clicking destination
+        // Either show the move to be written down, or let the user play it
+        this.moveFrom = null;
+        if (question.category === 'write_san' && question.target.length >= 4) {
+          this.board.drawArrow(question.target.slice(0, 2), question.target.slice(2, 4));
+        }
         break;
     }
 
@@ -120,16 +268,89 @@ export class DrillController {
 
     if (this.drillType === 'find_square') {
       this.handleFindSquareAnswer(square);
-    } else if (this.drillType === 'piece_movement') {
-      // For piece movement, check if clicked square is a legal destination
-      const prompt = this.currentQuestion.prompt || '';
-      const words = prompt.toLowerCase().split(' ');
-      const pieceType = words[3] as PieceType;
-      if (pieceType) {
-        const legalMoves = calculatePieceMoves(pieceType, this.currentQuestion.target);
-        console.log('Legal moves for', pieceType, 'on', this.currentQuestion.target, ':', legalMoves);
-      }
+    } else if (this.drillType === 'piece_movement' || this.drillType === 'pawn_rules' || this.drillType === 'check_recognition') {
+      this.togglePieceMovementSquare(square);
+    } else if (this.drillType === 'tactics' || this.drillType === 'repertoire' || (this.drillType === 'move_notation' && this.currentQuestion.category === 'play_san')) {
+      this.handlePlayMoveClick(square);
+    } else if (this.drillType === 'knight_distance' && this.currentQuestion.category === 'path') {
+      this.handleKnightRouteClick(square);
+    }
+  }
+
+  // Build a move from two clicks (origin, then destination) and submit it
+  private handlePlayMoveClick(square: string): void {
+    if (!this.moveFrom) {
+      this.moveFrom = square;
+      this.board.highlightSquare(square);
+      return;
+    }
+
+    const move = this.moveFrom + square;
+    this.moveFrom = null;
+    this.board.drawArrow(move.slice(0, 2), move.slice(2, 4), 'blue');
+    this.submitAnswer(move);
+  }
+
+  // Extend the knight route, or undo the last hop when it is clicked again
+  private handleKnightRouteClick(square: string): void {
+    if (!this.currentQuestion) return;
+
+    if (this.knightRoute[this.knightRoute.length - 1] === square) {
+      this.knightRoute.pop();
+    } else {
+      this.knightRoute.push(square);
+    }
+
+    const [from, to] = this.currentQuestion.target.split('-');
+    this.board.highlightSquares([from, to, ...this.knightRoute]);
+
+    const answerInput = document.getElementById('answer-input') as HTMLInputElement;
+    if (answerInput) {
+      answerInput.value = this.knightRoute.join(',');
+    }
+    const display = document.getElementById('selected-squares');
+    if (display) {
+      display.textContent = [from, ...this.knightRoute].join(' → ');
+    }
+  }
+
+  // Toggle a destination square for the piece movement drill. The selection
+  // is submitted as a comma-separated set and graded on the server.
+  private togglePieceMovementSquare(square: string): void {
+    if (!this.currentQuestion || square === this.currentQuestion.target) return;
+
+    if (this.selectedSquares.has(square)) {
+      this.selectedSquares.delete(square);
+    } else {
+      this.selectedSquares.add(square);
+    }
+
+    const selected = Array.from(this.selectedSquares).sort();
+    if (this.drillType === 'check_recognition') {
+      this.board.highlightSquares(selected);
+    } else {
+      this.board.highlightSquares([this.currentQuestion.target, ...selected]);
+    }
+
+    const answerInput = document.getElementById('answer-input') as HTMLInputElement;
+    if (answerInput) {
+      answerInput.value = selected.join(',');
+    }
+    const display = document.getElementById('selected-squares');
+    if (display) {
+      display.textContent = selected.join(' ');
+    }
+  }
+
+  // Submit a classification; a check also carries the selected checkers
+  private handleChoice(choice: string): void {
+    if (!this.currentQuestion) return;
+
+    let answer = choice;
+    if (this.drillType === 'check_recognition' && choice === 'check') {
+      answer += ':' + Array.from(this.selectedSquares).sort().join(',');
     }
+    this.submitAnswer(answer);
   }
 
   // Submit an answer via HTMX
@@ -155,10 +376,8 @@ export class DrillController {
           'HX-Request': 'true',
         },
         body: new URLSearchParams({
-          session_id: this.sessionId,
-          target: this.currentQuestion.target,
+          question_id: this.currentQuestion.questionId,
           answer: answer,
-          drill_type: this.drillType,
           response_ms: '0',
         }),
       })
@@ -216,6 +435,7 @@ export class DrillController {
   // End the current session
   endSession(): void {
     if (!this.sessionId) return;
+    this.stopMode();
 
     fetch('/api/drill/end', {
       method: 'POST',
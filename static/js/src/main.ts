@@ -2,24 +2,28 @@ import { ChessBoard } from './board';
 import { DrillController } from './drill';
 import { Timer } from './timer';
 import { HeatmapRenderer } from './heatmap';
+import { RaceController, initializeRaceLobby } from './race';
 
 // Global app state
 interface AppState {
   board: ChessBoard | null;
   drill: DrillController | null;
   timer: Timer | null;
+  race: RaceController | null;
 }
 
 const app: AppState = {
   board: null,
   drill: null,
   timer: null,
+  race: null,
 };
 
 // Initialize when DOM is ready
 document.addEventListener('DOMContentLoaded', () => {
   initializeBoard();
   initializeDrill();
+  initializeRace();
   initializeHeatmap();
   setupEventListeners();
 });
@@ -28,6 +32,9 @@ function initializeBoard(): void {
   const boardElement = document.getElementById('board');
   if (boardElement) {
     app.board = new ChessBoard(boardElement);
+    if (boardElement.dataset.perspective === 'black') {
+      app.board.setOrientation('black');
+    }
     
     // Expose for debugging
     (window as any).chessBoard = app.board;
@@ -46,6 +53,14 @@ function initializeDrill(): void {
   }
 }
 
+function initializeRace(): void {
+  const raceRoom = document.getElementById('race-room');
+  if (raceRoom && app.board) {
+    app.race = new RaceController(app.board, raceRoom);
+  }
+  initializeRaceLobby();
+}
+
 function initializeHeatmap(): void {
   const canvas = document.getElementById('heatmap-canvas') as HTMLCanvasElement;
   const container = document.getElementById('heatmap-container');
@@ -87,6 +102,48 @@ function setupEventListeners(): void {
     });
   }
   
+  // Show the limit that belongs to the chosen session mode
+  const modeSelect = document.getElementById('mode') as HTMLSelectElement | null;
+  if (modeSelect) {
+    modeSelect.addEventListener('change', () => {
+      document.querySelectorAll<HTMLElement>('[data-mode-option]').forEach(el => {
+        el.classList.toggle('hidden', el.dataset.modeOption !== modeSelect.value);
+      });
+    });
+  }
+
+  // Turn the board to the side the session will be played from
+  const perspectiveSelect = document.getElementById('perspective') as HTMLSelectElement | null;
+  if (perspectiveSelect) {
+    perspectiveSelect.addEventListener('change', () => {
+      app.board?.setOrientation(perspectiveSelect.value === 'black' ? 'black' : 'white');
+    });
+  }
+
+  // Show the inputs that belong to the chosen practice region
+  const regionSelect = document.getElementById('region') as HTMLSelectElement | null;
+  if (regionSelect) {
+    regionSelect.addEventListener('change', () => {
+      document.querySelectorAll<HTMLElement>('[data-region-option]').forEach(el => {
+        el.classList.toggle('hidden', el.dataset.regionOption !== regionSelect.value);
+      });
+    });
+  }
+
+  // Show a failed HTMX request's message where its element asks for it
+  document.body.addEventListener('htmx:responseError', ((e: CustomEvent) => {
+    const source = (e.detail.elt as HTMLElement).closest<HTMLElement>('[data-error-target]');
+    const target = source?.dataset.errorTarget ? document.querySelector(source.dataset.errorTarget) : null;
+    if (target) {
+      target.textContent = e.detail.xhr.responseText.trim();
+    }
+  }) as EventListener);
+
+  // The server ended the session (mode limit reached)
+  window.addEventListener('chessdrill:sessionEnded', () => {
+    app.drill?.handleSessionEnded();
+  });
+
   // Listen for question ready events (from HTMX)
   window.addEventListener('chessdrill:questionReady', ((e: CustomEvent) => {
     const detail = e.detail;
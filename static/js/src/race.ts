@@ -0,0 +1,299 @@
+import { ChessBoard } from './board';
+
+interface RaceStanding {
+  user_id: string;
+  username: string;
+  points: number;
+  answered: boolean;
+  connected: boolean;
+}
+
+interface RaceQuestion {
+  type: string;
+  target: string;
+  prompt: string;
+  fen: string;
+}
+
+interface RaceRoundResult {
+  round: number;
+  winner_id: string;
+  winner_name?: string;
+  answer: string;
+}
+
+interface RaceState {
+  code: string;
+  drill_type: string;
+  status: 'lobby' | 'running' | 'finished';
+  host_id: string;
+  players: RaceStanding[];
+  round: number;
+  rounds: number;
+  question?: RaceQuestion;
+  starts_at?: string;
+  round_ends_at?: string;
+  last_round?: RaceRoundResult;
+  server_time: string;
+}
+
+// Minimum number of players the server needs to start a race
+const MIN_PLAYERS = 2;
+
+// RaceController follows a race room over server-sent events and sends the
+// player's answers. The server decides everything; the page only renders
+// the latest state it was sent.
+export class RaceController {
+  private board: ChessBoard;
+  private code: string;
+  private userId: string;
+  private state: RaceState | null = null;
+  private events: EventSource | null = null;
+  private clockOffset = 0;
+  private tickId: number | null = null;
+  private answeredRound = 0;
+
+  constructor(board: ChessBoard, container: HTMLElement) {
+    this.board = board;
+    this.code = container.dataset.code || '';
+    this.userId = container.dataset.userId || '';
+
+    this.setupEvents();
+    this.connect();
+  }
+
+  private connect(): void {
+    this.events = new EventSource(`/api/race/${this.code}/events`);
+    this.events.addEventListener('state', ((e: MessageEvent) => {
+      this.render(JSON.parse(e.data) as RaceState);
+    }) as EventListener);
+  }
+
+  private setupEvents(): void {
+    document.getElementById('race-start')?.addEventListener('click', () => {
+      this.post('start');
+    });
+
+    document.getElementById('race-leave')?.addEventListener('click', async () => {
+      if (await this.post('leave')) {
+        window.location.href = '/race';
+      }
+    });
+
+    const form = document.getElementById('race-answer-form') as HTMLFormElement | null;
+    form?.addEventListener('submit', (e) => {
+      e.preventDefault();
+      const input = document.getElementById('race-answer') as HTMLInputElement;
+      if (input.value.trim()) {
+        this.submitAnswer(input.value.trim());
+      }
+    });
+  }
+
+  private render(state: RaceState): void {
+    const previous = this.state;
+    this.state = state;
+    this.clockOffset = Date.now() - Date.parse(state.server_time);
+
+    this.renderPlayers(state);
+    this.renderStatus(state);
+
+    const startButton = document.getElementById('race-start') as HTMLButtonElement | null;
+    if (startButton) {
+      startButton.classList.toggle('hidden', state.status !== 'lobby' || state.host_id !== this.userId);
+      startButton.disabled = state.players.length < MIN_PLAYERS;
+    }
+    document.getElementById('race-leave')?.classList.toggle('hidden', state.status !== 'lobby');
+
+    const newQuestion = state.question && (!previous?.question || previous.round !== state.round);
+    if (newQuestion) {
+      this.showQuestion(state.question!);
+    }
+    this.setAnswerEnabled(!!state.question && this.answeredRound !== state.round);
+
+    if (state.status === 'finished') {
+      this.events?.close();
+      this.board.clearHighlights();
+      this.board.disableSelection();
+    }
+
+    this.startTicking();
+  }
+
+  private renderPlayers(state: RaceState): void {
+    const list = document.getElementById('race-players');
+    if (!list) return;
+
+    const players = [...state.players];
+    if (state.status !== 'lobby') {
+      players.sort((a, b) => b.points - a.points);
+    }
+
+    list.replaceChildren(...players.map(player => {
+      const item = document.createElement('li');
+      item.className = 'flex items-center justify-between py-2';
+
+      const name = document.createElement('span');
+      name.className = 'text-gray-900 dark:text-white';
+      name.textContent = player.username;
+      if (player.user_id === state.host_id) name.textContent += ' (host)';
+      if (player.user_id === this.userId) name.classList.add('font-bold');
+      if (!player.connected) name.classList.add('opacity-50');
+
+      const points = document.createElement('span');
+      points.className = 'text-sm text-gray-500 dark:text-gray-400';
+      points.textContent = state.status === 'lobby'
+        ? (player.connected ? 'ready' : 'away')
+        : `${player.points}${player.answered && state.question ? ' · answered' : ''}`;
+
+      item.append(name, points);
+      return item;
+    }));
+  }
+
+  private renderStatus(state: RaceState): void {
+    const round = document.getElementById('race-round');
+    if (round) {
+      round.textContent = state.round > 0 ? `Round ${state.round} of ${state.rounds}` : `${state.rounds} rounds`;
+    }
+
+    const last = document.getElementById('race-last-round');
+    if (last) {
+      last.textContent = '';
+      if (state.last_round && !state.question) {
+        const result = state.last_round;
+        last.textContent = result.winner_name
+          ? `${result.winner_name} got it: ${result.answer}`
+          : `Nobody got it: ${result.answer}`;
+      }
+    }
+
+    const prompt = document.getElementById('race-prompt');
+    if (prompt && !state.question) {
+      if (state.status === 'lobby') {
+        prompt.textContent = `Waiting for the host to start. Share the code ${state.code} to invite players.`;
+      } else if (state.status === 'finished') {
+        prompt.textContent = this.finalMessage(state);
+      } else if (state.round === 0) {
+        prompt.textContent = 'Get ready...';
+      } else {
+        prompt.textContent = 'Next round...';
+      }
+    }
+  }
+
+  private finalMessage(state: RaceState): string {
+    const best = Math.max(...state.players.map(p => p.points));
+    const winners = state.players.filter(p => p.points === best).map(p => p.username);
+    if (winners.length > 1) {
+      return `Race over! Tie between ${winners.join(', ')} with ${best} points.`;
+    }
+    return `Race over! ${winners[0]} wins with ${best} points.`;
+  }
+
+  private showQuestion(question: RaceQuestion): void {
+    this.board.clearHighlights();
+    if (question.fen) {
+      this.board.setPosition(question.fen);
+    }
+
+    const prompt = document.getElementById('race-prompt');
+    const form = document.getElementById('race-answer-form');
+    const feedback = document.getElementById('race-feedback');
+    if (feedback) feedback.textContent = '';
+
+    if (question.type === 'find_square') {
+      if (prompt) prompt.textContent = `Find ${question.prompt || question.target}`;
+      form?.classList.add('hidden');
+      this.board.enableSelection((square) => this.submitAnswer(square));
+    } else {
+      if (prompt) prompt.textContent = question.prompt || 'Name the highlighted square';
+      form?.classList.remove('hidden');
+      this.board.disableSelection();
+      this.board.highlightSquare(question.target);
+      const input = document.getElementById('race-answer') as HTMLInputElement | null;
+      if (input) {
+        input.value = '';
+        input.focus();
+      }
+    }
+  }
+
+  private setAnswerEnabled(enabled: boolean): void {
+    const input = document.getElementById('race-answer') as HTMLInputElement | null;
+    if (input) input.disabled = !enabled;
+    if (!enabled) this.board.disableSelection();
+  }
+
+  private async submitAnswer(answer: string): Promise<void> {
+    if (!this.state?.question || this.answeredRound === this.state.round) return;
+    const round = this.state.round;
+    this.answeredRound = round;
+    this.setAnswerEnabled(false);
+
+    const body = new URLSearchParams({ round: String(round), answer });
+    const response = await fetch(`/api/race/${this.code}/answer`, { method: 'POST', body });
+    const feedback = document.getElementById('race-feedback');
+    if (!response.ok) {
+      if (feedback) feedback.textContent = 'Too late!';
+      return;
+    }
+    const result = await response.json() as { correct: boolean };
+    if (feedback) feedback.textContent = result.correct ? `Correct: ${answer}` : `Wrong: ${answer}`;
+  }
+
+  private async post(action: string): Promise<boolean> {
+    const response = await fetch(`/api/race/${this.code}/${action}`, { method: 'POST' });
+    if (!response.ok) {
+      const error = document.getElementById('race-error');
+      if (error) error.textContent = (await response.text()).trim();
+      return false;
+    }
+    return true;
+  }
+
+  // Count down to the start of the race or the end of the round
+  private startTicking(): void {
+    if (this.tickId !== null) {
+      window.clearInterval(this.tickId);
+      this.tickId = null;
+    }
+    const clock = document.getElementById('race-clock');
+    if (!clock) return;
+
+    const until = this.state?.round_ends_at || this.state?.starts_at;
+    if (!until) {
+      clock.textContent = '';
+      return;
+    }
+    const deadline = Date.parse(until) + this.clockOffset;
+    const tick = () => {
+      const remaining = Math.max(0, deadline - Date.now());
+      clock.textContent = `${Math.ceil(remaining / 1000)}s`;
+    };
+    tick();
+    this.tickId = window.setInterval(tick, 200);
+  }
+}
+
+// initializeRaceLobby sends the create and join forms and opens the room
+export function initializeRaceLobby(): void {
+  const error = document.getElementById('race-error');
+
+  document.querySelectorAll<HTMLFormElement>('form[data-race-action]').forEach(form => {
+    form.addEventListener('submit', async (e) => {
+      e.preventDefault();
+      const data = new URLSearchParams(new FormData(form) as any);
+      const action = form.dataset.raceAction;
+      const url = action === 'join' ? `/api/race/${encodeURIComponent(String(data.get('code') || '').trim().toUpperCase())}/join` : '/api/race';
+
+      const response = await fetch(url, { method: 'POST', body: data });
+      if (!response.ok) {
+        if (error) error.textContent = (await response.text()).trim();
+        return;
+      }
+      const { code } = await response.json() as { code: string };
+      window.location.href = `/race/${code}`;
+    });
+  });
+}